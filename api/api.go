@@ -1,6 +1,9 @@
 package api
 
-import "context"
+import (
+	"context"
+	"strings"
+)
 
 type API interface {
 	// Init initializes the API with the given arguments
@@ -11,22 +14,46 @@ type API interface {
 	// returns the messages in a slice of APIMessage, the last timestamp and an
 	// error if something goes wrong
 	LastMentions(ctx context.Context, timestamp uint64) ([]*APIMessage, uint64, error)
+	// ChannelCasts retrieves the casts published in the channel identified by
+	// the given parent url from the given timestamp, it returns the messages in
+	// a slice of APIMessage, the last timestamp and an error if something goes
+	// wrong
+	ChannelCasts(ctx context.Context, parentURL string, timestamp uint64) ([]*APIMessage, uint64, error)
+	// CastByHash retrieves the cast of the given fid with the given hash, it
+	// returns the cast as an APIMessage or an error if something goes wrong
+	CastByHash(ctx context.Context, fid uint64, hash string) (*APIMessage, error)
 	// Reply replies to a cast of the given fid with the given hash and content,
-	// it returns an error if something goes wrong
-	Reply(ctx context.Context, fid uint64, hash string, content string) error
+	// embedding the given urls if any, it returns the hash of the new cast or
+	// an error if something goes wrong
+	Reply(ctx context.Context, fid uint64, hash string, content string, embeds ...string) (string, error)
+	// Cast publishes a new top-level cast with the given content in the
+	// channel identified by the given parent url, it returns the hash of the
+	// new cast or an error if something goes wrong
+	Cast(ctx context.Context, parentURL string, content string) (string, error)
+	// DeleteCast deletes the cast with the given hash published by the bot, it
+	// returns an error if something goes wrong
+	DeleteCast(ctx context.Context, hash string) error
 	// UserDataByFID retrieves the Userdata of the user with the given fid, if
 	// something goes wrong, it returns an error
 	UserDataByFID(ctx context.Context, fid uint64) (*Userdata, error)
 	// UserDataByVerificationAddress retrieves the Userdata of the user with the
 	// given verification address, if something goes wrong, it returns an error
 	UserDataByVerificationAddress(ctx context.Context, address string) (*Userdata, error)
+	// UserDataByUsername retrieves the Userdata of the user with the given
+	// username, if something goes wrong, it returns an error
+	UserDataByUsername(ctx context.Context, username string) (*Userdata, error)
 }
 
 type APIMessage struct {
-	IsMention bool
-	Content   string
-	Author    uint64
-	Hash      string
+	IsMention    bool
+	Content      string
+	Author       uint64
+	Hash         string
+	ParentURL    string
+	ParentAuthor uint64
+	ParentHash   string
+	// Embeds contains the urls embedded in the cast
+	Embeds []string
 }
 
 type Userdata struct {
@@ -35,3 +62,14 @@ type Userdata struct {
 	CustodyAddress         string
 	VerificationsAddresses []string
 }
+
+// NormalizeSpaces trims every line of the given text and collapses the
+// consecutive spaces inside them into a single one. It allows to clean the
+// text of a cast after removing or inserting mentions.
+func NormalizeSpaces(text string) string {
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = strings.Join(strings.Fields(line), " ")
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
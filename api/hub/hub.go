@@ -9,7 +9,9 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/vocdoni/votebot/api"
@@ -22,12 +24,17 @@ import (
 const (
 	// endpoints
 	ENDPOINT_CAST_BY_MENTION       = "castsByMention?fid=%d"
+	ENDPOINT_CAST_BY_PARENT        = "castsByParent?url=%s&pageSize=%d&reverse=1&pageToken=%s"
+	ENDPOINT_CAST_BY_ID            = "castById?fid=%d&hash=%s"
 	ENDPOINT_SUBMIT_MESSAGE        = "submitMessage"
 	ENDPOINT_USERNAME_PROOFS       = "userNameProofsByFid?fid=%d"
+	ENDPOINT_USERNAME_PROOF        = "userNameProofByName?name=%s"
 	ENDPOINT_VERIFICATIONS         = "verificationsByFid?fid=%d"
 	ENDPOINT_IDREGISTRY_BY_ADDRESS = "onChainIdRegistryEventByAddress?address=%s"
 	// timeouts
 	getCastByMentionTimeout = 15 * time.Second
+	getCastByParentTimeout  = 15 * time.Second
+	getCastByIDTimeout      = 15 * time.Second
 	submitMessageTimeout    = 5 * time.Minute
 	userdataTimeout         = 15 * time.Second
 	// message types
@@ -35,14 +42,17 @@ const (
 	MESSAGE_TYPE_USERPROOF    = "USERNAME_TYPE_FNAME"
 	MESSAGE_TYPE_VERIFICATION = "MESSAGE_TYPE_VERIFICATION_ADD_ETH_ADDRESS"
 	// other constants
-	farcasterEpoch uint64 = 1609459200 // January 1, 2021 UTC
+	farcasterEpoch  uint64 = 1609459200 // January 1, 2021 UTC
+	channelPageSize        = 100
 )
 
 type Hub struct {
-	fid      uint64
-	privKey  []byte
-	endpoint string
-	auth     map[string]string
+	fid          uint64
+	privKey      []byte
+	endpoint     string
+	auth         map[string]string
+	usernames    map[uint64]string
+	usernamesMtx sync.Mutex
 }
 
 func (h *Hub) Init(args ...any) error {
@@ -70,6 +80,7 @@ func (h *Hub) Init(args ...any) error {
 			h.auth = auth
 		}
 	}
+	h.usernames = make(map[uint64]string)
 	return nil
 }
 
@@ -119,12 +130,10 @@ func (h *Hub) LastMentions(ctx context.Context, timestamp uint64) ([]*api.APIMes
 			continue
 		}
 		if m.Data.Timestamp > timestamp {
-			messages = append(messages, &api.APIMessage{
-				IsMention: true,
-				Content:   m.Data.CastAddBody.Text,
-				Author:    m.Data.From,
-				Hash:      m.HexHash,
-			})
+			msg := m.toAPIMessage()
+			msg.IsMention = true
+			msg.Content = h.castContent(ctx, m.Data.CastAddBody)
+			messages = append(messages, msg)
 			if m.Data.Timestamp > lastTimestamp {
 				lastTimestamp = m.Data.Timestamp
 			}
@@ -138,12 +147,126 @@ func (h *Hub) LastMentions(ctx context.Context, timestamp uint64) ([]*api.APIMes
 	return messages, lastTimestamp + farcasterEpoch, nil
 }
 
-func (h *Hub) Reply(ctx context.Context, targetFid uint64, targetHash string, content string) error {
+func (h *Hub) ChannelCasts(ctx context.Context, parentURL string, timestamp uint64) ([]*api.APIMessage, uint64, error) {
+	if timestamp > farcasterEpoch {
+		timestamp -= farcasterEpoch
+	}
+	internalCtx, cancel := context.WithTimeout(ctx, getCastByParentTimeout)
+	defer cancel()
+	// download the casts of the channel page by page, from the newest to the
+	// oldest, until a cast older than the given timestamp is found
+	lastTimestamp := timestamp
+	messages := []*api.APIMessage{}
+	pageToken := ""
+	for {
+		casts, err := h.channelCastsPage(internalCtx, parentURL, pageToken)
+		if err != nil {
+			return nil, 0, err
+		}
+		// filter messages and calculate the last timestamp
+		oldCastFound := false
+		for _, m := range casts.Messages {
+			if m.Data.Timestamp <= timestamp {
+				oldCastFound = true
+				break
+			}
+			isCast := m.Data.Type == MESSAGE_TYPE_CAST_ADD && m.Data.CastAddBody != nil && m.Data.CastAddBody.Text != ""
+			if !isCast {
+				continue
+			}
+			msg := m.toAPIMessage()
+			msg.Content = h.castContent(ctx, m.Data.CastAddBody)
+			messages = append(messages, msg)
+			if m.Data.Timestamp > lastTimestamp {
+				lastTimestamp = m.Data.Timestamp
+			}
+		}
+		// stop if there are no more new casts
+		if oldCastFound || casts.NextPageToken == "" {
+			break
+		}
+		pageToken = casts.NextPageToken
+	}
+	return messages, lastTimestamp + farcasterEpoch, nil
+}
+
+// channelCastsPage downloads the page of the casts of the channel identified
+// by the given parent url that starts at the given page token, the first page
+// if it is empty, with the newest casts first.
+func (h *Hub) channelCastsPage(ctx context.Context, parentURL, pageToken string) (*HubMentionsResponse, error) {
+	uri := fmt.Sprintf(ENDPOINT_CAST_BY_PARENT, url.QueryEscape(parentURL), channelPageSize, url.QueryEscape(pageToken))
+	req, err := h.newRequest(ctx, http.MethodGet, uri, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %w", err)
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error downloading json: %w", err)
+	}
+	defer func() {
+		if err := res.Body.Close(); err != nil {
+			log.Error("error closing response body")
+		}
+	}()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("error downloading json: %s", res.Status)
+	}
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading response body: %w", err)
+	}
+	// unmarshal the json
+	casts := &HubMentionsResponse{}
+	if err := json.Unmarshal(body, casts); err != nil {
+		return nil, fmt.Errorf("error unmarshalling json: %w", err)
+	}
+	return casts, nil
+}
+
+func (h *Hub) CastByHash(ctx context.Context, fid uint64, hash string) (*api.APIMessage, error) {
+	internalCtx, cancel := context.WithTimeout(ctx, getCastByIDTimeout)
+	defer cancel()
+	// download the cast from API endpoint
+	uri := fmt.Sprintf(ENDPOINT_CAST_BY_ID, fid, hash)
+	req, err := h.newRequest(internalCtx, http.MethodGet, uri, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %w", err)
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error downloading json: %w", err)
+	}
+	defer func() {
+		if err := res.Body.Close(); err != nil {
+			log.Error("error closing response body")
+		}
+	}()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("error downloading json: %s", res.Status)
+	}
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading response body: %w", err)
+	}
+	// unmarshal the json
+	cast := &HubMessage{}
+	if err := json.Unmarshal(body, cast); err != nil {
+		return nil, fmt.Errorf("error unmarshalling json: %w", err)
+	}
+	if cast.Data == nil || cast.Data.Type != MESSAGE_TYPE_CAST_ADD || cast.Data.CastAddBody == nil {
+		return nil, fmt.Errorf("message is not a cast")
+	}
+	msg := cast.toAPIMessage()
+	msg.Content = h.castContent(ctx, cast.Data.CastAddBody)
+	return msg, nil
+}
+
+func (h *Hub) Reply(ctx context.Context, targetFid uint64, targetHash string, content string, embeds ...string) (string, error) {
 	// create the cast as a reply to the message with the parentFID provided
 	// and the desired text
 	bTargetHash, err := hex.DecodeString(strings.TrimPrefix(targetHash, "0x"))
 	if err != nil {
-		return fmt.Errorf("error decoding target hash: %s", err)
+		return "", fmt.Errorf("error decoding target hash: %s", err)
 	}
 	castAdd := &protobufs.CastAddBody{
 		Text: content,
@@ -156,6 +279,11 @@ func (h *Hub) Reply(ctx context.Context, targetFid uint64, targetHash string, co
 			},
 		},
 	}
+	for _, url := range embeds {
+		castAdd.Embeds = append(castAdd.Embeds, &protobufs.Embed{
+			Embed: &protobufs.Embed_Url{Url: url},
+		})
+	}
 	// compose the message data with the message type, the bot FID, the current
 	// timestamp, the network, and the cast add body
 	msgData := &protobufs.MessageData{
@@ -165,58 +293,46 @@ func (h *Hub) Reply(ctx context.Context, targetFid uint64, targetHash string, co
 		Network:   protobufs.FarcasterNetwork_FARCASTER_NETWORK_MAINNET,
 		Body:      &protobufs.MessageData_CastAddBody{CastAddBody: castAdd},
 	}
-	// marshal the message data
-	msgDataBytes, err := proto.Marshal(msgData)
-	if err != nil {
-		return fmt.Errorf("error marshalling message data: %s", err)
-	}
-	// calculate the hash of the message data
-	hasher := blake3.New()
-	hasher.Write(msgDataBytes)
-	hash := hasher.Sum(nil)[:20]
-	// create the message with the hash scheme, the hash and the signature
-	// scheme
-	msg := &protobufs.Message{
-		HashScheme:      protobufs.HashScheme_HASH_SCHEME_BLAKE3,
-		Hash:            hash,
-		SignatureScheme: protobufs.SignatureScheme_SIGNATURE_SCHEME_ED25519,
-		Data:            msgData,
-		DataBytes:       msgDataBytes,
-	}
-	// sign the message with the private key
-	privateKey := ed25519.NewKeyFromSeed(h.privKey)
-	signature := ed25519.Sign(privateKey, hash)
-	signer := privateKey.Public().(ed25519.PublicKey)
-	// set the signature and the signer to the message
-	msg.Signature = signature
-	msg.Signer = signer
-	// marshal the message
-	msgBytes, err := proto.Marshal(msg)
-	if err != nil {
-		return fmt.Errorf("error marshalling message: %s", err)
+	return h.submitMessage(ctx, msgData)
+}
+
+func (h *Hub) Cast(ctx context.Context, parentURL string, content string) (string, error) {
+	// create the cast in the channel with the provided parent url and the
+	// desired text
+	castAdd := &protobufs.CastAddBody{
+		Text:   content,
+		Parent: &protobufs.CastAddBody_ParentUrl{ParentUrl: parentURL},
 	}
-	// create a new context with a timeout
-	internalCtx, cancel := context.WithTimeout(ctx, submitMessageTimeout)
-	defer cancel()
-	// submit the message to the API endpoint
-	req, err := h.newRequest(internalCtx, http.MethodPost, ENDPOINT_SUBMIT_MESSAGE, bytes.NewBuffer(msgBytes))
-	if err != nil {
-		return fmt.Errorf("error creating request: %s", err)
+	// compose the message data with the message type, the bot FID, the current
+	// timestamp, the network, and the cast add body
+	msgData := &protobufs.MessageData{
+		Type:      protobufs.MessageType_MESSAGE_TYPE_CAST_ADD,
+		Fid:       h.fid,
+		Timestamp: uint32(uint64(time.Now().Unix()) - farcasterEpoch),
+		Network:   protobufs.FarcasterNetwork_FARCASTER_NETWORK_MAINNET,
+		Body:      &protobufs.MessageData_CastAddBody{CastAddBody: castAdd},
 	}
-	req.Header.Set("Content-Type", "application/octet-stream")
-	res, err := http.DefaultClient.Do(req)
+	return h.submitMessage(ctx, msgData)
+}
+
+func (h *Hub) DeleteCast(ctx context.Context, hash string) error {
+	bHash, err := hex.DecodeString(strings.TrimPrefix(hash, "0x"))
 	if err != nil {
-		return fmt.Errorf("error submitting the message: %s", err)
+		return fmt.Errorf("error decoding cast hash: %s", err)
 	}
-	if res.StatusCode != http.StatusOK {
-		// read the response body
-		body, err := io.ReadAll(res.Body)
-		if err != nil {
-			return fmt.Errorf("error reading response body: %s", err)
-		}
-		return fmt.Errorf("error submitting the message: %s", string(body))
+	// compose the message data with the cast remove body pointing to the
+	// hash of the cast to delete
+	msgData := &protobufs.MessageData{
+		Type:      protobufs.MessageType_MESSAGE_TYPE_CAST_REMOVE,
+		Fid:       h.fid,
+		Timestamp: uint32(uint64(time.Now().Unix()) - farcasterEpoch),
+		Network:   protobufs.FarcasterNetwork_FARCASTER_NETWORK_MAINNET,
+		Body: &protobufs.MessageData_CastRemoveBody{
+			CastRemoveBody: &protobufs.CastRemoveBody{TargetHash: bHash},
+		},
 	}
-	return nil
+	_, err = h.submitMessage(ctx, msgData)
+	return err
 }
 
 func (h *Hub) UserDataByFID(ctx context.Context, fid uint64) (*api.Userdata, error) {
@@ -306,6 +422,171 @@ func (h *Hub) UserDataByVerificationAddress(ctx context.Context, address string)
 	return nil, fmt.Errorf("not implemented")
 }
 
+// UserDataByUsername gets the fid of the user with the given username from
+// its username proof and returns its user data.
+func (h *Hub) UserDataByUsername(ctx context.Context, username string) (*api.Userdata, error) {
+	// create a intenal context with a timeout
+	internalCtx, cancel := context.WithTimeout(ctx, userdataTimeout)
+	defer cancel()
+	// prepare the request to get the username proof from the API
+	uri := fmt.Sprintf(ENDPOINT_USERNAME_PROOF, url.QueryEscape(username))
+	req, err := h.newRequest(internalCtx, http.MethodGet, uri, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error creating username proof request: %w", err)
+	}
+	// download the username proof from the API and check for errors
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error downloading username proof: %w", err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("error downloading username proof: %s", res.Status)
+	}
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading username proof response body: %w", err)
+	}
+	proof := &UsernameProofs{}
+	if err := json.Unmarshal(body, proof); err != nil {
+		return nil, fmt.Errorf("error unmarshalling username proof: %w", err)
+	}
+	if proof.FID == 0 {
+		return nil, fmt.Errorf("no fid found for username %s", username)
+	}
+	return h.UserDataByFID(ctx, proof.FID)
+}
+
+// submitMessage hashes and signs the given message data with the bot private
+// key and submits the resulting message to the hub. It returns the hex encoded
+// hash of the submitted message or an error if something goes wrong.
+func (h *Hub) submitMessage(ctx context.Context, msgData *protobufs.MessageData) (string, error) {
+	// marshal the message data
+	msgDataBytes, err := proto.Marshal(msgData)
+	if err != nil {
+		return "", fmt.Errorf("error marshalling message data: %s", err)
+	}
+	// calculate the hash of the message data
+	hasher := blake3.New()
+	hasher.Write(msgDataBytes)
+	hash := hasher.Sum(nil)[:20]
+	// create the message with the hash scheme, the hash and the signature
+	// scheme
+	msg := &protobufs.Message{
+		HashScheme:      protobufs.HashScheme_HASH_SCHEME_BLAKE3,
+		Hash:            hash,
+		SignatureScheme: protobufs.SignatureScheme_SIGNATURE_SCHEME_ED25519,
+		Data:            msgData,
+		DataBytes:       msgDataBytes,
+	}
+	// sign the message with the private key
+	privateKey := ed25519.NewKeyFromSeed(h.privKey)
+	signature := ed25519.Sign(privateKey, hash)
+	signer := privateKey.Public().(ed25519.PublicKey)
+	// set the signature and the signer to the message
+	msg.Signature = signature
+	msg.Signer = signer
+	// marshal the message
+	msgBytes, err := proto.Marshal(msg)
+	if err != nil {
+		return "", fmt.Errorf("error marshalling message: %s", err)
+	}
+	// create a new context with a timeout
+	internalCtx, cancel := context.WithTimeout(ctx, submitMessageTimeout)
+	defer cancel()
+	// submit the message to the API endpoint
+	req, err := h.newRequest(internalCtx, http.MethodPost, ENDPOINT_SUBMIT_MESSAGE, bytes.NewBuffer(msgBytes))
+	if err != nil {
+		return "", fmt.Errorf("error creating request: %s", err)
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("error submitting the message: %s", err)
+	}
+	if res.StatusCode != http.StatusOK {
+		// read the response body
+		body, err := io.ReadAll(res.Body)
+		if err != nil {
+			return "", fmt.Errorf("error reading response body: %s", err)
+		}
+		return "", fmt.Errorf("error submitting the message: %s", string(body))
+	}
+	return fmt.Sprintf("0x%s", hex.EncodeToString(hash)), nil
+}
+
+// castContent returns the text of the cast with the mentions to other users
+// inserted as @username and the mentions to the bot removed. The hub returns
+// the text of the casts without the mentions, which are encoded by fid and
+// position, so the usernames of the mentioned users are resolved and cached.
+func (h *Hub) castContent(ctx context.Context, body *HubCastAddBody) string {
+	usernames := make(map[uint64]string, len(body.Mentions))
+	for _, fid := range body.Mentions {
+		if fid == h.fid {
+			continue
+		}
+		if _, ok := usernames[fid]; ok {
+			continue
+		}
+		username, err := h.username(ctx, fid)
+		if err != nil {
+			log.Warnw("error resolving mentioned username", "fid", fid, "error", err)
+			continue
+		}
+		usernames[fid] = username
+	}
+	return composeText(body.Text, body.Mentions, body.MentionsPositions, usernames)
+}
+
+// username returns the username of the user with the given fid, using the
+// cached one if it has been already resolved.
+func (h *Hub) username(ctx context.Context, fid uint64) (string, error) {
+	h.usernamesMtx.Lock()
+	username, ok := h.usernames[fid]
+	h.usernamesMtx.Unlock()
+	if ok {
+		return username, nil
+	}
+	userdata, err := h.UserDataByFID(ctx, fid)
+	if err != nil {
+		return "", err
+	}
+	if userdata.Username == "" {
+		return "", fmt.Errorf("no username found")
+	}
+	h.usernamesMtx.Lock()
+	h.usernames[fid] = userdata.Username
+	h.usernamesMtx.Unlock()
+	return userdata.Username, nil
+}
+
+// composeText inserts the mentions in the text of a cast at the given byte
+// positions as @username, using the provided usernames by fid. The mentions
+// whose username is not provided (such as the bot ones) are removed. The
+// resulting text is normalized to remove the extra spaces left by the removed
+// mentions.
+func composeText(text string, mentions []uint64, positions []uint32, usernames map[uint64]string) string {
+	if len(mentions) == 0 || len(mentions) != len(positions) {
+		return api.NormalizeSpaces(text)
+	}
+	var sb strings.Builder
+	last := 0
+	for i, fid := range mentions {
+		position := int(positions[i])
+		// skip invalid positions, they must be sorted and inside the text
+		if position < last || position > len(text) {
+			continue
+		}
+		sb.WriteString(text[last:position])
+		if username, ok := usernames[fid]; ok {
+			sb.WriteString("@" + username)
+		}
+		last = position
+	}
+	sb.WriteString(text[last:])
+	return api.NormalizeSpaces(sb.String())
+}
+
 func (h *Hub) newRequest(ctx context.Context, method string, uri string, body io.Reader) (*http.Request, error) {
 	endpoint := fmt.Sprintf("%s/%s", h.endpoint, uri)
 	req, err := http.NewRequestWithContext(ctx, method, endpoint, body)
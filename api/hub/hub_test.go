@@ -0,0 +1,147 @@
+package hub
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestComposeText(t *testing.T) {
+	c := qt.New(t)
+
+	const botFID, aliceFID, bobFID = 1, 2, 3
+	usernames := map[uint64]string{
+		aliceFID: "alice",
+		bobFID:   "bob",
+	}
+	tests := []struct {
+		name      string
+		text      string
+		mentions  []uint64
+		positions []uint32
+		expected  string
+	}{
+		{
+			name:     "no mentions",
+			text:     "!poll\nWhat is your favourite colour?",
+			expected: "!poll\nWhat is your favourite colour?",
+		},
+		{
+			name:      "bot mention as prefix",
+			text:      " !poll\nWhat is your favourite colour?",
+			mentions:  []uint64{botFID},
+			positions: []uint32{0},
+			expected:  "!poll\nWhat is your favourite colour?",
+		},
+		{
+			name:      "bot mention after the command",
+			text:      "!poll \nWhat is your favourite colour?",
+			mentions:  []uint64{botFID},
+			positions: []uint32{6},
+			expected:  "!poll\nWhat is your favourite colour?",
+		},
+		{
+			name:      "bot mention in the middle of a line",
+			text:      "hey  please\n!poll",
+			mentions:  []uint64{botFID},
+			positions: []uint32{4},
+			expected:  "hey please\n!poll",
+		},
+		{
+			name:      "other mentions are inserted",
+			text:      " !poll\nDo you prefer  or ?",
+			mentions:  []uint64{botFID, aliceFID, bobFID},
+			positions: []uint32{0, 21, 25},
+			expected:  "!poll\nDo you prefer @alice or @bob?",
+		},
+		{
+			name:      "adjacent mentions",
+			text:      "!poll\n-  \n- ",
+			mentions:  []uint64{aliceFID, botFID, bobFID},
+			positions: []uint32{8, 8, 12},
+			expected:  "!poll\n- @alice\n- @bob",
+		},
+		{
+			name:      "multibyte text before the mention",
+			text:      "!poll 🗳️ \nQuestion",
+			mentions:  []uint64{aliceFID},
+			positions: []uint32{uint32(len("!poll 🗳️ "))},
+			expected:  "!poll 🗳️ @alice\nQuestion",
+		},
+		{
+			name:      "unknown mentions are removed",
+			text:      "!poll\nIs  right?",
+			mentions:  []uint64{99},
+			positions: []uint32{9},
+			expected:  "!poll\nIs right?",
+		},
+		{
+			name:      "invalid positions are ignored",
+			text:      "Hi ",
+			mentions:  []uint64{aliceFID, bobFID},
+			positions: []uint32{3, 100},
+			expected:  "Hi @alice",
+		},
+		{
+			name:      "mismatched mentions and positions",
+			text:      " !poll",
+			mentions:  []uint64{aliceFID, bobFID},
+			positions: []uint32{0},
+			expected:  "!poll",
+		},
+	}
+	for _, test := range tests {
+		c.Run(test.name, func(c *qt.C) {
+			text := composeText(test.text, test.mentions, test.positions, usernames)
+			c.Assert(text, qt.Equals, test.expected)
+		})
+	}
+}
+
+func TestChannelCasts(t *testing.T) {
+	c := qt.New(t)
+
+	// the channel has two pages of casts, from the newest to the oldest, and
+	// the casts older than the timestamp start in the second page
+	const channelURL = "https://warpcast.com/~/channel/vocdoni"
+	pages := map[string]string{
+		"": `{"messages": [
+			{"hash": "0x4", "data": {"type": "MESSAGE_TYPE_CAST_ADD", "fid": 2, "timestamp": 104, "castAddBody": {"text": "!poll 4"}}},
+			{"hash": "0x3", "data": {"type": "MESSAGE_TYPE_CAST_ADD", "fid": 2, "timestamp": 103, "castAddBody": {"text": "!poll 3"}}}
+		], "nextPageToken": "page2"}`,
+		"page2": `{"messages": [
+			{"hash": "0x2", "data": {"type": "MESSAGE_TYPE_CAST_ADD", "fid": 2, "timestamp": 102, "castAddBody": {"text": "!poll 2"}}},
+			{"hash": "0x1", "data": {"type": "MESSAGE_TYPE_CAST_ADD", "fid": 2, "timestamp": 100, "castAddBody": {"text": "!poll 1"}}}
+		], "nextPageToken": "page3"}`,
+	}
+	requested := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/castsByParent" || r.URL.Query().Get("url") != channelURL {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		pageToken := r.URL.Query().Get("pageToken")
+		requested = append(requested, pageToken)
+		page, ok := pages[pageToken]
+		if !ok {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		fmt.Fprint(w, page)
+	}))
+	defer server.Close()
+
+	hub := &Hub{endpoint: server.URL, usernames: map[uint64]string{}}
+	casts, lastTimestamp, err := hub.ChannelCasts(context.Background(), channelURL, farcasterEpoch+101)
+	c.Assert(err, qt.IsNil)
+	c.Assert(requested, qt.DeepEquals, []string{"", "page2"})
+	c.Assert(casts, qt.HasLen, 3)
+	for i, hash := range []string{"0x4", "0x3", "0x2"} {
+		c.Assert(casts[i].Hash, qt.Equals, hash)
+	}
+	c.Assert(lastTimestamp, qt.Equals, farcasterEpoch+104)
+}
@@ -1,8 +1,24 @@
 package hub
 
+import "github.com/vocdoni/votebot/api"
+
+type HubCastID struct {
+	FID  uint64 `json:"fid"`
+	Hash string `json:"hash"`
+}
+
+type HubEmbed struct {
+	URL    string     `json:"url,omitempty"`
+	CastID *HubCastID `json:"castId,omitempty"`
+}
+
 type HubCastAddBody struct {
-	Text      string `json:"text"`
-	ParentURL string `json:"parentUrl"`
+	Text              string      `json:"text"`
+	ParentURL         string      `json:"parentUrl"`
+	ParentCastID      *HubCastID  `json:"parentCastId,omitempty"`
+	Mentions          []uint64    `json:"mentions"`
+	MentionsPositions []uint32    `json:"mentionsPositions"`
+	Embeds            []*HubEmbed `json:"embeds,omitempty"`
 }
 
 type HubMessageData struct {
@@ -17,8 +33,31 @@ type HubMessage struct {
 	HexHash string          `json:"hash"`
 }
 
+// toAPIMessage converts the hub message, which must be a cast, to an
+// APIMessage, including the parent url or the parent cast and the embedded
+// urls if any.
+func (m *HubMessage) toAPIMessage() *api.APIMessage {
+	msg := &api.APIMessage{
+		Content:   m.Data.CastAddBody.Text,
+		Author:    m.Data.From,
+		Hash:      m.HexHash,
+		ParentURL: m.Data.CastAddBody.ParentURL,
+	}
+	if parent := m.Data.CastAddBody.ParentCastID; parent != nil {
+		msg.ParentAuthor = parent.FID
+		msg.ParentHash = parent.Hash
+	}
+	for _, embed := range m.Data.CastAddBody.Embeds {
+		if embed.URL != "" {
+			msg.Embeds = append(msg.Embeds, embed.URL)
+		}
+	}
+	return msg
+}
+
 type HubMentionsResponse struct {
-	Messages []*HubMessage `json:"messages"`
+	Messages      []*HubMessage `json:"messages"`
+	NextPageToken string        `json:"nextPageToken"`
 }
 
 type UsernameProofs struct {
@@ -7,8 +7,11 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"strings"
 	"time"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/vocdoni/votebot/api"
 )
@@ -17,11 +20,16 @@ const (
 	// endpoints
 	neynarGetUsernameEndpoint = "v1/farcaster/user?fid=%d"
 	neynarGetCastsEndpoint    = "v1/farcaster/mentions-and-replies?fid=%d&limit=150&cursor=%s"
-	neynarReplyEndpoint       = "v2/farcaster/cast"
+	neynarCastEndpoint        = "v2/farcaster/cast"
+	neynarCastByHashEndpoint  = "v2/farcaster/cast?identifier=%s&type=hash"
+	neynarChannelFeedEndpoint = "v2/farcaster/feed?feed_type=filter&filter_type=parent_url&parent_url=%s&limit=100&cursor=%s"
 	neynarUserByEthAddresses  = "v2/farcaster/user/bulk-by-address?addresses=%s"
+	neynarUserByUsername      = "v1/farcaster/user-by-username?username=%s"
 	// timeouts
 	getBotUsernameTimeout   = 10 * time.Second
 	getCastByMentionTimeout = 60 * time.Second
+	getChannelCastsTimeout  = 60 * time.Second
+	getCastByHashTimeout    = 10 * time.Second
 	postCastTimeout         = 10 * time.Second
 	// other
 	neynarMentionType = "cast-mention"
@@ -128,13 +136,16 @@ func (n *NeynarAPI) LastMentions(ctx context.Context, timestamp uint64) ([]*api.
 			}
 			// parse the text to remove the bot username and add mention to the
 			// list
-			mention := fmt.Sprintf("@%s", n.username)
-			text := strings.TrimSpace(strings.TrimPrefix(notification.Text, mention))
+			text := removeMention(notification.Text, n.username)
 			messages = append(messages, &api.APIMessage{
-				IsMention: true,
-				Author:    notification.Author.FID,
-				Content:   text,
-				Hash:      notification.Hash,
+				IsMention:    true,
+				Author:       notification.Author.FID,
+				Content:      text,
+				Hash:         notification.Hash,
+				ParentURL:    notification.ParentURL,
+				ParentAuthor: notification.ParentAuthor.FID,
+				ParentHash:   notification.ParentHash,
+				Embeds:       embedURLs(notification.Embeds),
 			})
 			// update last timestamp
 			if notificationTimestamp > lastTimestamp {
@@ -150,22 +161,152 @@ func (n *NeynarAPI) LastMentions(ctx context.Context, timestamp uint64) ([]*api.
 	return messages, lastTimestamp, nil
 }
 
-func (n *NeynarAPI) Reply(ctx context.Context, fid uint64, parentHash, content string) error {
-	// create request body
-	castReq := &CastPostRequest{
-		Signer: n.signerUUID,
-		Text:   content,
-		Parent: parentHash,
+func (n *NeynarAPI) ChannelCasts(ctx context.Context, parentURL string, timestamp uint64) ([]*api.APIMessage, uint64, error) {
+	baseURL := fmt.Sprintf("%s/%s", n.endpoint, neynarChannelFeedEndpoint)
+
+	internalCtx, cancel := context.WithTimeout(ctx, getChannelCastsTimeout)
+	defer cancel()
+
+	escapedParentURL := url.QueryEscape(parentURL)
+	messages := []*api.APIMessage{}
+	lastTimestamp := timestamp
+	cursor := ""
+	for {
+		// create request with the given cursor and set the api key header
+		url := fmt.Sprintf(baseURL, escapedParentURL, cursor)
+		req, err := http.NewRequestWithContext(internalCtx, http.MethodGet, url, nil)
+		if err != nil {
+			return nil, 0, fmt.Errorf("error creating request: %w", err)
+		}
+		req.Header.Set("api_key", n.apiKey)
+		// send request and check response status
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return nil, 0, fmt.Errorf("error downloading json: %w", err)
+		}
+		if res.StatusCode != http.StatusOK {
+			return nil, 0, fmt.Errorf("error downloading json: %s", res.Status)
+		}
+		// read response body
+		body, err := io.ReadAll(res.Body)
+		if err != nil {
+			return nil, 0, fmt.Errorf("error reading response body: %w", err)
+		}
+		defer res.Body.Close()
+		// decode casts
+		feedResponse := &FeedResponse{}
+		if err := json.Unmarshal(body, feedResponse); err != nil {
+			return nil, 0, fmt.Errorf("error unmarshalling response body: %w", err)
+		}
+		// parse casts, the feed is sorted from the newest to the oldest, so
+		// stop when an old cast is found
+		oldCastFound := false
+		for _, cast := range feedResponse.Casts {
+			// parse timestamp
+			parsedTimestamp, err := time.Parse(timeLayout, cast.Timestamp)
+			if err != nil {
+				return nil, 0, fmt.Errorf("error parsing timestamp: %w", err)
+			}
+			castTimestamp := uint64(parsedTimestamp.Unix())
+			if castTimestamp <= timestamp {
+				oldCastFound = true
+				break
+			}
+			// parse the text to remove the bot username and add the cast to
+			// the list
+			text := removeMention(cast.Text, n.username)
+			messages = append(messages, &api.APIMessage{
+				IsMention:    false,
+				Author:       cast.Author.FID,
+				Content:      text,
+				Hash:         cast.Hash,
+				ParentURL:    parentURL,
+				ParentAuthor: cast.ParentAuthor.FID,
+				ParentHash:   cast.ParentHash,
+				Embeds:       embedURLs(cast.Embeds),
+			})
+			// update last timestamp
+			if castTimestamp > lastTimestamp {
+				lastTimestamp = castTimestamp
+			}
+		}
+		// stop if there are no more new casts
+		if oldCastFound || feedResponse.Next.Cursor == "" {
+			break
+		}
+		cursor = feedResponse.Next.Cursor
 	}
-	body, err := json.Marshal(castReq)
+	return messages, lastTimestamp, nil
+}
+
+func (n *NeynarAPI) CastByHash(ctx context.Context, fid uint64, hash string) (*api.APIMessage, error) {
+	internalCtx, cancel := context.WithTimeout(ctx, getCastByHashTimeout)
+	defer cancel()
+
+	// create request with the cast hash, the fid is not required by neynar
+	baseURL := fmt.Sprintf("%s/%s", n.endpoint, neynarCastByHashEndpoint)
+	url := fmt.Sprintf(baseURL, hash)
+	req, err := http.NewRequestWithContext(internalCtx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %w", err)
+	}
+	req.Header.Set("api_key", n.apiKey)
+	// send request and check response status
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error downloading json: %w", err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("error downloading json: %s", res.Status)
+	}
+	// read response body
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading response body: %w", err)
+	}
+	// decode cast
+	castResponse := &CastResponse{}
+	if err := json.Unmarshal(body, castResponse); err != nil {
+		return nil, fmt.Errorf("error unmarshalling response body: %w", err)
+	}
+	if castResponse.Cast == nil {
+		return nil, fmt.Errorf("cast not found")
+	}
+	cast := castResponse.Cast
+	return &api.APIMessage{
+		Author:       cast.Author.FID,
+		Content:      removeMention(cast.Text, n.username),
+		Hash:         cast.Hash,
+		ParentURL:    cast.ParentURL,
+		ParentAuthor: cast.ParentAuthor.FID,
+		ParentHash:   cast.ParentHash,
+		Embeds:       embedURLs(cast.Embeds),
+	}, nil
+}
+
+func (n *NeynarAPI) Reply(ctx context.Context, fid uint64, parentHash, content string, embeds ...string) (string, error) {
+	return n.postCast(ctx, parentHash, content, embeds)
+}
+
+func (n *NeynarAPI) Cast(ctx context.Context, parentURL string, content string) (string, error) {
+	return n.postCast(ctx, parentURL, content, nil)
+}
+
+func (n *NeynarAPI) DeleteCast(ctx context.Context, hash string) error {
+	// create request body
+	body, err := json.Marshal(&CastDeleteRequest{
+		Signer:     n.signerUUID,
+		TargetHash: hash,
+	})
 	if err != nil {
 		return fmt.Errorf("error marshalling request body: %w", err)
 	}
-	url := fmt.Sprintf("%s/%s", n.endpoint, neynarReplyEndpoint)
+	url := fmt.Sprintf("%s/%s", n.endpoint, neynarCastEndpoint)
 	internalCtx, cancel := context.WithTimeout(ctx, postCastTimeout)
 	defer cancel()
-	// create request with the bot fid and set the api key header
-	req, err := http.NewRequestWithContext(internalCtx, http.MethodPost, url, bytes.NewReader(body))
+	// create request and set the api key header
+	req, err := http.NewRequestWithContext(internalCtx, http.MethodDelete, url, bytes.NewReader(body))
 	if err != nil {
 		return fmt.Errorf("error creating request: %w", err)
 	}
@@ -174,10 +315,11 @@ func (n *NeynarAPI) Reply(ctx context.Context, fid uint64, parentHash, content s
 	// send request and check response status
 	res, err := http.DefaultClient.Do(req)
 	if err != nil {
-		return fmt.Errorf("error sending cast: %w", err)
+		return fmt.Errorf("error deleting cast: %w", err)
 	}
+	defer res.Body.Close()
 	if res.StatusCode != http.StatusOK {
-		return fmt.Errorf("error sending cast: %s", res.Status)
+		return fmt.Errorf("error deleting cast: %s", res.Status)
 	}
 	return nil
 }
@@ -275,3 +417,150 @@ func (n *NeynarAPI) UserDataByVerificationAddress(ctx context.Context, address s
 		VerificationsAddresses: data.VerificationsAddresses,
 	}, nil
 }
+
+// UserDataByUsername returns the fid, the custody address and the
+// verification addresses of the user with the given username. If something
+// goes wrong, it returns an error.
+func (n *NeynarAPI) UserDataByUsername(ctx context.Context, username string) (*api.Userdata, error) {
+	internalCtx, cancel := context.WithTimeout(ctx, getBotUsernameTimeout)
+	defer cancel()
+
+	baseURL := fmt.Sprintf("%s/%s", n.endpoint, neynarUserByUsername)
+	url := fmt.Sprintf(baseURL, url.QueryEscape(username))
+	req, err := http.NewRequestWithContext(internalCtx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %w", err)
+	}
+	req.Header.Set("api_key", n.apiKey)
+	// send request and check response status
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error downloading json: %w", err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("error downloading json: %s", res.Status)
+	}
+	// read response body
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading response body: %w", err)
+	}
+	// decode user data
+	userdataResponse := &UserdataResponse{}
+	if err := json.Unmarshal(body, userdataResponse); err != nil {
+		return nil, fmt.Errorf("error unmarshalling response body: %w", err)
+	}
+	if userdataResponse.Result == nil || userdataResponse.Result.User == nil || userdataResponse.Result.User.FID == 0 {
+		return nil, fmt.Errorf("no user found with username %s", username)
+	}
+	user := userdataResponse.Result.User
+	return &api.Userdata{
+		FID:                    user.FID,
+		Username:               user.Username,
+		CustodyAddress:         user.CustodyAddress,
+		VerificationsAddresses: user.VerificationsAddresses,
+	}, nil
+}
+
+// postCast publishes a new cast with the given content and embedded urls as
+// a child of the given parent, which can be the hash of another cast or the
+// url of a channel. It returns the hash of the new cast or an error if
+// something goes wrong.
+func (n *NeynarAPI) postCast(ctx context.Context, parent, content string, embeds []string) (string, error) {
+	// create request body
+	castReq := &CastPostRequest{
+		Signer: n.signerUUID,
+		Text:   content,
+		Parent: parent,
+	}
+	for _, url := range embeds {
+		castReq.Embeds = append(castReq.Embeds, &Embed{URL: url})
+	}
+	body, err := json.Marshal(castReq)
+	if err != nil {
+		return "", fmt.Errorf("error marshalling request body: %w", err)
+	}
+	url := fmt.Sprintf("%s/%s", n.endpoint, neynarCastEndpoint)
+	internalCtx, cancel := context.WithTimeout(ctx, postCastTimeout)
+	defer cancel()
+	// create request with the bot fid and set the api key header
+	req, err := http.NewRequestWithContext(internalCtx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return "", fmt.Errorf("error creating request: %w", err)
+	}
+	req.Header.Set("api_key", n.apiKey)
+	req.Header.Set("Content-Type", "application/json")
+	// send request and check response status
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("error sending cast: %w", err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("error sending cast: %s", res.Status)
+	}
+	// decode the response to get the hash of the new cast
+	resBody, err := io.ReadAll(res.Body)
+	if err != nil {
+		return "", fmt.Errorf("error reading response body: %w", err)
+	}
+	castRes := &CastPostResponse{}
+	if err := json.Unmarshal(resBody, castRes); err != nil {
+		return "", fmt.Errorf("error unmarshalling response body: %w", err)
+	}
+	return castRes.Cast.Hash, nil
+}
+
+// removeMention removes every mention to the given username from the text,
+// wherever it appears, and normalizes the resulting text to remove the extra
+// spaces left. Mentions to other users whose username starts with the given
+// one are kept.
+func removeMention(text, username string) string {
+	mention := "@" + username
+	var sb strings.Builder
+	for {
+		idx := indexFold(text, mention)
+		if idx < 0 {
+			break
+		}
+		end := idx + len(mention)
+		sb.WriteString(text[:idx])
+		if !isUsernameBoundary(text[end:]) {
+			sb.WriteString(text[idx:end])
+		}
+		text = text[end:]
+	}
+	sb.WriteString(text)
+	return api.NormalizeSpaces(sb.String())
+}
+
+// indexFold returns the index of the first case-insensitive occurrence of
+// substr in s, or -1 if it is not present.
+func indexFold(s, substr string) int {
+	for i := 0; i+len(substr) <= len(s); i++ {
+		if strings.EqualFold(s[i:i+len(substr)], substr) {
+			return i
+		}
+	}
+	return -1
+}
+
+// isUsernameBoundary returns if the given text, which follows a mention, does
+// not continue the mentioned username. Usernames can contain letters, digits,
+// dashes and dots (such as ENS names), but a trailing dot is considered a
+// punctuation mark.
+func isUsernameBoundary(rest string) bool {
+	if rest == "" {
+		return true
+	}
+	r, size := utf8.DecodeRuneInString(rest)
+	switch {
+	case unicode.IsLetter(r), unicode.IsDigit(r), r == '-', r == '_':
+		return false
+	case r == '.':
+		next, _ := utf8.DecodeRuneInString(rest[size:])
+		return !unicode.IsLetter(next) && !unicode.IsDigit(next)
+	}
+	return true
+}
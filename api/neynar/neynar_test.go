@@ -0,0 +1,73 @@
+package neynar
+
+import (
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestRemoveMention(t *testing.T) {
+	c := qt.New(t)
+
+	tests := []struct {
+		name     string
+		text     string
+		expected string
+	}{
+		{
+			name:     "no mention",
+			text:     "!poll\nWhat is your favourite colour?",
+			expected: "!poll\nWhat is your favourite colour?",
+		},
+		{
+			name:     "mention as prefix",
+			text:     "@votebot !poll\nWhat is your favourite colour?",
+			expected: "!poll\nWhat is your favourite colour?",
+		},
+		{
+			name:     "mention in its own line",
+			text:     "@votebot\n!poll\nWhat is your favourite colour?",
+			expected: "!poll\nWhat is your favourite colour?",
+		},
+		{
+			name:     "mention after the command",
+			text:     "!poll @votebot\nWhat is your favourite colour?",
+			expected: "!poll\nWhat is your favourite colour?",
+		},
+		{
+			name:     "mention in the middle of a line",
+			text:     "hey @votebot please\n!poll",
+			expected: "hey please\n!poll",
+		},
+		{
+			name:     "several mentions",
+			text:     "@votebot !poll @votebot",
+			expected: "!poll",
+		},
+		{
+			name:     "case insensitive mention",
+			text:     "@VoteBot !poll",
+			expected: "!poll",
+		},
+		{
+			name:     "mention followed by punctuation",
+			text:     "thanks @votebot. !poll",
+			expected: "thanks . !poll",
+		},
+		{
+			name:     "other mentions are kept",
+			text:     "@votebot !poll\nDo you prefer @alice or @bob?",
+			expected: "!poll\nDo you prefer @alice or @bob?",
+		},
+		{
+			name:     "usernames starting with the bot one are kept",
+			text:     "@votebot !poll\n- @votebotfan\n- @votebot-dev\n- @votebot.eth",
+			expected: "!poll\n- @votebotfan\n- @votebot-dev\n- @votebot.eth",
+		},
+	}
+	for _, test := range tests {
+		c.Run(test.name, func(c *qt.C) {
+			c.Assert(removeMention(test.text, "votebot"), qt.Equals, test.expected)
+		})
+	}
+}
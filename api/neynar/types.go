@@ -4,12 +4,31 @@ type NotificationAuthor struct {
 	FID uint64 `json:"fid"`
 }
 
+type Embed struct {
+	URL string `json:"url"`
+}
+
+// embedURLs returns the urls of the given embeds, skipping the empty ones.
+func embedURLs(embeds []*Embed) []string {
+	urls := []string{}
+	for _, embed := range embeds {
+		if embed != nil && embed.URL != "" {
+			urls = append(urls, embed.URL)
+		}
+	}
+	return urls
+}
+
 type Notification struct {
-	Hash      string             `json:"hash"`
-	Author    NotificationAuthor `json:"author"`
-	Type      string             `json:"type"`
-	Text      string             `json:"text"`
-	Timestamp string             `json:"timestamp"`
+	Hash         string             `json:"hash"`
+	Author       NotificationAuthor `json:"author"`
+	Type         string             `json:"type"`
+	Text         string             `json:"text"`
+	Timestamp    string             `json:"timestamp"`
+	ParentURL    string             `json:"parentUrl"`
+	ParentHash   string             `json:"parentHash"`
+	ParentAuthor NotificationAuthor `json:"parentAuthor"`
+	Embeds       []*Embed           `json:"embeds"`
 }
 
 type NextNotificationCursor struct {
@@ -25,10 +44,43 @@ type NotificationsResponse struct {
 	Result *NotificationsResult `json:"result"`
 }
 
+type FeedCast struct {
+	Hash         string             `json:"hash"`
+	ParentURL    string             `json:"parent_url"`
+	ParentHash   string             `json:"parent_hash"`
+	ParentAuthor NotificationAuthor `json:"parent_author"`
+	Author       NotificationAuthor `json:"author"`
+	Text         string             `json:"text"`
+	Timestamp    string             `json:"timestamp"`
+	Embeds       []*Embed           `json:"embeds"`
+}
+
+type FeedResponse struct {
+	Casts []*FeedCast            `json:"casts"`
+	Next  NextNotificationCursor `json:"next"`
+}
+
+type CastResponse struct {
+	Cast *FeedCast `json:"cast"`
+}
+
 type CastPostRequest struct {
-	Signer string `json:"signer_uuid"`
-	Text   string `json:"text"`
-	Parent string `json:"parent"`
+	Signer string   `json:"signer_uuid"`
+	Text   string   `json:"text"`
+	Parent string   `json:"parent"`
+	Embeds []*Embed `json:"embeds,omitempty"`
+}
+
+type CastPostResponse struct {
+	Success bool `json:"success"`
+	Cast    struct {
+		Hash string `json:"hash"`
+	} `json:"cast"`
+}
+
+type CastDeleteRequest struct {
+	Signer     string `json:"signer_uuid"`
+	TargetHash string `json:"target_hash"`
 }
 
 type UserdataV1 struct {
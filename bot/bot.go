@@ -9,21 +9,32 @@ import (
 	"go.vocdoni.io/dvote/log"
 )
 
-// defaultCoolDown is the default time to wait between casts
-const defaultCoolDown = time.Second * 30
+const (
+	// defaultCoolDown is the default time to wait between casts
+	defaultCoolDown = time.Second * 30
+	// seenCastsTTL is the time that the hash of a received cast is kept to
+	// avoid sending it twice when it comes from different sources
+	seenCastsTTL = time.Hour
+)
 
 type BotConfig struct {
 	API      api.API
 	CoolDown time.Duration
+	// Channels contains the parent urls of the channels to listen to, the
+	// casts published in them are sent to the messages channel along with the
+	// mentions
+	Channels []string
 }
 
 type Bot struct {
-	api      api.API
-	ctx      context.Context
-	cancel   context.CancelFunc
-	coolDown time.Duration
-	lastCast uint64
-	Messages chan *api.APIMessage
+	api          api.API
+	ctx          context.Context
+	cancel       context.CancelFunc
+	coolDown     time.Duration
+	lastCast     uint64
+	channelsLast map[string]uint64
+	seenCasts    map[string]time.Time
+	Messages     chan *api.APIMessage
 }
 
 func New(config BotConfig) (*Bot, error) {
@@ -34,11 +45,18 @@ func New(config BotConfig) (*Bot, error) {
 	if config.CoolDown == 0 {
 		config.CoolDown = defaultCoolDown
 	}
+	now := uint64(time.Now().Unix())
+	channelsLast := make(map[string]uint64, len(config.Channels))
+	for _, parentURL := range config.Channels {
+		channelsLast[parentURL] = now
+	}
 	return &Bot{
-		api:      config.API,
-		coolDown: config.CoolDown,
-		lastCast: uint64(time.Now().Unix()),
-		Messages: make(chan *api.APIMessage),
+		api:          config.API,
+		coolDown:     config.CoolDown,
+		lastCast:     now,
+		channelsLast: channelsLast,
+		seenCasts:    make(map[string]time.Time),
+		Messages:     make(chan *api.APIMessage),
 	}, nil
 }
 
@@ -58,11 +76,29 @@ func (b *Bot) Start(ctx context.Context) {
 					log.Errorf("error retrieving new casts: %s", err)
 				}
 				b.lastCast = lastCast
-				if len(messages) > 0 {
-					for _, msg := range messages {
-						b.Messages <- msg
+				// retrieve new messages from the configured channels
+				for parentURL, channelLast := range b.channelsLast {
+					casts, lastCast, err := b.api.ChannelCasts(b.ctx, parentURL, channelLast)
+					if err != nil {
+						log.Errorf("error retrieving new casts from channel %s: %s", parentURL, err)
+						continue
 					}
-				} else {
+					b.channelsLast[parentURL] = lastCast
+					messages = append(messages, casts...)
+				}
+				// send every message only once, even if it has been received
+				// from more than one source
+				b.pruneSeenCasts()
+				newMessages := 0
+				for _, msg := range messages {
+					if _, seen := b.seenCasts[msg.Hash]; seen {
+						continue
+					}
+					b.seenCasts[msg.Hash] = time.Now()
+					b.Messages <- msg
+					newMessages++
+				}
+				if newMessages == 0 {
 					log.Debugw("no new casts", "last-cast", b.lastCast)
 				}
 				<-ticker.C
@@ -78,3 +114,13 @@ func (b *Bot) Stop() {
 	b.cancel()
 	close(b.Messages)
 }
+
+// pruneSeenCasts removes the hashes of the casts that have been received
+// before the seen casts TTL.
+func (b *Bot) pruneSeenCasts() {
+	for hash, receivedAt := range b.seenCasts {
+		if time.Since(receivedAt) > seenCastsTTL {
+			delete(b.seenCasts, hash)
+		}
+	}
+}
@@ -0,0 +1,188 @@
+// Package census resolves the explicit censuses of the polls, defined as
+// lists of usernames, FIDs or addresses, inline in the cast or in a CSV or
+// JSON file, into the FIDs and addresses of the voters.
+package census
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/vocdoni/votebot/api"
+)
+
+const (
+	// MaxEntries is the max number of entries of a census
+	MaxEntries = 1000
+	// maxFileSize is the max size in bytes of a census file
+	maxFileSize = 1 << 20
+	// fetchTimeout is the timeout to download a census file
+	fetchTimeout = 10 * time.Second
+)
+
+var (
+	// addressRgx matches an hex encoded ethereum address
+	addressRgx = regexp.MustCompile(`^0x[0-9a-fA-F]{40}$`)
+	// headerCells contains the cells that identify the header row of a CSV
+	// census file
+	headerCells = map[string]bool{
+		"username": true,
+		"fid":      true,
+		"address":  true,
+		"voter":    true,
+	}
+)
+
+// Voters contains the FIDs and the addresses of the voters of a resolved
+// census.
+type Voters struct {
+	FIDs      []uint64
+	Addresses []string
+}
+
+// Fetch downloads the census file of the given url and returns its entries.
+// It returns an error if the file can not be downloaded, it is bigger than
+// the max file size or it can not be parsed.
+func Fetch(ctx context.Context, url string) ([]string, error) {
+	internalCtx, cancel := context.WithTimeout(ctx, fetchTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(internalCtx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrFetchingFile, err)
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrFetchingFile, err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w: %s", ErrFetchingFile, res.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(res.Body, maxFileSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrFetchingFile, err)
+	}
+	if len(data) > maxFileSize {
+		return nil, fmt.Errorf("%w: file bigger than %d bytes", ErrFetchingFile, maxFileSize)
+	}
+	return Parse(data)
+}
+
+// Parse returns the entries of the given census file, which can be a JSON
+// array of usernames, FIDs and addresses, or a CSV file with them in its
+// first column, optionally preceded by a header row. The empty entries and
+// the duplicated ones are discarded.
+func Parse(data []byte) ([]string, error) {
+	var rawEntries []string
+	if trimmed := bytes.TrimSpace(data); bytes.HasPrefix(trimmed, []byte("[")) {
+		values := []any{}
+		decoder := json.NewDecoder(bytes.NewReader(trimmed))
+		decoder.UseNumber()
+		if err := decoder.Decode(&values); err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrParsingFile, err)
+		}
+		for _, value := range values {
+			switch v := value.(type) {
+			case string:
+				rawEntries = append(rawEntries, v)
+			case json.Number:
+				rawEntries = append(rawEntries, v.String())
+			default:
+				return nil, fmt.Errorf("%w: invalid entry %v", ErrParsingFile, value)
+			}
+		}
+	} else {
+		reader := csv.NewReader(bytes.NewReader(data))
+		reader.FieldsPerRecord = -1
+		reader.TrimLeadingSpace = true
+		records, err := reader.ReadAll()
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrParsingFile, err)
+		}
+		for i, record := range records {
+			if len(record) == 0 {
+				continue
+			}
+			if i == 0 && headerCells[strings.ToLower(strings.TrimSpace(record[0]))] {
+				continue
+			}
+			rawEntries = append(rawEntries, record[0])
+		}
+	}
+	entries := []string{}
+	seen := map[string]bool{}
+	for _, entry := range rawEntries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" || seen[strings.ToLower(entry)] {
+			continue
+		}
+		seen[strings.ToLower(entry)] = true
+		entries = append(entries, entry)
+	}
+	if len(entries) == 0 {
+		return nil, ErrEmptyCensus
+	}
+	if len(entries) > MaxEntries {
+		return nil, fmt.Errorf("%w: %d of %d", ErrTooManyEntries, len(entries), MaxEntries)
+	}
+	return entries, nil
+}
+
+// Resolve resolves the given census entries into the FIDs and addresses of
+// the voters. The addresses are included as they are, while the usernames
+// (with or without the @ prefix) and the FIDs are resolved using the given
+// API to include the FID and the verification addresses of every user. It
+// returns the resolved voters and the entries that could not be resolved, or
+// an error if none of them could be resolved.
+func Resolve(ctx context.Context, farcaster api.API, entries []string) (*Voters, []string, error) {
+	if len(entries) > MaxEntries {
+		return nil, nil, fmt.Errorf("%w: %d of %d", ErrTooManyEntries, len(entries), MaxEntries)
+	}
+	voters := &Voters{}
+	seenFIDs := map[uint64]bool{}
+	seenAddresses := map[string]bool{}
+	addAddress := func(address string) {
+		address = strings.ToLower(address)
+		if !seenAddresses[address] {
+			voters.Addresses = append(voters.Addresses, address)
+			seenAddresses[address] = true
+		}
+	}
+	unresolved := []string{}
+	for _, entry := range entries {
+		if addressRgx.MatchString(entry) {
+			addAddress(entry)
+			continue
+		}
+		var userdata *api.Userdata
+		var err error
+		if fid, parseErr := strconv.ParseUint(entry, 10, 64); parseErr == nil {
+			userdata, err = farcaster.UserDataByFID(ctx, fid)
+		} else {
+			userdata, err = farcaster.UserDataByUsername(ctx, strings.TrimPrefix(entry, "@"))
+		}
+		if err != nil || userdata == nil || userdata.FID == 0 {
+			unresolved = append(unresolved, entry)
+			continue
+		}
+		if !seenFIDs[userdata.FID] {
+			voters.FIDs = append(voters.FIDs, userdata.FID)
+			seenFIDs[userdata.FID] = true
+		}
+		for _, address := range userdata.VerificationsAddresses {
+			addAddress(address)
+		}
+	}
+	if len(voters.FIDs) == 0 && len(voters.Addresses) == 0 {
+		return nil, unresolved, ErrEmptyCensus
+	}
+	return voters, unresolved, nil
+}
@@ -0,0 +1,120 @@
+package census
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/vocdoni/votebot/api"
+)
+
+// testAPI resolves the users of a fixed list, the rest of the methods of the
+// API are not implemented.
+type testAPI struct {
+	api.API
+	users []*api.Userdata
+}
+
+func (t *testAPI) UserDataByFID(_ context.Context, fid uint64) (*api.Userdata, error) {
+	for _, user := range t.users {
+		if user.FID == fid {
+			return user, nil
+		}
+	}
+	return nil, fmt.Errorf("user not found")
+}
+
+func (t *testAPI) UserDataByUsername(_ context.Context, username string) (*api.Userdata, error) {
+	for _, user := range t.users {
+		if user.Username == username {
+			return user, nil
+		}
+	}
+	return nil, fmt.Errorf("user not found")
+}
+
+func TestParse(t *testing.T) {
+	c := qt.New(t)
+
+	tests := []struct {
+		name     string
+		data     string
+		expected []string
+		err      error
+	}{
+		{
+			name:     "json",
+			data:     `["@alice", 3, "0x71C7656EC7ab88b098defB751B7401B5f6d8976F", "@Alice", ""]`,
+			expected: []string{"@alice", "3", "0x71C7656EC7ab88b098defB751B7401B5f6d8976F"},
+		},
+		{name: "json with objects", data: `[{"fid": 3}]`, err: ErrParsingFile},
+		{name: "invalid json", data: `["alice",`, err: ErrParsingFile},
+		{
+			name:     "csv with header",
+			data:     "username,weight\nalice,1\n bob ,2\n\n3\n",
+			expected: []string{"alice", "bob", "3"},
+		},
+		{
+			name:     "csv without header",
+			data:     "alice\nbob\n",
+			expected: []string{"alice", "bob"},
+		},
+		{name: "invalid csv", data: "\"alice\nbob", err: ErrParsingFile},
+		{name: "empty", data: "username\n", err: ErrEmptyCensus},
+	}
+	for _, test := range tests {
+		c.Run(test.name, func(c *qt.C) {
+			entries, err := Parse([]byte(test.data))
+			if test.err != nil {
+				c.Assert(err, qt.ErrorIs, test.err)
+				return
+			}
+			c.Assert(err, qt.IsNil)
+			c.Assert(entries, qt.DeepEquals, test.expected)
+		})
+	}
+}
+
+func TestFetch(t *testing.T) {
+	c := qt.New(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/census.csv" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		fmt.Fprint(w, "fid\n1\n2\n")
+	}))
+	defer server.Close()
+
+	entries, err := Fetch(context.Background(), server.URL+"/census.csv")
+	c.Assert(err, qt.IsNil)
+	c.Assert(entries, qt.DeepEquals, []string{"1", "2"})
+
+	_, err = Fetch(context.Background(), server.URL+"/missing.csv")
+	c.Assert(err, qt.ErrorIs, ErrFetchingFile)
+}
+
+func TestResolve(t *testing.T) {
+	c := qt.New(t)
+
+	farcaster := &testAPI{users: []*api.Userdata{
+		{FID: 1, Username: "alice", VerificationsAddresses: []string{"0xAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA"}},
+		{FID: 2, Username: "bob"},
+	}}
+	address := "0x71c7656ec7ab88b098defb751b7401b5f6d8976f"
+
+	voters, unresolved, err := Resolve(context.Background(), farcaster,
+		[]string{"@alice", "2", "carol", "1", address, "99"})
+	c.Assert(err, qt.IsNil)
+	c.Assert(voters.FIDs, qt.DeepEquals, []uint64{1, 2})
+	c.Assert(voters.Addresses, qt.DeepEquals, []string{"0xaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", address})
+	c.Assert(unresolved, qt.DeepEquals, []string{"carol", "99"})
+
+	_, unresolved, err = Resolve(context.Background(), farcaster, []string{"carol"})
+	c.Assert(err, qt.ErrorIs, ErrEmptyCensus)
+	c.Assert(unresolved, qt.DeepEquals, []string{"carol"})
+}
@@ -0,0 +1,10 @@
+package census
+
+import "fmt"
+
+var (
+	ErrFetchingFile   = fmt.Errorf("error fetching census file")
+	ErrParsingFile    = fmt.Errorf("error parsing census file")
+	ErrTooManyEntries = fmt.Errorf("too many census entries")
+	ErrEmptyCensus    = fmt.Errorf("empty census")
+)
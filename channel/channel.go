@@ -0,0 +1,151 @@
+package channel
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/vocdoni/votebot/poll"
+)
+
+// Config represents the configuration of the bot for a farcaster channel,
+// identified by its parent url. If the channel is not enabled, the bot will
+// ignore the polls requested from it. If listen is set, the bot will handle
+// the commands published in the channel even if the bot is not mentioned. The
+// min and max number of options and the min, max and default durations
+// override the base poll config when they are set, and if announce is set,
+// the bot will publish a top-level cast in the channel for every poll created
+// from it.
+type Config struct {
+	URL             string
+	Enabled         bool
+	Listen          bool
+	DefaultDuration time.Duration
+	MinDuration     time.Duration
+	MaxDuration     time.Duration
+	MinOptions      int
+	MaxOptions      int
+	Announce        bool
+}
+
+// jsonConfig is the representation of a channel config in the config file,
+// it allows to define the durations as human readable strings.
+type jsonConfig struct {
+	URL             string `json:"url"`
+	Enabled         bool   `json:"enabled"`
+	Listen          bool   `json:"listen"`
+	DefaultDuration string `json:"defaultDuration"`
+	MinDuration     string `json:"minDuration"`
+	MaxDuration     string `json:"maxDuration"`
+	MinOptions      int    `json:"minOptions"`
+	MaxOptions      int    `json:"maxOptions"`
+	Announce        bool   `json:"announce"`
+}
+
+type jsonConfigFile struct {
+	Channels []*jsonConfig `json:"channels"`
+}
+
+// Load reads the channels config from the JSON file in the given path and
+// returns them indexed by their url. The file should follow the format:
+//
+//	{
+//	  "channels": [
+//	    {
+//	      "url": "https://warpcast.com/~/channel/vocdoni",
+//	      "enabled": true,
+//	      "listen": true,
+//	      "defaultDuration": "2d",
+//	      "minDuration": "1h",
+//	      "maxDuration": "4w",
+//	      "minOptions": 2,
+//	      "maxOptions": 4,
+//	      "announce": true
+//	    }
+//	  ]
+//	}
+func Load(path string) (map[string]*Config, error) {
+	body, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Join(ErrReadingConfig, err)
+	}
+	file := &jsonConfigFile{}
+	if err := json.Unmarshal(body, file); err != nil {
+		return nil, errors.Join(ErrInvalidConfig, err)
+	}
+	channels := make(map[string]*Config, len(file.Channels))
+	for _, c := range file.Channels {
+		if c.URL == "" {
+			return nil, ErrChannelURLNotSet
+		}
+		if _, ok := channels[c.URL]; ok {
+			return nil, fmt.Errorf("%w: %s", ErrDuplicatedURL, c.URL)
+		}
+		if c.MinOptions < 0 || c.MaxOptions < 0 {
+			return nil, fmt.Errorf("%w: negative number of options for %s", ErrInvalidConfig, c.URL)
+		}
+		config := &Config{
+			URL:        c.URL,
+			Enabled:    c.Enabled,
+			Listen:     c.Listen,
+			MinOptions: c.MinOptions,
+			MaxOptions: c.MaxOptions,
+			Announce:   c.Announce,
+		}
+		for _, duration := range []struct {
+			name   string
+			value  string
+			target *time.Duration
+		}{
+			{"default duration", c.DefaultDuration, &config.DefaultDuration},
+			{"min duration", c.MinDuration, &config.MinDuration},
+			{"max duration", c.MaxDuration, &config.MaxDuration},
+		} {
+			if duration.value == "" {
+				continue
+			}
+			if *duration.target, err = poll.ParseDuration(duration.value); err != nil {
+				return nil, fmt.Errorf("%w: invalid %s for %s: %w", ErrInvalidConfig, duration.name, c.URL, err)
+			}
+		}
+		channels[c.URL] = config
+	}
+	return channels, nil
+}
+
+// IsEnabled returns if the bot should handle the polls requested from the
+// channel. A nil config, which means that the channel is not configured,
+// is considered enabled.
+func (c *Config) IsEnabled() bool {
+	return c == nil || c.Enabled
+}
+
+// ListenURLs returns the parent urls of the enabled channels that the bot
+// should listen to.
+func ListenURLs(channels map[string]*Config) []string {
+	urls := []string{}
+	for url, config := range channels {
+		if config.Enabled && config.Listen {
+			urls = append(urls, url)
+		}
+	}
+	return urls
+}
+
+// PollConfig returns the given base poll config with the overrides of the
+// channel applied. If the config is nil, the base config is returned as it
+// is.
+func (c *Config) PollConfig(base poll.PollConfig) poll.PollConfig {
+	if c == nil {
+		return base
+	}
+	return base.Override(poll.Limits{
+		MinOptions:      c.MinOptions,
+		MaxOptions:      c.MaxOptions,
+		MinDuration:     c.MinDuration,
+		MaxDuration:     c.MaxDuration,
+		DefaultDuration: c.DefaultDuration,
+	})
+}
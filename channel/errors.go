@@ -0,0 +1,10 @@
+package channel
+
+import "fmt"
+
+var (
+	ErrReadingConfig    = fmt.Errorf("error reading channels config")
+	ErrInvalidConfig    = fmt.Errorf("invalid channels config")
+	ErrDuplicatedURL    = fmt.Errorf("duplicated channel url")
+	ErrChannelURLNotSet = fmt.Errorf("channel url not set")
+)
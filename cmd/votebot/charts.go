@@ -0,0 +1,92 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/vocdoni/votebot/election"
+	"github.com/vocdoni/votebot/ledger"
+	"github.com/vocdoni/votebot/results"
+	"go.vocdoni.io/dvote/log"
+)
+
+const (
+	// chartsPath is the path of the http server where the results charts are
+	// served, followed by the election id and the chartExtension
+	chartsPath     = "/charts/"
+	chartExtension = ".png"
+	// liveChartMaxAge and finalChartMaxAge are the times that the clients can
+	// cache the charts of the ongoing and the ended polls
+	liveChartMaxAge  = time.Minute
+	finalChartMaxAge = 24 * time.Hour
+)
+
+// chartURL returns the public url of the results chart of the given poll, or
+// an empty string if the bot does not serve the charts. The url of the live
+// charts includes the current time to avoid the cached versions.
+func (h *commandHandler) chartURL(entry *ledger.Entry, live bool) string {
+	if h.publicURL == "" || entry.ElectionID == "" {
+		return ""
+	}
+	chartURL := fmt.Sprintf("%s%s%s%s", strings.TrimSuffix(h.publicURL, "/"),
+		chartsPath, url.PathEscape(entry.ElectionID), chartExtension)
+	if live {
+		chartURL += fmt.Sprintf("?t=%d", time.Now().Unix())
+	}
+	return chartURL
+}
+
+// serveChart serves the PNG chart with the current results of the poll whose
+// election id is in the path of the request.
+func (h *commandHandler) serveChart(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	electionID := strings.TrimPrefix(r.URL.Path, chartsPath)
+	if !strings.HasSuffix(electionID, chartExtension) {
+		http.NotFound(w, r)
+		return
+	}
+	electionID = strings.TrimSuffix(electionID, chartExtension)
+	entry, err := h.polls.Get(electionID)
+	if err != nil || entry.ElectionID != electionID {
+		http.NotFound(w, r)
+		return
+	}
+	electionResults, err := h.elections.Results(r.Context(), electionID)
+	if err != nil {
+		log.Errorf("error getting election results for the chart: %s", err)
+		if errors.Is(err, election.ErrElectionNotFound) {
+			http.NotFound(w, r)
+			return
+		}
+		http.Error(w, "error getting results", http.StatusBadGateway)
+		return
+	}
+	summary, err := results.New(entry.Question, entry.Options, entry.VoteType, electionResults)
+	if err != nil {
+		log.Errorf("error summarizing election results for the chart: %s", err)
+		http.Error(w, "error getting results", http.StatusInternalServerError)
+		return
+	}
+	chart, err := summary.PNG(entry.EndDate)
+	if err != nil {
+		log.Errorf("error rendering results chart: %s", err)
+		http.Error(w, "error rendering chart", http.StatusInternalServerError)
+		return
+	}
+	maxAge := liveChartMaxAge
+	if summary.Final {
+		maxAge = finalChartMaxAge
+	}
+	w.Header().Set("Content-Type", "image/png")
+	w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(maxAge.Seconds())))
+	if _, err := w.Write(chart); err != nil {
+		log.Warnw("error writing results chart", "error", err)
+	}
+}
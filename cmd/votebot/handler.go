@@ -0,0 +1,559 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vocdoni/votebot/api"
+	"github.com/vocdoni/votebot/census"
+	"github.com/vocdoni/votebot/channel"
+	"github.com/vocdoni/votebot/election"
+	"github.com/vocdoni/votebot/ledger"
+	"github.com/vocdoni/votebot/poll"
+	"github.com/vocdoni/votebot/results"
+	"go.vocdoni.io/dvote/log"
+)
+
+const (
+	// commandPrefix is the prefix of every bot command, it is used to detect
+	// the commands published in the channels that the bot listens to
+	commandPrefix = "!"
+	// pollCommand is the command to create a new poll
+	pollCommand = "!poll"
+	// helpCommand is the command that anyone can use to get the usage of
+	// the bot and the poll limits that apply to them
+	helpCommand = "!help"
+	// deleteCommand is the command that the author of a poll can use to
+	// delete the bot reply with the election frame
+	deleteCommand = "!delete"
+	// resultsCommand is the command that anyone can use to get the current
+	// results of a poll
+	resultsCommand = "!results"
+	// closeCommand is the command that the author of a poll can use to end
+	// its voting before the end date
+	closeCommand = "!close"
+	// dateLayout is the layout used to show dates to the users
+	dateLayout = "2006-01-02 15:04 UTC"
+	// maxUnresolvedShown is the max number of unresolved census entries
+	// listed to the author of a poll
+	maxUnresolvedShown = 5
+	// maxResultsDelay is the max time to wait for the final results of an
+	// election after its end date, after that, the results are not published
+	maxResultsDelay = 24 * time.Hour
+)
+
+// commandHandler handles the commands received by the bot, it contains the
+// API to interact with farcaster, the ledger of the polls created by the bot,
+// the base poll config with the poll templates, the tiers of users and the
+// channels config, which override the base poll config, and the backend to
+// create the elections. If the public url of the bot http server is set, the
+// results replies embed the charts served by it.
+type commandHandler struct {
+	api        api.API
+	polls      *ledger.Ledger
+	pollConfig poll.PollConfig
+	tiers      poll.Tiers
+	channels   map[string]*channel.Config
+	elections  election.Creator
+	publicURL  string
+	// pollsMtx serializes the commands that change the state of the polls
+	// and the periodic checks that notify them, which run in different
+	// goroutines, so they always work with the current state of the polls
+	pollsMtx sync.Mutex
+}
+
+// newPoll tries to parse the message as a poll, creates the election frame
+// and replies to the user with the frame url. If the message comes from a
+// channel, the channel config is applied to the poll and, if it is enabled,
+// a top-level announcement is published in the channel. The created poll is
+// stored in the ledger to allow the author to manage it later.
+func (h *commandHandler) newPoll(ctx context.Context, msg *api.APIMessage) {
+	// get the config of the channel where the message was published, if
+	// the channel is disabled, skip the message
+	channelConfig := h.channels[msg.ParentURL]
+	if !channelConfig.IsEnabled() {
+		log.Debugw("poll requested from a disabled channel", "channel", msg.ParentURL)
+		return
+	}
+	// try to parse the message as a poll with the config that applies to its
+	// author in the channel, if the question is not set and the message is a
+	// reply to another cast, use the content of the parent cast as the
+	// question, if it fails and the message is a poll command, reply to the
+	// author with the error and the poll limits
+	pollConfig := h.userPollConfig(msg)
+	userPoll, err := poll.ParseString(msg.Content, pollConfig)
+	if errors.Is(err, poll.ErrQuestionNotSet) && msg.ParentHash != "" {
+		var question string
+		if question, err = h.parentQuestion(ctx, msg); err == nil {
+			userPoll, err = poll.ParseStringWithQuestion(msg.Content, question, pollConfig)
+		}
+	}
+	if err != nil {
+		log.Errorf("error parsing poll: %s", err)
+		if !errors.Is(err, poll.ErrUnrecognisedCommand) {
+			h.reply(ctx, msg, pollErrorText(err, pollConfig))
+		}
+		return
+	}
+	// no election backend can require a minimum participation, so reject the
+	// polls with a quorum instead of creating them without it
+	if !userPoll.Quorum.IsZero() {
+		log.Errorf("error creating poll: %s: %s", election.ErrUnsupportedQuorum, userPoll.Quorum)
+		h.reply(ctx, msg, createErrorText(election.ErrUnsupportedQuorum))
+		return
+	}
+	// get the user data such as username, custody address and verification
+	// addresses to create the election frame
+	userdata, err := h.api.UserDataByFID(ctx, msg.Author)
+	if err != nil {
+		log.Errorf("error getting user data: %s", err)
+		return
+	}
+	log.Infow("new poll",
+		"poll", userPoll,
+		"userdata", userdata,
+		"channel", msg.ParentURL)
+	// create a new poll and send the result to the user, if the poll has a
+	// scheduled start, the duration is counted from it. The exact end date is
+	// also set for the backends that support it
+	electionOpts := &election.ElectionOptions{
+		Author: &election.Profile{
+			FID:           msg.Author,
+			Custody:       userdata.CustodyAddress,
+			Verifications: userdata.VerificationsAddresses,
+		},
+		Question:      userPoll.Question,
+		Description:   userPoll.Description,
+		Options:       userPoll.Options,
+		Duration:      election.DurationHours(time.Until(userPoll.EndDate)),
+		EndDate:       &userPoll.EndDate,
+		VoteType:      string(userPoll.Type),
+		MaxSelections: userPoll.MaxSelections,
+	}
+	if !userPoll.StartDate.IsZero() {
+		electionOpts.StartDate = &userPoll.StartDate
+		electionOpts.Duration = election.DurationHours(userPoll.Duration)
+	}
+	// resolve the census of the poll, reporting the entries that can not be
+	// resolved to the author
+	var unresolved []string
+	if userPoll.Census != nil {
+		if electionOpts.Census, unresolved, err = h.electionCensus(ctx, userPoll.Census, msg); err != nil {
+			log.Errorf("error resolving poll census: %s", err)
+			text := "I can't resolve the census of your poll 😕"
+			if len(unresolved) > 0 {
+				text += " " + unresolvedText(unresolved)
+			}
+			h.reply(ctx, msg, text)
+			return
+		}
+	}
+	newElection, err := h.elections.Create(ctx, electionOpts)
+	if err != nil {
+		log.Errorf("error creating election frame: %s", err)
+		h.reply(ctx, msg, createErrorText(err))
+		return
+	}
+	frameURL := newElection.URL
+	// compose the reply text and send it to the user as a reply to the
+	// original cast
+	replyText := fmt.Sprintf("Here is your election 🗳️ frame url! %s", frameURL)
+	if !userPoll.StartDate.IsZero() {
+		replyText = fmt.Sprintf("Here is your election 🗳️ frame url! Voting opens on %s %s",
+			userPoll.StartDate.UTC().Format(dateLayout), frameURL)
+	}
+	if len(unresolved) > 0 {
+		replyText += "\n" + unresolvedText(unresolved)
+	}
+	replyHash, err := h.api.Reply(ctx, msg.Author, msg.Hash, replyText)
+	if err != nil {
+		log.Errorf("error replying to cast: %s", err)
+		return
+	}
+	entry := &ledger.Entry{
+		Author:     msg.Author,
+		CastHash:   msg.Hash,
+		ReplyHash:  replyHash,
+		ElectionID: newElection.ID,
+		FrameURL:   frameURL,
+		ParentURL:  msg.ParentURL,
+		Question:   userPoll.Question,
+		Options:    userPoll.Options,
+		VoteType:   string(userPoll.Type),
+		CreatedAt:  time.Now(),
+		StartDate:  userPoll.StartDate,
+		EndDate:    userPoll.EndDate,
+	}
+	// if the channel requires it, announce the poll in the channel
+	if channelConfig != nil && channelConfig.Announce {
+		announceText := fmt.Sprintf("📢 New poll by @%s: %s\n%s", userdata.Username, userPoll.Question, frameURL)
+		if entry.AnnounceHash, err = h.api.Cast(ctx, msg.ParentURL, announceText); err != nil {
+			log.Errorf("error announcing poll in channel: %s", err)
+		}
+	}
+	// store the poll in the ledger
+	if err := h.polls.Add(entry); err != nil {
+		log.Errorf("error storing poll: %s", err)
+	}
+}
+
+// parentQuestion composes a poll question from the content of the cast that
+// the given message replies to, attributing it to its author.
+func (h *commandHandler) parentQuestion(ctx context.Context, msg *api.APIMessage) (string, error) {
+	parent, err := h.api.CastByHash(ctx, msg.ParentAuthor, msg.ParentHash)
+	if err != nil {
+		return "", fmt.Errorf("error getting parent cast: %w", err)
+	}
+	text := strings.TrimSpace(parent.Content)
+	if text == "" {
+		return "", fmt.Errorf("parent cast has no text")
+	}
+	parentAuthor, err := h.api.UserDataByFID(ctx, parent.Author)
+	if err != nil {
+		return "", fmt.Errorf("error getting parent cast author: %w", err)
+	}
+	return fmt.Sprintf("%s (by @%s)", text, parentAuthor.Username), nil
+}
+
+// deletePoll deletes the bot reply of a poll created by the author of the
+// message, and its announcement in the channel if any. The poll can be
+// referenced by the hash of the cast that requested it, the hash of the bot
+// reply, the frame url or the election id; if no reference is provided, the
+// poll of the cast that the message replies to is deleted, or the last poll
+// of the author if the message is not a reply, see referencedPoll. Only the
+// author of the poll can delete it. The election is also canceled if the election
+// backend supports it, if not, the author is notified about it.
+func (h *commandHandler) deletePoll(ctx context.Context, msg *api.APIMessage) {
+	h.pollsMtx.Lock()
+	defer h.pollsMtx.Unlock()
+	// get the poll referenced by the command, by the parent cast or the last
+	// one of the author
+	entry, err := h.referencedPoll(msg, deleteCommand)
+	if err != nil {
+		log.Errorf("error getting poll to delete: %s", err)
+		h.reply(ctx, msg, "I can't find any poll to delete 🤷")
+		return
+	}
+	// check that the author of the message is the author of the poll
+	if entry.Author != msg.Author {
+		log.Warnw("unauthorized poll deletion", "author", entry.Author, "requester", msg.Author)
+		h.reply(ctx, msg, "Only the author of the poll can delete it 🙅")
+		return
+	}
+	// delete the bot reply and the announcement, and remove the poll from the
+	// ledger
+	if err := h.api.DeleteCast(ctx, entry.ReplyHash); err != nil {
+		log.Errorf("error deleting cast: %s", err)
+		return
+	}
+	if entry.AnnounceHash != "" {
+		if err := h.api.DeleteCast(ctx, entry.AnnounceHash); err != nil {
+			log.Errorf("error deleting announcement cast: %s", err)
+		}
+	}
+	if err := h.polls.Delete(entry.CastHash); err != nil {
+		log.Errorf("error deleting poll from ledger: %s", err)
+	}
+	log.Infow("poll deleted", "author", entry.Author, "frame", entry.FrameURL)
+	// try to cancel the election, which is not supported by every backend
+	if entry.ElectionID != "" {
+		err := h.elections.Cancel(ctx, entry.ElectionID)
+		if err == nil {
+			h.reply(ctx, msg, "Your poll has been deleted and its election canceled 🗑️")
+			return
+		}
+		if !errors.Is(err, election.ErrCancelNotSupported) {
+			log.Errorf("error canceling election: %s", err)
+		}
+	}
+	h.reply(ctx, msg, "Your poll reply has been deleted 🗑️ The election can't be canceled, but nobody will find it through me anymore.")
+}
+
+// showResults replies to the message with the current results of a poll as a
+// text bar chart, with the remaining time to vote, embedding the image chart
+// if the bot serves them. The poll is referenced as in deletePoll, but anyone
+// can get its results.
+func (h *commandHandler) showResults(ctx context.Context, msg *api.APIMessage) {
+	entry, err := h.referencedPoll(msg, resultsCommand)
+	if err != nil {
+		log.Errorf("error getting poll to show results: %s", err)
+		h.reply(ctx, msg, "I can't find the poll 🤷 Reply to it or include its election id")
+		return
+	}
+	if entry.ElectionID == "" {
+		h.reply(ctx, msg, "I can't get the results of this poll 😕")
+		return
+	}
+	status, err := h.elections.Status(ctx, entry.ElectionID)
+	if err != nil {
+		log.Errorf("error getting election status: %s", err)
+		h.reply(ctx, msg, "I can't get the results of this poll right now 😕 Try again later")
+		return
+	}
+	if status.Status == election.StatusUpcoming {
+		h.reply(ctx, msg, fmt.Sprintf("Voting has not started yet ⏳ It opens on %s",
+			status.StartDate.UTC().Format(dateLayout)))
+		return
+	}
+	electionResults, err := h.elections.Results(ctx, entry.ElectionID)
+	if err != nil {
+		log.Errorf("error getting election results: %s", err)
+		h.reply(ctx, msg, "I can't get the results of this poll right now 😕 Try again later")
+		return
+	}
+	summary, err := results.New(entry.Question, entry.Options, entry.VoteType, electionResults)
+	if err != nil {
+		log.Errorf("error summarizing election results: %s", err)
+		h.reply(ctx, msg, "I can't get the results of this poll 😕")
+		return
+	}
+	var remaining time.Duration
+	if status.Status == election.StatusOngoing {
+		remaining = time.Until(status.EndDate)
+	}
+	text := summary.BarChart(remaining)
+	if _, err := h.api.Reply(ctx, msg.Author, msg.Hash, text, embedURLs(h.chartURL(entry, true))...); err != nil {
+		log.Errorf("error replying to cast: %s", err)
+	}
+}
+
+// closePoll ends the voting of a poll created by the author of the message
+// before its end date, if the election backend supports it, and replies with
+// its final results. The poll is referenced as in deletePoll. If the results
+// are not final yet, the author is notified and they are published in the
+// thread of the poll when they are ready, see notifyEndedPolls.
+func (h *commandHandler) closePoll(ctx context.Context, msg *api.APIMessage) {
+	h.pollsMtx.Lock()
+	defer h.pollsMtx.Unlock()
+	entry, err := h.referencedPoll(msg, closeCommand)
+	if err != nil {
+		log.Errorf("error getting poll to close: %s", err)
+		h.reply(ctx, msg, "I can't find any poll to close 🤷")
+		return
+	}
+	// check that the author of the message is the author of the poll
+	if entry.Author != msg.Author {
+		log.Warnw("unauthorized poll close", "author", entry.Author, "requester", msg.Author)
+		h.reply(ctx, msg, "Only the author of the poll can close it 🙅")
+		return
+	}
+	if entry.ElectionID == "" || entry.ResultsNotified || !entry.EndDate.After(time.Now()) {
+		h.reply(ctx, msg, "This poll has already ended 🏁")
+		return
+	}
+	// end the election, which is not supported by every backend
+	if err := h.elections.End(ctx, entry.ElectionID); err != nil {
+		switch {
+		case errors.Is(err, election.ErrEndNotSupported):
+			h.reply(ctx, msg, "Sorry, this poll can't be closed before its end date 😕")
+		case errors.Is(err, election.ErrElectionNotActive):
+			h.reply(ctx, msg, "This poll is not open, it can't be closed 😕")
+		default:
+			log.Errorf("error ending election: %s", err)
+			h.reply(ctx, msg, "I can't close your poll right now 😕 Try again later")
+		}
+		return
+	}
+	log.Infow("poll closed", "author", entry.Author, "election", entry.ElectionID)
+	// update the end date of the poll and reply with the final results if
+	// they are ready
+	entry.EndDate = time.Now()
+	text, err := h.resultsText(ctx, entry)
+	if err != nil {
+		log.Debugw("poll results not available yet", "election", entry.ElectionID, "error", err)
+		h.reply(ctx, msg, "Your poll has been closed 🔒 I'll publish the final results as soon as they are ready")
+	} else if _, err := h.api.Reply(ctx, msg.Author, msg.Hash, "Your poll has been closed 🔒\n"+text,
+		embedURLs(h.chartURL(entry, false))...); err != nil {
+		log.Errorf("error replying to cast: %s", err)
+	} else {
+		entry.ResultsNotified = true
+	}
+	if err := h.polls.Update(entry); err != nil {
+		log.Errorf("error updating poll: %s", err)
+	}
+}
+
+// isCommand returns if the given message content starts with the given
+// command as a whole word, so '!deleted' is not the delete command.
+func isCommand(content, command string) bool {
+	fields := strings.Fields(content)
+	return len(fields) > 0 && fields[0] == command
+}
+
+// referencedPoll returns the poll referenced by the given command message: by
+// the argument of the command, which can be the hash of the cast that
+// requested the poll, the hash of the bot reply, the frame url or the
+// election id; by the cast that the message replies to; or, if the message is
+// not a reply, the last poll of the author of the message. If the message is
+// a reply to a cast that is not a poll, no poll is returned, so the commands
+// published in other threads do not apply to the last poll of the author.
+func (h *commandHandler) referencedPoll(msg *api.APIMessage, command string) (*ledger.Entry, error) {
+	ref := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(msg.Content), command))
+	switch {
+	case ref != "":
+		return h.polls.Get(ref)
+	case msg.ParentHash != "":
+		return h.polls.Get(msg.ParentHash)
+	default:
+		return h.polls.LastByAuthor(msg.Author)
+	}
+}
+
+// notifyStartedPolls replies in the thread of every poll with a scheduled
+// start whose voting has already opened, to remind the users that they can
+// vote. Every poll is notified only once, and not if it has already ended.
+func (h *commandHandler) notifyStartedPolls(ctx context.Context) {
+	h.pollsMtx.Lock()
+	defer h.pollsMtx.Unlock()
+	now := time.Now()
+	started := h.polls.Filter(func(e *ledger.Entry) bool {
+		return !e.StartDate.IsZero() && !e.StartNotified && !e.StartDate.After(now) && e.EndDate.After(now)
+	})
+	for _, entry := range started {
+		text := fmt.Sprintf("🗳️ Voting is now open! %s", entry.FrameURL)
+		if _, err := h.api.Reply(ctx, entry.Author, entry.CastHash, text); err != nil {
+			log.Errorf("error notifying poll start: %s", err)
+			continue
+		}
+		entry.StartNotified = true
+		if err := h.polls.Update(entry); err != nil {
+			log.Errorf("error updating poll: %s", err)
+		}
+	}
+}
+
+// notifyEndedPolls replies in the thread of every poll whose election has
+// ended with its final results, embedding the image chart if the bot serves
+// them. If the results are not final yet, the poll is
+// checked again in the next call, up to maxResultsDelay after its end date.
+// Every poll is notified only once.
+func (h *commandHandler) notifyEndedPolls(ctx context.Context) {
+	h.pollsMtx.Lock()
+	defer h.pollsMtx.Unlock()
+	now := time.Now()
+	ended := h.polls.Filter(func(e *ledger.Entry) bool {
+		return e.ElectionID != "" && !e.ResultsNotified && !e.EndDate.After(now)
+	})
+	for _, entry := range ended {
+		text, err := h.resultsText(ctx, entry)
+		if err != nil {
+			if now.Sub(entry.EndDate) < maxResultsDelay {
+				log.Debugw("poll results not available yet", "election", entry.ElectionID, "error", err)
+				continue
+			}
+			log.Errorf("error getting poll results, giving up: %s", err)
+		} else if _, err := h.api.Reply(ctx, entry.Author, entry.CastHash, text, embedURLs(h.chartURL(entry, false))...); err != nil {
+			log.Errorf("error notifying poll results: %s", err)
+			continue
+		}
+		entry.ResultsNotified = true
+		if err := h.polls.Update(entry); err != nil {
+			log.Errorf("error updating poll: %s", err)
+		}
+	}
+}
+
+// resultsText gets the final results of the election of the given poll and
+// composes the text to publish them. It returns an error if the results are
+// not final yet.
+func (h *commandHandler) resultsText(ctx context.Context, entry *ledger.Entry) (string, error) {
+	electionResults, err := h.elections.Results(ctx, entry.ElectionID)
+	if err != nil {
+		return "", fmt.Errorf("error getting election results: %w", err)
+	}
+	if !electionResults.Final {
+		return "", fmt.Errorf("results of election %s are not final", entry.ElectionID)
+	}
+	summary, err := results.New(entry.Question, entry.Options, entry.VoteType, electionResults)
+	if err != nil {
+		return "", fmt.Errorf("error summarizing election results: %w", err)
+	}
+	return summary.Text(), nil
+}
+
+// reply sends the given text as a reply to the given message, logging the
+// error if something goes wrong.
+func (h *commandHandler) reply(ctx context.Context, msg *api.APIMessage, text string) {
+	if _, err := h.api.Reply(ctx, msg.Author, msg.Hash, text); err != nil {
+		log.Errorf("error replying to cast: %s", err)
+	}
+}
+
+// embedURLs returns the non empty urls of the given ones to embed them in a
+// cast.
+func embedURLs(urls ...string) []string {
+	embeds := []string{}
+	for _, url := range urls {
+		if url != "" {
+			embeds = append(embeds, url)
+		}
+	}
+	return embeds
+}
+
+// electionCensus returns the election census that corresponds to the census
+// of a poll. If the census refers to the current channel, the url of the
+// channel where the poll was published is used. The lists of usernames and
+// the census files, which are downloaded from the given url or the first url
+// embedded in the message, are resolved into a voters census. It returns the
+// census entries that can not be resolved, and an error if the census can
+// not be resolved at all.
+func (h *commandHandler) electionCensus(ctx context.Context, pollCensus *poll.Census, msg *api.APIMessage) (*election.Census, []string, error) {
+	switch pollCensus.Type {
+	case poll.CensusTypeUsernames, poll.CensusTypeFile:
+		entries := pollCensus.Usernames
+		if pollCensus.Type == poll.CensusTypeFile {
+			fileURL := pollCensus.URL
+			if fileURL == "" {
+				if len(msg.Embeds) == 0 {
+					return nil, nil, fmt.Errorf("census file not embedded")
+				}
+				fileURL = msg.Embeds[0]
+			}
+			var err error
+			if entries, err = census.Fetch(ctx, fileURL); err != nil {
+				return nil, nil, err
+			}
+		}
+		voters, unresolved, err := census.Resolve(ctx, h.api, entries)
+		if err != nil {
+			return nil, unresolved, err
+		}
+		return &election.Census{
+			Type:      election.CensusTypeVoters,
+			FIDs:      voters.FIDs,
+			Addresses: voters.Addresses,
+		}, unresolved, nil
+	}
+	electionCensus := &election.Census{
+		Type:      string(pollCensus.Type),
+		Channel:   pollCensus.Channel,
+		Contract:  pollCensus.Contract,
+		Network:   pollCensus.Network,
+		Weighted:  pollCensus.Weighted,
+		FIDs:      pollCensus.FIDs,
+		Addresses: pollCensus.Addresses,
+	}
+	if pollCensus.Type == poll.CensusTypeChannel && pollCensus.Channel == "" {
+		electionCensus.Channel = msg.ParentURL
+	}
+	return electionCensus, nil, nil
+}
+
+// unresolvedText composes the text to report the given unresolved census
+// entries to the author of a poll, listing up to maxUnresolvedShown of them.
+func unresolvedText(unresolved []string) string {
+	shown := unresolved
+	if len(shown) > maxUnresolvedShown {
+		shown = shown[:maxUnresolvedShown]
+	}
+	text := fmt.Sprintf("⚠️ These voters can't be found: %s", strings.Join(shown, ", "))
+	if hidden := len(unresolved) - len(shown); hidden > 0 {
+		text += fmt.Sprintf(" and %d more", hidden)
+	}
+	return text
+}
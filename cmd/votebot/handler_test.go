@@ -0,0 +1,753 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/vocdoni/votebot/api"
+	"github.com/vocdoni/votebot/channel"
+	"github.com/vocdoni/votebot/election"
+	"github.com/vocdoni/votebot/frameserver"
+	"github.com/vocdoni/votebot/ledger"
+	"github.com/vocdoni/votebot/poll"
+	"github.com/vocdoni/votebot/results"
+)
+
+// channelURL is the url of the channel used in the tests
+const channelURL = "https://warpcast.com/~/channel/vocdoni"
+
+// testCast is a cast published by the testAPI.
+type testCast struct {
+	Hash       string
+	ParentURL  string
+	ParentHash string
+	Content    string
+	Embeds     []string
+}
+
+// testAPI is an in-memory api.API that records the casts published by the
+// bot and resolves the users of a fixed list.
+type testAPI struct {
+	mtx     sync.Mutex
+	users   map[uint64]*api.Userdata
+	casts   map[string]*api.APIMessage
+	sent    []*testCast
+	deleted []string
+}
+
+func newTestAPI(users ...*api.Userdata) *testAPI {
+	t := &testAPI{
+		users: make(map[uint64]*api.Userdata),
+		casts: make(map[string]*api.APIMessage),
+	}
+	for _, user := range users {
+		t.users[user.FID] = user
+	}
+	return t
+}
+
+func (t *testAPI) Init(...any) error { return nil }
+
+func (t *testAPI) Stop() error { return nil }
+
+func (t *testAPI) LastMentions(_ context.Context, timestamp uint64) ([]*api.APIMessage, uint64, error) {
+	return nil, timestamp, nil
+}
+
+func (t *testAPI) ChannelCasts(_ context.Context, _ string, timestamp uint64) ([]*api.APIMessage, uint64, error) {
+	return nil, timestamp, nil
+}
+
+func (t *testAPI) CastByHash(_ context.Context, _ uint64, hash string) (*api.APIMessage, error) {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	if cast, ok := t.casts[hash]; ok {
+		return cast, nil
+	}
+	return nil, fmt.Errorf("cast not found")
+}
+
+func (t *testAPI) Reply(_ context.Context, _ uint64, hash string, content string, embeds ...string) (string, error) {
+	return t.publish(&testCast{ParentHash: hash, Content: content, Embeds: embeds}), nil
+}
+
+func (t *testAPI) Cast(_ context.Context, parentURL string, content string) (string, error) {
+	return t.publish(&testCast{ParentURL: parentURL, Content: content}), nil
+}
+
+func (t *testAPI) DeleteCast(_ context.Context, hash string) error {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	t.deleted = append(t.deleted, hash)
+	return nil
+}
+
+func (t *testAPI) UserDataByFID(_ context.Context, fid uint64) (*api.Userdata, error) {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	if user, ok := t.users[fid]; ok {
+		return user, nil
+	}
+	return nil, fmt.Errorf("user not found")
+}
+
+func (t *testAPI) UserDataByVerificationAddress(_ context.Context, _ string) (*api.Userdata, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (t *testAPI) UserDataByUsername(_ context.Context, username string) (*api.Userdata, error) {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	for _, user := range t.users {
+		if user.Username == username {
+			return user, nil
+		}
+	}
+	return nil, fmt.Errorf("user not found")
+}
+
+// publish records the given cast with a sequential hash and returns it.
+func (t *testAPI) publish(cast *testCast) string {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	cast.Hash = fmt.Sprintf("0xbot%d", len(t.sent)+1)
+	t.sent = append(t.sent, cast)
+	return cast.Hash
+}
+
+// sentCasts returns a copy of the casts published by the bot.
+func (t *testAPI) sentCasts() []*testCast {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	return append([]*testCast{}, t.sent...)
+}
+
+// newTestHandler returns a command handler with the given API, an empty
+// ledger, the default poll config, no channels config and an in-memory
+// election creator.
+func newTestHandler(testAPI *testAPI) (*commandHandler, *election.MemoryCreator) {
+	elections := &election.MemoryCreator{}
+	return &commandHandler{
+		api:        testAPI,
+		polls:      ledger.New(),
+		pollConfig: poll.DefaultConfig,
+		channels:   map[string]*channel.Config{},
+		elections:  elections,
+	}, elections
+}
+
+var (
+	alice = &api.Userdata{
+		FID:                    1,
+		Username:               "alice",
+		CustodyAddress:         "0x1111111111111111111111111111111111111111",
+		VerificationsAddresses: []string{"0xaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"},
+	}
+	bob = &api.Userdata{
+		FID:            2,
+		Username:       "bob",
+		CustodyAddress: "0x2222222222222222222222222222222222222222",
+	}
+)
+
+func TestNewPoll(t *testing.T) {
+	c := qt.New(t)
+
+	ctx := context.Background()
+	testAPI := newTestAPI(alice, bob)
+	handler, elections := newTestHandler(testAPI)
+
+	handler.newPoll(ctx, &api.APIMessage{
+		IsMention: true,
+		Author:    alice.FID,
+		Hash:      "0xcast1",
+		Content:   "!poll\ntype: multiple 2\ndescription: Next quarter\nWhat should we build?\n- Frames\n- Polls\n- Bots\n2d",
+	})
+
+	// the election is created with the poll and the author profile
+	c.Assert(elections.IDs(), qt.DeepEquals, []string{"1"})
+	opts := elections.Options("1")
+	c.Assert(opts.Question, qt.Equals, "What should we build?")
+	c.Assert(opts.Description, qt.Equals, "Next quarter")
+	c.Assert(opts.Options, qt.DeepEquals, []string{"Frames", "Polls", "Bots"})
+	c.Assert(opts.Duration, qt.Equals, 48)
+	c.Assert(opts.VoteType, qt.Equals, election.VoteTypeMultiple)
+	c.Assert(opts.MaxSelections, qt.Equals, 2)
+	c.Assert(opts.Author, qt.DeepEquals, &election.Profile{
+		FID:           alice.FID,
+		Custody:       alice.CustodyAddress,
+		Verifications: alice.VerificationsAddresses,
+	})
+
+	// the bot replies to the cast with the election url
+	sent := testAPI.sentCasts()
+	c.Assert(sent, qt.HasLen, 1)
+	c.Assert(sent[0].ParentHash, qt.Equals, "0xcast1")
+	c.Assert(sent[0].Content, qt.Contains, election.DefaultMemoryURL+"/1")
+
+	// the poll is stored in the ledger linked to the election
+	entry, err := handler.polls.Get("0xcast1")
+	c.Assert(err, qt.IsNil)
+	c.Assert(entry.Author, qt.Equals, alice.FID)
+	c.Assert(entry.ReplyHash, qt.Equals, sent[0].Hash)
+	c.Assert(entry.ElectionID, qt.Equals, "1")
+	c.Assert(entry.FrameURL, qt.Equals, election.DefaultMemoryURL+"/1")
+	c.Assert(entry.EndDate.Sub(entry.CreatedAt).Round(time.Hour), qt.Equals, 48*time.Hour)
+
+	// the election ends at the exact end date of the poll
+	status, err := elections.Status(ctx, "1")
+	c.Assert(err, qt.IsNil)
+	c.Assert(status.EndDate.Equal(entry.EndDate), qt.IsTrue)
+}
+
+func TestNewPollTemplate(t *testing.T) {
+	c := qt.New(t)
+
+	testAPI := newTestAPI(alice)
+	handler, elections := newTestHandler(testAPI)
+	handler.pollConfig.Templates = map[string]*poll.Template{
+		"yesno": {Name: "yesno", Options: []string{"Yes", "No", "Abstain"}, Duration: "3d"},
+	}
+
+	handler.newPoll(context.Background(), &api.APIMessage{
+		IsMention: true,
+		Author:    alice.FID,
+		Hash:      "0xcast1",
+		Content:   "!poll yesno Should we ship v2?",
+	})
+
+	// the poll is created with the options and the duration of the template
+	c.Assert(elections.IDs(), qt.HasLen, 1)
+	opts := elections.Options("1")
+	c.Assert(opts.Question, qt.Equals, "Should we ship v2?")
+	c.Assert(opts.Options, qt.DeepEquals, []string{"Yes", "No", "Abstain"})
+	c.Assert(opts.Duration, qt.Equals, 72)
+}
+
+func TestNewPollInvalid(t *testing.T) {
+	c := qt.New(t)
+
+	testAPI := newTestAPI(alice)
+	handler, elections := newTestHandler(testAPI)
+
+	// a message that is not a poll is ignored
+	handler.newPoll(context.Background(), &api.APIMessage{
+		IsMention: true,
+		Author:    alice.FID,
+		Hash:      "0xcast1",
+		Content:   "hello bot",
+	})
+	c.Assert(elections.IDs(), qt.HasLen, 0)
+	c.Assert(testAPI.sentCasts(), qt.HasLen, 0)
+
+	// an invalid poll is reported to the author with the poll limits
+	handler.newPoll(context.Background(), &api.APIMessage{
+		IsMention: true,
+		Author:    alice.FID,
+		Hash:      "0xcast2",
+		Content:   "!poll\nQuestion?\n- A\n- B\n- C\n- D\n- E",
+	})
+	c.Assert(elections.IDs(), qt.HasLen, 0)
+	sent := testAPI.sentCasts()
+	c.Assert(sent, qt.HasLen, 1)
+	c.Assert(sent[0].ParentHash, qt.Equals, "0xcast2")
+	c.Assert(sent[0].Content, qt.Contains, "line 7, column 1: max number of options reached: 4")
+	c.Assert(sent[0].Content, qt.Contains, "2-4 options of up to 32 characters and a duration between 1h and 52w 1d (1d by default)")
+
+	// the invalid options are reported with the problem to fix
+	handler.newPoll(context.Background(), &api.APIMessage{
+		IsMention: true,
+		Author:    alice.FID,
+		Hash:      "0xcast3",
+		Content:   "!poll Which colour? Red | Blue | red ",
+	})
+	sent = testAPI.sentCasts()
+	c.Assert(sent, qt.HasLen, 2)
+	c.Assert(sent[1].Content, qt.Contains, "line 1, column 34: duplicated option: 'red' repeats 'Red'")
+
+	// the polls with a quorum are rejected because no backend supports it
+	handler.newPoll(context.Background(), &api.APIMessage{
+		IsMention: true,
+		Author:    alice.FID,
+		Hash:      "0xcast4",
+		Content:   "!poll\nquorum: 10\nQuestion?\n- A\n- B",
+	})
+	c.Assert(elections.IDs(), qt.HasLen, 0)
+	sent = testAPI.sentCasts()
+	c.Assert(sent, qt.HasLen, 3)
+	c.Assert(sent[2].ParentHash, qt.Equals, "0xcast4")
+	c.Assert(sent[2].Content, qt.Contains, election.ErrUnsupportedQuorum.Error())
+}
+
+func TestNewPollUnsupported(t *testing.T) {
+	c := qt.New(t)
+
+	testAPI := newTestAPI(alice)
+	handler, _ := newTestHandler(testAPI)
+	frames, err := frameserver.New("https://frames.test", "")
+	c.Assert(err, qt.IsNil)
+	handler.elections = frames
+
+	// the polls that the backend does not support are reported to the author
+	handler.newPoll(context.Background(), &api.APIMessage{
+		IsMention: true,
+		Author:    alice.FID,
+		Hash:      "0xcast1",
+		Content:   "!poll\ntype: ranked\nQuestion?\n- A\n- B",
+	})
+	sent := testAPI.sentCasts()
+	c.Assert(sent, qt.HasLen, 1)
+	c.Assert(sent[0].ParentHash, qt.Equals, "0xcast1")
+	c.Assert(sent[0].Content, qt.Equals, "I can't create your poll 😕 vote type not supported by the frame server: ranked")
+	_, err = handler.polls.Get("0xcast1")
+	c.Assert(err, qt.ErrorIs, ledger.ErrEntryNotFound)
+}
+
+func TestCreateErrorText(t *testing.T) {
+	c := qt.New(t)
+
+	err := fmt.Errorf("%w: nft", election.ErrUnsupportedCensus)
+	c.Assert(createErrorText(err), qt.Equals, "I can't create your poll 😕 census not supported by the election backend: nft")
+	err = fmt.Errorf("%w: %s", frameserver.ErrInvalidNumOptions, strings.Repeat("x", results.MaxTextLength))
+	c.Assert(createErrorText(err), qt.Equals, "I can't create your poll 😕 invalid number of options for the frame buttons")
+	c.Assert(createErrorText(fmt.Errorf("error requesting the election: timeout")), qt.Equals,
+		"I can't create your poll right now 😕 Try again later")
+}
+
+func TestShowHelp(t *testing.T) {
+	c := qt.New(t)
+
+	testAPI := newTestAPI(alice, bob)
+	handler, _ := newTestHandler(testAPI)
+	handler.pollConfig.Templates = map[string]*poll.Template{
+		"yesno": {Name: "yesno"},
+		"ship":  {Name: "ship"},
+	}
+	handler.channels[channelURL] = &channel.Config{URL: channelURL, Enabled: true, MaxOptions: 3, MaxDuration: 7 * 24 * time.Hour}
+	handler.tiers = poll.Tiers{{Name: "pro", FIDs: []uint64{bob.FID}, Limits: poll.Limits{MaxOptions: 6}}}
+
+	// the limits of the channel apply to the users without tier
+	handler.showHelp(context.Background(), &api.APIMessage{Author: alice.FID, Hash: "0xcast1", ParentURL: channelURL})
+	// the limits of the tier override the ones of the channel
+	handler.showHelp(context.Background(), &api.APIMessage{Author: bob.FID, Hash: "0xcast2", ParentURL: channelURL})
+	sent := testAPI.sentCasts()
+	c.Assert(sent, qt.HasLen, 2)
+	c.Assert(sent[0].Content, qt.Contains, "2-3 options of up to 32 characters and a duration between 1h and 1w (1d by default)")
+	c.Assert(sent[0].Content, qt.Contains, "Templates: ship, yesno")
+	c.Assert(len(sent[0].Content) <= results.MaxTextLength, qt.IsTrue)
+	c.Assert(sent[1].Content, qt.Contains, "2-6 options of up to 32 characters and a duration between 1h and 1w (1d by default)")
+}
+
+func TestCheckPollConfigs(t *testing.T) {
+	c := qt.New(t)
+
+	handler, _ := newTestHandler(newTestAPI())
+	handler.channels[channelURL] = &channel.Config{URL: channelURL, MaxOptions: 3}
+	handler.tiers = poll.Tiers{{Name: "pro", Limits: poll.Limits{MinOptions: 3}}}
+	c.Assert(handler.checkPollConfigs(), qt.IsNil)
+
+	// the min options of the tier are above the max options of the channel
+	handler.tiers[0].Limits.MinOptions = 4
+	c.Assert(handler.checkPollConfigs(), qt.ErrorIs, poll.ErrInvalidConfig)
+
+	// the default duration of the channel is above the max duration of the
+	// tier
+	handler.tiers[0].Limits = poll.Limits{MaxDuration: 2 * 24 * time.Hour}
+	c.Assert(handler.checkPollConfigs(), qt.IsNil)
+	handler.channels[channelURL].DefaultDuration = 3 * 24 * time.Hour
+	err := handler.checkPollConfigs()
+	c.Assert(err, qt.ErrorIs, poll.ErrInvalidConfig)
+	c.Assert(err, qt.ErrorMatches, "channel '"+channelURL+"', tier 'pro': .*")
+
+	// the template has more options than the max options of the channel,
+	// and longer options than the max option length of the tier
+	handler.channels[channelURL].DefaultDuration = 0
+	handler.pollConfig.Templates = map[string]*poll.Template{
+		"colours": {Name: "colours", Options: []string{"Red", "Green", "Blue", "Yellow"}},
+	}
+	err = handler.checkPollConfigs()
+	c.Assert(err, qt.ErrorIs, poll.ErrInvalidTemplate)
+	c.Assert(err, qt.ErrorMatches, "channel '"+channelURL+"', tier '': .*colours has 4 options, the max is 3")
+	handler.pollConfig.Templates["colours"].Options = []string{"Red", "Green", "Blue"}
+	c.Assert(handler.checkPollConfigs(), qt.IsNil)
+	handler.tiers[0].Limits = poll.Limits{MaxOptionLength: 4}
+	err = handler.checkPollConfigs()
+	c.Assert(err, qt.ErrorIs, poll.ErrOptionTooLong)
+	c.Assert(err, qt.ErrorMatches, "channel '.*', tier 'pro': .*colours: .*")
+}
+
+func TestNewPollCensus(t *testing.T) {
+	c := qt.New(t)
+
+	testAPI := newTestAPI(alice, bob)
+	handler, elections := newTestHandler(testAPI)
+
+	handler.newPoll(context.Background(), &api.APIMessage{
+		IsMention: true,
+		Author:    alice.FID,
+		Hash:      "0xcast1",
+		Content:   "!poll\ncensus: @alice @bob @carol\nShip it?\n- Yes\n- No",
+	})
+
+	// the usernames are resolved and the unresolved ones reported
+	c.Assert(elections.IDs(), qt.HasLen, 1)
+	census := elections.Options("1").Census
+	c.Assert(census.Type, qt.Equals, election.CensusTypeVoters)
+	c.Assert(census.FIDs, qt.DeepEquals, []uint64{alice.FID, bob.FID})
+	c.Assert(census.Addresses, qt.DeepEquals, alice.VerificationsAddresses)
+	sent := testAPI.sentCasts()
+	c.Assert(sent, qt.HasLen, 1)
+	c.Assert(sent[0].Content, qt.Contains, "carol")
+}
+
+func TestIsCommand(t *testing.T) {
+	c := qt.New(t)
+
+	c.Assert(isCommand("!delete", deleteCommand), qt.IsTrue)
+	c.Assert(isCommand("  !delete 0xcast1", deleteCommand), qt.IsTrue)
+	c.Assert(isCommand("!close\n0xcast1", closeCommand), qt.IsTrue)
+	c.Assert(isCommand("!deleted my poll", deleteCommand), qt.IsFalse)
+	c.Assert(isCommand("!closed polls", closeCommand), qt.IsFalse)
+	c.Assert(isCommand("!poll !delete", deleteCommand), qt.IsFalse)
+	c.Assert(isCommand("", deleteCommand), qt.IsFalse)
+}
+
+func TestDeletePoll(t *testing.T) {
+	c := qt.New(t)
+
+	ctx := context.Background()
+	testAPI := newTestAPI(alice, bob)
+	handler, elections := newTestHandler(testAPI)
+
+	handler.newPoll(ctx, &api.APIMessage{
+		IsMention: true,
+		Author:    alice.FID,
+		Hash:      "0xcast1",
+		Content:   "!poll\nShip it?\n- Yes\n- No",
+	})
+	replyHash := testAPI.sentCasts()[0].Hash
+
+	// only the author can delete the poll
+	handler.deletePoll(ctx, &api.APIMessage{
+		Author:     bob.FID,
+		Hash:       "0xcast2",
+		Content:    "!delete",
+		ParentHash: replyHash,
+	})
+	sent := testAPI.sentCasts()
+	c.Assert(sent[len(sent)-1].Content, qt.Contains, "Only the author")
+	_, err := handler.polls.Get("0xcast1")
+	c.Assert(err, qt.IsNil)
+
+	// the author replies to a cast that is not a poll, so the last poll of
+	// the author is not deleted
+	handler.deletePoll(ctx, &api.APIMessage{
+		Author:     alice.FID,
+		Hash:       "0xcast4",
+		Content:    "!delete",
+		ParentHash: "0xunrelated",
+	})
+	sent = testAPI.sentCasts()
+	c.Assert(sent[len(sent)-1].Content, qt.Contains, "can't find any poll")
+	c.Assert(testAPI.deleted, qt.HasLen, 0)
+	_, err = handler.polls.Get("0xcast1")
+	c.Assert(err, qt.IsNil)
+
+	// the author deletes the poll replying to the bot reply, and the election
+	// is canceled
+	handler.deletePoll(ctx, &api.APIMessage{
+		Author:     alice.FID,
+		Hash:       "0xcast3",
+		Content:    "!delete",
+		ParentHash: replyHash,
+	})
+	c.Assert(testAPI.deleted, qt.DeepEquals, []string{replyHash})
+	_, err = handler.polls.Get("0xcast1")
+	c.Assert(err, qt.ErrorIs, ledger.ErrEntryNotFound)
+	status, err := elections.Status(ctx, "1")
+	c.Assert(err, qt.IsNil)
+	c.Assert(status.Status, qt.Equals, election.StatusCanceled)
+	sent = testAPI.sentCasts()
+	c.Assert(strings.Contains(sent[len(sent)-1].Content, "canceled"), qt.IsTrue)
+}
+
+func TestNotifyEndedPolls(t *testing.T) {
+	c := qt.New(t)
+
+	ctx := context.Background()
+	testAPI := newTestAPI(alice, bob)
+	handler, elections := newTestHandler(testAPI)
+
+	handler.newPoll(ctx, &api.APIMessage{
+		IsMention: true,
+		Author:    alice.FID,
+		Hash:      "0xcast1",
+		Content:   "!poll\nShip it?\n- Yes\n- No\n1h",
+	})
+	c.Assert(elections.Vote("1", 0), qt.IsNil)
+	c.Assert(elections.Vote("1", 0), qt.IsNil)
+	c.Assert(elections.Vote("1", 1), qt.IsNil)
+
+	// the ongoing poll is not notified
+	handler.notifyEndedPolls(ctx)
+	c.Assert(testAPI.sentCasts(), qt.HasLen, 1)
+
+	// once the poll and its election end, the results are published in the
+	// thread only once
+	entry, err := handler.polls.Get("0xcast1")
+	c.Assert(err, qt.IsNil)
+	entry.EndDate = time.Now().Add(-time.Minute)
+	c.Assert(handler.polls.Update(entry), qt.IsNil)
+	elections.Now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+	handler.notifyEndedPolls(ctx)
+	handler.notifyEndedPolls(ctx)
+	sent := testAPI.sentCasts()
+	c.Assert(sent, qt.HasLen, 2)
+	c.Assert(sent[1].ParentHash, qt.Equals, "0xcast1")
+	c.Assert(sent[1].Content, qt.Contains, "Final results: Ship it?")
+	c.Assert(sent[1].Content, qt.Contains, "1. Yes: 2 votes (66.7%)")
+	c.Assert(sent[1].Content, qt.Contains, "Winner: Yes")
+	entry, err = handler.polls.Get("0xcast1")
+	c.Assert(err, qt.IsNil)
+	c.Assert(entry.ResultsNotified, qt.IsTrue)
+}
+
+func TestShowResults(t *testing.T) {
+	c := qt.New(t)
+
+	ctx := context.Background()
+	testAPI := newTestAPI(alice, bob)
+	handler, elections := newTestHandler(testAPI)
+
+	handler.newPoll(ctx, &api.APIMessage{
+		IsMention: true,
+		Author:    alice.FID,
+		Hash:      "0xcast1",
+		Content:   "!poll\nShip it?\n- Yes\n- No\n2d",
+	})
+	c.Assert(elections.Vote("1", 1), qt.IsNil)
+
+	// anyone can get the results replying to the poll
+	handler.showResults(ctx, &api.APIMessage{
+		Author:     bob.FID,
+		Hash:       "0xcast2",
+		Content:    "!results",
+		ParentHash: "0xcast1",
+	})
+	sent := testAPI.sentCasts()
+	c.Assert(sent, qt.HasLen, 2)
+	c.Assert(sent[1].ParentHash, qt.Equals, "0xcast2")
+	c.Assert(sent[1].Content, qt.Contains, "📊 Ship it?\n1 vote · 1d")
+	c.Assert(sent[1].Content, qt.Contains, "░░░░░░░░ 0% Yes\n▓▓▓▓▓▓▓▓ 100% No")
+
+	// or with the election id
+	handler.showResults(ctx, &api.APIMessage{
+		Author:  bob.FID,
+		Hash:    "0xcast3",
+		Content: "!results 1",
+	})
+	sent = testAPI.sentCasts()
+	c.Assert(sent, qt.HasLen, 3)
+	c.Assert(sent[2].Content, qt.Equals, sent[1].Content)
+
+	// unknown polls are reported
+	handler.showResults(ctx, &api.APIMessage{
+		Author:  bob.FID,
+		Hash:    "0xcast4",
+		Content: "!results 42",
+	})
+	sent = testAPI.sentCasts()
+	c.Assert(sent[3].Content, qt.Contains, "I can't find the poll")
+}
+
+func TestServeChart(t *testing.T) {
+	c := qt.New(t)
+
+	ctx := context.Background()
+	testAPI := newTestAPI(alice, bob)
+	handler, elections := newTestHandler(testAPI)
+	handler.publicURL = "https://votebot.test/"
+
+	handler.newPoll(ctx, &api.APIMessage{
+		IsMention: true,
+		Author:    alice.FID,
+		Hash:      "0xcast1",
+		Content:   "!poll\nShip it?\n- Yes\n- No",
+	})
+	c.Assert(elections.Vote("1", 0), qt.IsNil)
+
+	// the results replies embed the chart of the poll
+	handler.showResults(ctx, &api.APIMessage{
+		Author:     bob.FID,
+		Hash:       "0xcast2",
+		Content:    "!results",
+		ParentHash: "0xcast1",
+	})
+	sent := testAPI.sentCasts()
+	c.Assert(sent[1].Embeds, qt.HasLen, 1)
+	c.Assert(strings.HasPrefix(sent[1].Embeds[0], "https://votebot.test/charts/1.png?t="), qt.IsTrue)
+
+	// the chart is served as a PNG image
+	res := httptest.NewRecorder()
+	handler.serveChart(res, httptest.NewRequest(http.MethodGet, "/charts/1.png", nil))
+	c.Assert(res.Code, qt.Equals, http.StatusOK)
+	c.Assert(res.Header().Get("Content-Type"), qt.Equals, "image/png")
+	img, err := png.Decode(res.Body)
+	c.Assert(err, qt.IsNil)
+	c.Assert(img.Bounds().Dx() > 0, qt.IsTrue)
+
+	// unknown elections and paths are not found
+	for _, path := range []string{"/charts/2.png", "/charts/1", "/charts/0xcast1.png"} {
+		res := httptest.NewRecorder()
+		handler.serveChart(res, httptest.NewRequest(http.MethodGet, path, nil))
+		c.Assert(res.Code, qt.Equals, http.StatusNotFound, qt.Commentf(path))
+	}
+}
+
+func TestClosePoll(t *testing.T) {
+	c := qt.New(t)
+
+	ctx := context.Background()
+	testAPI := newTestAPI(alice, bob)
+	handler, elections := newTestHandler(testAPI)
+
+	handler.newPoll(ctx, &api.APIMessage{
+		IsMention: true,
+		Author:    alice.FID,
+		Hash:      "0xcast1",
+		Content:   "!poll\nShip it?\n- Yes\n- No\n2d",
+	})
+	c.Assert(elections.Vote("1", 0), qt.IsNil)
+
+	// only the author can close the poll
+	handler.closePoll(ctx, &api.APIMessage{
+		Author:     bob.FID,
+		Hash:       "0xcast2",
+		Content:    "!close",
+		ParentHash: "0xcast1",
+	})
+	sent := testAPI.sentCasts()
+	c.Assert(sent[len(sent)-1].Content, qt.Contains, "Only the author")
+	status, err := elections.Status(ctx, "1")
+	c.Assert(err, qt.IsNil)
+	c.Assert(status.Status, qt.Equals, election.StatusOngoing)
+
+	// the author closes the poll and gets the final results
+	handler.closePoll(ctx, &api.APIMessage{
+		Author:     alice.FID,
+		Hash:       "0xcast3",
+		Content:    "!close",
+		ParentHash: "0xcast1",
+	})
+	sent = testAPI.sentCasts()
+	c.Assert(sent[len(sent)-1].ParentHash, qt.Equals, "0xcast3")
+	c.Assert(sent[len(sent)-1].Content, qt.Contains, "Your poll has been closed")
+	c.Assert(sent[len(sent)-1].Content, qt.Contains, "Winner: Yes")
+	status, err = elections.Status(ctx, "1")
+	c.Assert(err, qt.IsNil)
+	c.Assert(status.Status, qt.Equals, election.StatusEnded)
+	entry, err := handler.polls.Get("0xcast1")
+	c.Assert(err, qt.IsNil)
+	c.Assert(entry.ResultsNotified, qt.IsTrue)
+	c.Assert(entry.EndDate.After(time.Now()), qt.IsFalse)
+
+	// the results are not published again and the poll can not be closed
+	// twice
+	casts := len(testAPI.sentCasts())
+	handler.notifyEndedPolls(ctx)
+	c.Assert(testAPI.sentCasts(), qt.HasLen, casts)
+	handler.closePoll(ctx, &api.APIMessage{
+		Author:  alice.FID,
+		Hash:    "0xcast4",
+		Content: "!close 1",
+	})
+	sent = testAPI.sentCasts()
+	c.Assert(sent[len(sent)-1].Content, qt.Contains, "already ended")
+}
+
+// blockingCreator is an election creator that blocks the end of every
+// election until it is released, to run other tasks while a poll is closed.
+type blockingCreator struct {
+	*election.MemoryCreator
+	ending  chan struct{}
+	release chan struct{}
+}
+
+func (b *blockingCreator) End(ctx context.Context, electionID string) error {
+	b.ending <- struct{}{}
+	<-b.release
+	return b.MemoryCreator.End(ctx, electionID)
+}
+
+func TestClosePollWhileNotifying(t *testing.T) {
+	c := qt.New(t)
+
+	ctx := context.Background()
+	testAPI := newTestAPI(alice, bob)
+	handler, elections := newTestHandler(testAPI)
+	creator := &blockingCreator{
+		MemoryCreator: elections,
+		ending:        make(chan struct{}),
+		release:       make(chan struct{}),
+	}
+	handler.elections = creator
+
+	// create a poll whose voting has started but has not been notified yet
+	handler.newPoll(ctx, &api.APIMessage{
+		IsMention: true,
+		Author:    alice.FID,
+		Hash:      "0xcast1",
+		Content:   "!poll\nShip it?\n- Yes\n- No\n2d",
+	})
+	c.Assert(elections.Vote("1", 0), qt.IsNil)
+	entry, err := handler.polls.Get("0xcast1")
+	c.Assert(err, qt.IsNil)
+	entry.StartDate = time.Now().Add(-time.Minute)
+	c.Assert(handler.polls.Update(entry), qt.IsNil)
+
+	// close the poll and run the periodic checks while its election ends
+	closed := make(chan struct{})
+	go func() {
+		handler.closePoll(ctx, &api.APIMessage{
+			Author:     alice.FID,
+			Hash:       "0xcast2",
+			Content:    "!close",
+			ParentHash: "0xcast1",
+		})
+		close(closed)
+	}()
+	<-creator.ending
+	checked := make(chan struct{})
+	go func() {
+		handler.notifyStartedPolls(ctx)
+		handler.notifyEndedPolls(ctx)
+		close(checked)
+	}()
+	// give the checks time to run if they are not serialized with the close
+	time.Sleep(50 * time.Millisecond)
+	close(creator.release)
+	<-closed
+	<-checked
+
+	// the results are only published in the reply to the close command, and
+	// the poll is not announced as open after it
+	sent := testAPI.sentCasts()
+	c.Assert(sent, qt.HasLen, 2)
+	c.Assert(sent[1].ParentHash, qt.Equals, "0xcast2")
+	c.Assert(sent[1].Content, qt.Contains, "Your poll has been closed")
+	entry, err = handler.polls.Get("0xcast1")
+	c.Assert(err, qt.IsNil)
+	c.Assert(entry.ResultsNotified, qt.IsTrue)
+	c.Assert(entry.EndDate.After(time.Now()), qt.IsFalse)
+	handler.notifyStartedPolls(ctx)
+	handler.notifyEndedPolls(ctx)
+	c.Assert(testAPI.sentCasts(), qt.HasLen, 2)
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/vocdoni/votebot/api"
+	"github.com/vocdoni/votebot/channel"
+	"github.com/vocdoni/votebot/election"
+	"github.com/vocdoni/votebot/frameserver"
+	"github.com/vocdoni/votebot/poll"
+	"github.com/vocdoni/votebot/results"
+)
+
+// userPollConfig returns the poll config that applies to the message: the
+// base poll config with the overrides of the channel where the message was
+// published and then the ones of the tier of its author.
+func (h *commandHandler) userPollConfig(msg *api.APIMessage) poll.PollConfig {
+	config := h.channels[msg.ParentURL].PollConfig(h.pollConfig)
+	if tier := h.tiers.Find(msg.Author); tier != nil {
+		config = config.Override(tier.Limits)
+	}
+	return config
+}
+
+// checkPollConfigs validates the poll config that results of every
+// combination of channel and tier of users, including the messages published
+// outside the configured channels and the users without tier, and the poll
+// templates against each of them.
+func (h *commandHandler) checkPollConfigs() error {
+	channels := []*channel.Config{nil}
+	for _, c := range h.channels {
+		channels = append(channels, c)
+	}
+	tiers := append(poll.Tiers{nil}, h.tiers...)
+	for _, c := range channels {
+		for _, tier := range tiers {
+			config := c.PollConfig(h.pollConfig)
+			if tier != nil {
+				config = config.Override(tier.Limits)
+			}
+			if err := config.Validate(); err != nil {
+				var channelURL, tierName string
+				if c != nil {
+					channelURL = c.URL
+				}
+				if tier != nil {
+					tierName = tier.Name
+				}
+				return fmt.Errorf("channel '%s', tier '%s': %w", channelURL, tierName, err)
+			}
+		}
+	}
+	return nil
+}
+
+// showHelp replies to the message with the usage of the bot and the poll
+// limits that apply to its author in the channel where it was published,
+// with the names of the poll templates if they fit in the reply.
+func (h *commandHandler) showHelp(ctx context.Context, msg *api.APIMessage) {
+	config := h.userPollConfig(msg)
+	text := fmt.Sprintf("🗳️ Create a poll with %s, the question and the options, one per line starting "+
+		"with '-' or in one line: '%s Which colour? Red | Blue'. Polls here allow %s. Other commands: %s, %s and %s",
+		pollCommand, pollCommand, limitsText(config), resultsCommand, closeCommand, deleteCommand)
+	if len(config.Templates) > 0 {
+		names := make([]string, 0, len(config.Templates))
+		for name := range config.Templates {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		if withTemplates := text + ". Templates: " + strings.Join(names, ", "); len(withTemplates) <= results.MaxTextLength {
+			text = withTemplates
+		}
+	}
+	h.reply(ctx, msg, text)
+}
+
+// pollErrorText returns the text of the reply to a poll that can not be
+// parsed, with the error and the poll limits of the given config. If the
+// error is too long to fit in the reply, it is omitted.
+func pollErrorText(err error, config poll.PollConfig) string {
+	limits := fmt.Sprintf("Polls here allow %s, send %s for more info", limitsText(config), helpCommand)
+	text := fmt.Sprintf("I can't create your poll 😕 %s. %s", err, limits)
+	if len(text) > results.MaxTextLength {
+		return fmt.Sprintf("I can't create your poll 😕 %s", limits)
+	}
+	return text
+}
+
+// unsupportedErrors are the errors of the election backends for the polls
+// that they do not support, which the author can fix
+var unsupportedErrors = []error{
+	election.ErrUnsupportedCensus,
+	election.ErrEmptyCensus,
+	election.ErrUnsupportedQuorum,
+	frameserver.ErrUnsupportedVoteType,
+	frameserver.ErrInvalidNumOptions,
+}
+
+// createErrorText returns the text of the reply to a poll whose election can
+// not be created. If the backend does not support the poll, the error is
+// reported to the author, otherwise the author is asked to try again later.
+func createErrorText(err error) string {
+	for _, unsupported := range unsupportedErrors {
+		if !errors.Is(err, unsupported) {
+			continue
+		}
+		if text := fmt.Sprintf("I can't create your poll 😕 %s", err); len(text) <= results.MaxTextLength {
+			return text
+		}
+		return fmt.Sprintf("I can't create your poll 😕 %s", unsupported)
+	}
+	return "I can't create your poll right now 😕 Try again later"
+}
+
+// limitsText returns the number of options and the durations allowed by the
+// given config as a text for the users.
+func limitsText(config poll.PollConfig) string {
+	options := fmt.Sprintf("%d-%d options", config.MinOptions, config.MaxOptions)
+	if config.MinOptions == config.MaxOptions {
+		options = fmt.Sprintf("%d options", config.MinOptions)
+	}
+	if config.MaxOptionLength > 0 {
+		options += fmt.Sprintf(" of up to %d characters", config.MaxOptionLength)
+	}
+	return fmt.Sprintf("%s and a duration between %s and %s (%s by default)", options,
+		poll.FormatDuration(config.MinDuration), poll.FormatDuration(config.MaxDuration),
+		poll.FormatDuration(config.DefaultDuration))
+}
@@ -3,8 +3,9 @@ package main
 import (
 	"context"
 	"encoding/hex"
+	"errors"
 	"flag"
-	"fmt"
+	"net/http"
 	"os"
 	"os/signal"
 	"strings"
@@ -15,11 +16,19 @@ import (
 	"github.com/vocdoni/votebot/api/hub"
 	"github.com/vocdoni/votebot/api/neynar"
 	"github.com/vocdoni/votebot/bot"
+	"github.com/vocdoni/votebot/channel"
 	"github.com/vocdoni/votebot/election"
+	"github.com/vocdoni/votebot/frames"
+	"github.com/vocdoni/votebot/frameserver"
+	"github.com/vocdoni/votebot/ledger"
 	"github.com/vocdoni/votebot/poll"
 	"go.vocdoni.io/dvote/log"
 )
 
+// pollsCheckInterval is the time between checks of the polls to notify the
+// start of the voting of the scheduled ones and the results of the ended ones
+const pollsCheckInterval = time.Minute
+
 func main() {
 	botFid := flag.Uint64("botFid", 0, "bot fid")
 	mode := flag.String("mode", "", "bot mode: neynar or hub")
@@ -34,8 +43,22 @@ func main() {
 	hubEndpoint := flag.String("hubEndpoint", "https://hub.freefarcasterhub.com:3281", "hub http API endpoint")
 	hubAuthHeaders := flag.String("hubAuthHeaders", "", "hub auth headers")
 	hubAuthKeys := flag.String("hubAuthKeys", "", "hub auth keys")
-	// onvote flags
+	// election backend flags
+	electionBackend := flag.String("electionBackend", "onvote", "election backend: onvote, vocdoni or frame")
 	onvoteEndpoint := flag.String("onvoteEndpoint", "https://dev.farcaster.vote", "onvote frame generator http API endpoint")
+	vocdoniEndpoint := flag.String("vocdoniEndpoint", "https://api-dev.vocdoni.net/v2", "vocdoni http API endpoint, also used to get the status of the onvote elections")
+	vocdoniPrivateKey := flag.String("vocdoniPrivateKey", "", "private key of the vocdoni organization account")
+	vocdoniVoteURL := flag.String("vocdoniVoteURL", election.DefaultVocdoniVoteURL, "base url of the vocdoni page to vote in the elections")
+	// channels flags
+	channelsConfig := flag.String("channelsConfig", "", "path to the JSON file with the channels config (optional)")
+	pollConfigFile := flag.String("pollConfig", "", "path to the YAML or JSON file with the poll limits and the tiers of users (optional)")
+	templatesConfig := flag.String("templatesConfig", "", "path to the YAML or JSON file with the poll templates (optional)")
+	frameStateFile := flag.String("frameStateFile", "", "path to the JSON file to persist the polls and votes of the frame backend (optional, kept in memory if empty)")
+	// http server flags
+	httpAddr := flag.String("httpAddr", "", "address of the http server that serves the results charts and the frames, such as ':8080' (optional, disabled if empty)")
+	publicURL := flag.String("publicURL", "", "public base url of the http server, required to embed the results charts and to use the frame backend")
+	// ledger flags
+	ledgerFile := flag.String("ledgerFile", "", "path to the JSON file to persist the polls created by the bot (optional, kept in memory if empty)")
 	flag.Parse()
 	// init logger with the given log level
 	log.Init(*logLevel, "stdout", nil)
@@ -45,6 +68,7 @@ func main() {
 	}
 	// check bot mode to initialize the API
 	var botAPI api.API
+	hubAuth := make(map[string]string)
 	switch *mode {
 	case "neynar":
 		if *neynarSignerUUID == "" {
@@ -75,9 +99,8 @@ func main() {
 		if (*hubAuthHeaders != "" && *hubAuthKeys == "") || (*hubAuthHeaders == "" && *hubAuthKeys != "") {
 			log.Fatal("if authHeaders is set, authKeys must be set too and viceversa")
 		}
-		// create a map to store the auth headers and keys, parsing the given
+		// fill the map to store the auth headers and keys, parsing the given
 		// strings separated by commas
-		hubAuth := make(map[string]string)
 		headers := strings.Split(*hubAuthHeaders, ",")
 		keys := strings.Split(*hubAuthKeys, ",")
 		if len(headers) != len(keys) {
@@ -93,19 +116,125 @@ func main() {
 	default:
 		log.Fatal("'hub' or 'neynar' mode is required")
 	}
-	// check onvote endpoint
-	if *onvoteEndpoint == "" {
-		log.Fatal("onvote endpoint is required")
+	// check the election backend to initialize the election creator
+	var elections election.Creator
+	var frameServer *frameserver.Server
+	switch *electionBackend {
+	case "onvote":
+		if *onvoteEndpoint == "" {
+			log.Fatal("onvote endpoint is required")
+		}
+		elections = &election.OnvoteCreator{
+			Endpoint:        *onvoteEndpoint,
+			VocdoniEndpoint: *vocdoniEndpoint,
+		}
+	case "vocdoni":
+		if *vocdoniEndpoint == "" {
+			log.Fatal("vocdoni endpoint is required")
+		}
+		if *vocdoniPrivateKey == "" {
+			log.Fatal("vocdoni private key is required")
+		}
+		var err error
+		if elections, err = election.NewVocdoniCreator(*vocdoniEndpoint, *vocdoniPrivateKey, *vocdoniVoteURL); err != nil {
+			log.Fatalf("error initializing vocdoni election backend: %s", err)
+		}
+	case "frame":
+		if *httpAddr == "" || *publicURL == "" {
+			log.Fatal("http address and public url are required to serve the frames")
+		}
+		var err error
+		if frameServer, err = frameserver.New(*publicURL, *frameStateFile); err != nil {
+			log.Fatalf("error initializing frame election backend: %s", err)
+		}
+		// in hub mode, the signers of the frame actions are checked against
+		// the hub
+		if *mode == "hub" {
+			frameServer.Signers = &frames.HubSigners{Endpoint: *hubEndpoint, Headers: hubAuth}
+		}
+		elections = frameServer
+	default:
+		log.Fatal("'onvote', 'vocdoni' or 'frame' election backend is required")
 	}
 
+	// load the channels config if it is provided
+	channels := map[string]*channel.Config{}
+	if *channelsConfig != "" {
+		var err error
+		if channels, err = channel.Load(*channelsConfig); err != nil {
+			log.Fatalf("error loading channels config: %s", err)
+		}
+	}
+	// load the poll config and the tiers of users, and the poll templates,
+	// if they are provided
+	pollConfig := poll.DefaultConfig
+	var tiers poll.Tiers
+	if *pollConfigFile != "" {
+		var err error
+		if pollConfig, tiers, err = poll.LoadConfig(*pollConfigFile, poll.DefaultConfig); err != nil {
+			log.Fatalf("error loading poll config: %s", err)
+		}
+	}
+	if *templatesConfig != "" {
+		var err error
+		if pollConfig.Templates, err = poll.LoadTemplates(*templatesConfig); err != nil {
+			log.Fatalf("error loading poll templates: %s", err)
+		}
+	}
 	// set up the bot with the given configuration and the initialized API
 	voteBot, err := bot.New(bot.BotConfig{
 		CoolDown: *coolDown,
 		API:      botAPI,
+		Channels: channel.ListenURLs(channels),
 	})
 	if err != nil {
 		log.Fatal(err)
 	}
+	// open the ledger to keep track of the polls created by the bot, persisted
+	// in the given file if any
+	polls := ledger.New()
+	if *ledgerFile != "" {
+		if polls, err = ledger.Open(*ledgerFile); err != nil {
+			log.Fatalf("error opening ledger: %s", err)
+		}
+	}
+	// create the handler of the bot commands
+	handler := &commandHandler{
+		api:        botAPI,
+		polls:      polls,
+		pollConfig: pollConfig,
+		tiers:      tiers,
+		channels:   channels,
+		elections:  elections,
+	}
+	if err := handler.checkPollConfigs(); err != nil {
+		log.Fatalf("invalid poll config: %s", err)
+	}
+	// start the http server to serve the results charts, and the frames if
+	// the frame backend is used, if it is enabled
+	var server *http.Server
+	if *httpAddr != "" {
+		if *publicURL == "" {
+			log.Fatal("public url is required to serve the results charts")
+		}
+		handler.publicURL = *publicURL
+		mux := http.NewServeMux()
+		mux.HandleFunc(chartsPath, handler.serveChart)
+		if frameServer != nil {
+			mux.Handle(frameserver.Path, frameServer)
+		}
+		server = &http.Server{
+			Addr:              *httpAddr,
+			Handler:           mux,
+			ReadHeaderTimeout: 10 * time.Second,
+		}
+		go func() {
+			log.Infow("http server started", "addr", *httpAddr, "publicURL", *publicURL)
+			if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				log.Fatalf("error running http server: %s", err)
+			}
+		}()
+	}
 	// start a context and a cancel function for the bot and start listening for
 	// new casts
 	ctx, cancel := context.WithCancel(context.Background())
@@ -115,53 +244,45 @@ func main() {
 			case <-ctx.Done():
 				return
 			case msg := <-voteBot.Messages:
-				// when a new cast is received, check if it is a mention and if
-				// it is not, continue to the next cast
-				if !msg.IsMention {
-					continue
-				}
-				// try to parse the message as a poll, if it fails continue to
+				// when a new cast is received, check if it is a mention or a
+				// command published in a channel, if it is not, continue to
 				// the next cast
-				poll, err := poll.ParseString(msg.Content, poll.DefaultConfig)
-				if err != nil {
-					log.Errorf("error parsing poll: %s", err)
-					continue
-				}
-				// get the user data such as username, custody address and
-				// verification addresses to create the election frame
-				userdata, err := botAPI.UserDataByFID(ctx, msg.Author)
-				if err != nil {
-					log.Errorf("error getting user data: %s", err)
-					continue
-				}
-				log.Infow("new poll",
-					"poll", poll,
-					"userdata", userdata)
-				// create a new poll and send the result to the user
-				frameURL, err := election.FrameElection(ctx, &election.ElectionOptions{
-					BaseEndpoint: *onvoteEndpoint,
-					Author: &election.Profile{
-						FID:           msg.Author,
-						Custody:       userdata.CustodyAddress,
-						Verifications: userdata.VerificationsAddresses,
-					},
-					Question: poll.Question,
-					Options:  poll.Options,
-					Duration: int(poll.Duration.Hours()),
-				})
-				if err != nil {
-					log.Errorf("error creating election frame: %s", err)
+				content := strings.TrimSpace(msg.Content)
+				if !msg.IsMention && !strings.HasPrefix(content, commandPrefix) {
 					continue
 				}
-				// compose the reply text and send it to the user as a reply to
-				// the original cast
-				replyText := fmt.Sprintf("Here is your election 🗳️ frame url! %s", frameURL)
-				if err := botAPI.Reply(ctx, msg.Author, msg.Hash, replyText); err != nil {
-					log.Errorf("error replying to cast: %s", err)
+				// check if the message is a delete, results, close or help
+				// command, if it is not, try to handle it as a new poll
+				switch {
+				case isCommand(content, deleteCommand):
+					handler.deletePoll(ctx, msg)
+				case isCommand(content, resultsCommand):
+					handler.showResults(ctx, msg)
+				case isCommand(content, closeCommand):
+					handler.closePoll(ctx, msg)
+				case isCommand(content, helpCommand):
+					handler.showHelp(ctx, msg)
+				default:
+					handler.newPoll(ctx, msg)
 				}
 			}
 		}
 	}()
+	// check periodically if the voting of any scheduled poll has started or
+	// any poll has ended to notify it
+	go func() {
+		ticker := time.NewTicker(pollsCheckInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				handler.notifyStartedPolls(ctx)
+				handler.notifyEndedPolls(ctx)
+			}
+		}
+	}()
 	// start the bot
 	voteBot.Start(ctx)
 	// wait for SIGTERM to cancel the context and stop the bot
@@ -175,6 +296,11 @@ func main() {
 	// routines to end gracefully
 	go func() {
 		voteBot.Stop()
+		if server != nil {
+			if err := server.Shutdown(context.Background()); err != nil {
+				log.Warnw("error stopping http server", "error", err)
+			}
+		}
 		log.Debug("all routines ended")
 	}()
 	time.Sleep(5 * time.Second)
@@ -0,0 +1,95 @@
+package election
+
+import (
+	"fmt"
+	"regexp"
+)
+
+const (
+	// census types
+	CensusTypeFollowers = "followers"
+	CensusTypeChannel   = "channel"
+	CensusTypeERC20     = "erc20"
+	CensusTypeNFT       = "nft"
+	CensusTypeFIDs      = "fids"
+	CensusTypeAddresses = "addresses"
+	CensusTypeVoters    = "voters"
+)
+
+// addressRgx matches an hex encoded ethereum address
+var addressRgx = regexp.MustCompile(`^0x[0-9a-fA-F]{40}$`)
+
+// Census contains the definition of who can vote in an election. If it is not
+// set, the default census of the onvote API is used. The channel census
+// includes the members of the channel with the given url, the token censuses
+// include the holders of the contract in the given network, weighting their
+// votes by their balance if weighted is set, and the fids and addresses
+// censuses include the listed voters. The voters census includes both the
+// listed fids and addresses, it is used for the censuses resolved from lists
+// of users.
+type Census struct {
+	Type      string   `json:"type"`
+	Channel   string   `json:"channel,omitempty"`
+	Contract  string   `json:"contract,omitempty"`
+	Network   string   `json:"network,omitempty"`
+	Weighted  bool     `json:"weighted,omitempty"`
+	FIDs      []uint64 `json:"fids,omitempty"`
+	Addresses []string `json:"addresses,omitempty"`
+}
+
+// Validate checks that the census contains the fields required by its type
+// and only them. It returns an error describing the first problem found.
+func (c *Census) Validate() error {
+	isToken := c.Type == CensusTypeERC20 || c.Type == CensusTypeNFT
+	if !isToken && (c.Contract != "" || c.Network != "" || c.Weighted) {
+		return fmt.Errorf("token fields set in a %s census", c.Type)
+	}
+	if c.Type != CensusTypeChannel && c.Channel != "" {
+		return fmt.Errorf("channel set in a %s census", c.Type)
+	}
+	if c.Type != CensusTypeFIDs && c.Type != CensusTypeVoters && len(c.FIDs) > 0 {
+		return fmt.Errorf("fids set in a %s census", c.Type)
+	}
+	if c.Type != CensusTypeAddresses && c.Type != CensusTypeVoters && len(c.Addresses) > 0 {
+		return fmt.Errorf("addresses set in a %s census", c.Type)
+	}
+	switch c.Type {
+	case CensusTypeFollowers:
+	case CensusTypeChannel:
+		if c.Channel == "" {
+			return fmt.Errorf("channel census without channel")
+		}
+	case CensusTypeERC20, CensusTypeNFT:
+		if !addressRgx.MatchString(c.Contract) {
+			return fmt.Errorf("invalid contract address: %s", c.Contract)
+		}
+		if c.Network == "" {
+			return fmt.Errorf("%s census without network", c.Type)
+		}
+	case CensusTypeFIDs:
+		if len(c.FIDs) == 0 {
+			return fmt.Errorf("fids census without fids")
+		}
+	case CensusTypeAddresses:
+		if len(c.Addresses) == 0 {
+			return fmt.Errorf("addresses census without addresses")
+		}
+	case CensusTypeVoters:
+		if len(c.FIDs) == 0 && len(c.Addresses) == 0 {
+			return fmt.Errorf("voters census without voters")
+		}
+	default:
+		return fmt.Errorf("unknown census type: %s", c.Type)
+	}
+	for _, fid := range c.FIDs {
+		if fid == 0 {
+			return fmt.Errorf("invalid fid: %d", fid)
+		}
+	}
+	for _, address := range c.Addresses {
+		if !addressRgx.MatchString(address) {
+			return fmt.Errorf("invalid address: %s", address)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package election
+
+import (
+	"crypto/sha256"
+	"encoding/base32"
+	"fmt"
+	"strings"
+)
+
+const (
+	// maxCIDDataSize is the max size of the data whose CID can be calculated,
+	// which is the size of an IPFS chunk, because bigger data is split in a
+	// DAG of chunks
+	maxCIDDataSize = 262144
+	// cidPrefix is the prefix of the binary CIDs: version 1, dag-pb codec and
+	// sha256 multihash of 32 bytes
+	cidPrefix = "\x01\x70\x12\x20"
+)
+
+// ipfsCID returns the IPFS CID (v1) of the given data as it is calculated by
+// vocdoni to reference the election metadata: the base32 encoded dag-pb CID
+// with the sha256 hash of the UnixFS file node that contains the data. The
+// data must fit in a single chunk, if not, it returns an error. The election
+// metadata is always far below that size.
+//
+// It replaces ipfs.CalculateCIDv1json of the dvote module, which can not be
+// imported by the bot: it depends on libp2p, whose protobufs register the
+// 'message.proto' file and the 'Message' type, as the hub protobufs do, so
+// the bot panics on startup. The CIDs are tested against the dvote ones.
+func ipfsCID(data []byte) (string, error) {
+	if len(data) > maxCIDDataSize {
+		return "", fmt.Errorf("data too big to calculate its CID: %d bytes", len(data))
+	}
+	// encode the UnixFS file data (type file, data and file size) and wrap it
+	// in a dag-pb node without links
+	fsNode := []byte{0x08, 0x02}
+	if len(data) > 0 {
+		fsNode = appendBytesField(fsNode, 0x12, data)
+	}
+	fsNode = append(fsNode, 0x18)
+	fsNode = appendVarint(fsNode, uint64(len(data)))
+	pbNode := appendBytesField(nil, 0x0a, fsNode)
+	// hash the node and encode the CID in base32 with its multibase prefix
+	hash := sha256.Sum256(pbNode)
+	cid := append([]byte(cidPrefix), hash[:]...)
+	return "b" + strings.ToLower(base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(cid)), nil
+}
+
+// appendBytesField appends a protobuf length-delimited field with the given
+// tag and value to the buffer.
+func appendBytesField(buf []byte, tag byte, value []byte) []byte {
+	buf = append(buf, tag)
+	buf = appendVarint(buf, uint64(len(value)))
+	return append(buf, value...)
+}
+
+// appendVarint appends the given value to the buffer encoded as a protobuf
+// varint.
+func appendVarint(buf []byte, value uint64) []byte {
+	for value >= 0x80 {
+		buf = append(buf, byte(value)|0x80)
+		value >>= 7
+	}
+	return append(buf, byte(value))
+}
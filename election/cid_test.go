@@ -0,0 +1,38 @@
+package election
+
+import (
+	"bytes"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestIPFSCID(t *testing.T) {
+	c := qt.New(t)
+
+	// the expected CIDs are the ones calculated by ipfs.CalculateCIDv1json
+	// of the dvote module for the same data
+	metadata := []byte(`{"title":{"default":"Q?"}}`)
+	for _, test := range []struct {
+		name     string
+		data     []byte
+		expected string
+	}{
+		{"empty", []byte{}, "bafybeif7ztnhq65lumvvtr4ekcwd2ifwgm3awq4zfr3srh462rwyinlb4y"},
+		{"single byte", []byte("a"), "bafybeih22o2lqjyoumhqtqjwjomq3mzvdmxxeaivw52aoh2mytrlujo7yi"},
+		{"metadata", metadata, "bafybeidcepn3h33gl7zhx4oj6luthhkqxmcklyovfhqjq62mrabjwjpzsi"},
+		{"long varint", bytes.Repeat([]byte("x"), 300), "bafybeifxw36ihej6odnzb7sgg3wc4dl3kzjjlnlka7zqqde5d2hznmr7xq"},
+		{
+			"max chunk size",
+			bytes.Repeat(metadata, maxCIDDataSize/len(metadata)+1)[:maxCIDDataSize],
+			"bafybeicpth43v3xp7z6qfe2k32m67ltapo42cbjsg5jjf3dpdkczkolbty",
+		},
+	} {
+		cid, err := ipfsCID(test.data)
+		c.Assert(err, qt.IsNil, qt.Commentf(test.name))
+		c.Assert(cid, qt.Equals, test.expected, qt.Commentf(test.name))
+	}
+
+	_, err := ipfsCID(make([]byte, maxCIDDataSize+1))
+	c.Assert(err, qt.IsNotNil)
+}
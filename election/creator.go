@@ -0,0 +1,112 @@
+package election
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"time"
+)
+
+const (
+	// election statuses
+	StatusUpcoming = "upcoming"
+	StatusOngoing  = "ongoing"
+	StatusEnded    = "ended"
+	StatusCanceled = "canceled"
+)
+
+// Election identifies a created election and contains the url where the
+// users can vote in it.
+type Election struct {
+	ID  string
+	URL string
+}
+
+// Status contains the current status of an election, its start and end
+// dates and the number of votes received.
+type Status struct {
+	Status    string
+	StartDate time.Time
+	EndDate   time.Time
+	VoteCount uint64
+}
+
+// Results contains the results of an election. The tally contains, for every
+// field of the ballots, the number of votes received by every value, as the
+// vocdoni results. The ballots contain the values of every ballot if the
+// backend keeps them, which are required to count the ranked choice
+// elections by instant-runoff, or nil otherwise. Final is set when the
+// election has ended and its results will not change anymore.
+type Results struct {
+	Tally      [][]uint64
+	Ballots    [][]int
+	VoteCount  uint64
+	CensusSize uint64
+	Final      bool
+}
+
+// Creator is the backend that manages the elections of the polls: it creates
+// them from the given options, returning the url where the users can vote in
+// them, and gets their status and results. It also cancels them and ends
+// them before their end date if the backend supports it, if not, it returns
+// ErrCancelNotSupported or ErrEndNotSupported.
+type Creator interface {
+	Create(ctx context.Context, opts *ElectionOptions) (*Election, error)
+	Status(ctx context.Context, electionID string) (*Status, error)
+	Results(ctx context.Context, electionID string) (*Results, error)
+	Cancel(ctx context.Context, electionID string) error
+	End(ctx context.Context, electionID string) error
+}
+
+// OnvoteCreator creates election frames using the onvote (farcaster.vote)
+// service of the given endpoint. The frames are backed by vocdoni elections,
+// so their status and results are got from the vocdoni API endpoint.
+type OnvoteCreator struct {
+	Endpoint        string
+	VocdoniEndpoint string
+}
+
+// Create creates an election frame with the given options using the onvote
+// endpoint of the creator, and returns the election with the url of the
+// frame, which ends with the id of the election.
+func (o *OnvoteCreator) Create(ctx context.Context, opts *ElectionOptions) (*Election, error) {
+	onvoteOpts := *opts
+	onvoteOpts.BaseEndpoint = o.Endpoint
+	frameURL, err := FrameElection(ctx, &onvoteOpts)
+	if err != nil {
+		return nil, err
+	}
+	return &Election{ID: path.Base(frameURL), URL: frameURL}, nil
+}
+
+// Status returns the status of the election with the given id from the
+// vocdoni API.
+func (o *OnvoteCreator) Status(ctx context.Context, electionID string) (*Status, error) {
+	info, err := vocdoniElection(ctx, o.VocdoniEndpoint, electionID)
+	if err != nil {
+		return nil, err
+	}
+	return vocdoniStatus(info), nil
+}
+
+// Results returns the results of the election with the given id from the
+// vocdoni API.
+func (o *OnvoteCreator) Results(ctx context.Context, electionID string) (*Results, error) {
+	info, err := vocdoniElection(ctx, o.VocdoniEndpoint, electionID)
+	if err != nil {
+		return nil, err
+	}
+	return vocdoniResults(info), nil
+}
+
+// Cancel returns ErrCancelNotSupported because the onvote service does not
+// allow to cancel the elections.
+func (o *OnvoteCreator) Cancel(_ context.Context, electionID string) error {
+	return fmt.Errorf("%w: election %s", ErrCancelNotSupported, electionID)
+}
+
+// End returns ErrEndNotSupported because the onvote service does not allow to
+// end the elections before their end date.
+func (o *OnvoteCreator) End(_ context.Context, electionID string) error {
+	return fmt.Errorf("%w: election %s", ErrEndNotSupported, electionID)
+}
@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"math"
 	"net/http"
 	"strings"
 	"time"
@@ -20,18 +21,101 @@ const (
 	checkTimeout  = 10 * time.Second
 )
 
+const (
+	// vote types
+	VoteTypeSingle   = "single"
+	VoteTypeMultiple = "multiple"
+	VoteTypeApproval = "approval"
+	VoteTypeRanked   = "ranked"
+)
+
 type Profile struct {
 	FID           uint64   `json:"fid"`
 	Custody       string   `json:"custody"`
 	Verifications []string `json:"verifications"`
 }
 
+// ElectionOptions contains the options to create an election frame. The
+// duration is the number of hours that the election lasts since its start
+// date, as required by the onvote API. The end date, if it is set, is the
+// exact end of the election, which is used instead of the duration by the
+// backends that support it. If the start date is not set, the election
+// starts when it is created.
+// The vote type defines how many options every voter can choose, single
+// choice by default, up to the max number of selections for multiple choice
+// and ranked choice elections. The census defines who can vote, if it is not
+// set, the default census is used. The description is optional and is
+// included in the election metadata by the backends that support it.
 type ElectionOptions struct {
-	BaseEndpoint string   `json:"-"`
-	Author       *Profile `json:"profile"`
-	Question     string   `json:"question"`
-	Options      []string `json:"options"`
-	Duration     int      `json:"duration"`
+	BaseEndpoint  string     `json:"-"`
+	Author        *Profile   `json:"profile"`
+	Question      string     `json:"question"`
+	Description   string     `json:"description,omitempty"`
+	Options       []string   `json:"options"`
+	Duration      int        `json:"duration"`
+	StartDate     *time.Time `json:"startDate,omitempty"`
+	EndDate       *time.Time `json:"-"`
+	VoteType      string     `json:"voteType,omitempty"`
+	MaxSelections int        `json:"maxSelections,omitempty"`
+	Census        *Census    `json:"census,omitempty"`
+}
+
+// BallotMode contains the parameters of the vocdoni ballot protocol for an
+// election. Every ballot contains up to MaxCount fields with values between 0
+// and MaxValue, which must be different if UniqueChoices is set.
+type BallotMode struct {
+	MaxCount      int  `json:"maxCount"`
+	MaxValue      int  `json:"maxValue"`
+	UniqueChoices bool `json:"uniqueChoices"`
+}
+
+// BallotMode returns the vocdoni ballot mode that corresponds to the vote type
+// of the election options:
+//   - single choice: one field with the index of the chosen option.
+//   - multiple choice: up to max selections fields with the indexes of the
+//     chosen options, which must be different.
+//   - approval: one field per option with 1 if it is approved or 0 if not.
+//   - ranked choice: one field per option with its position in the ranking,
+//     from 1 for the favourite to max selections, or 0 if it is not ranked.
+//     The values can not be unique because every unranked option is 0.
+//
+// It returns an error if the vote type is unknown or the max number of
+// selections is not valid for the number of options.
+func (opts *ElectionOptions) BallotMode() (*BallotMode, error) {
+	numOptions := len(opts.Options)
+	switch opts.VoteType {
+	case "", VoteTypeSingle:
+		return &BallotMode{MaxCount: 1, MaxValue: numOptions - 1}, nil
+	case VoteTypeMultiple, VoteTypeRanked:
+		if opts.MaxSelections < 1 || opts.MaxSelections > numOptions {
+			return nil, fmt.Errorf("invalid max selections %d for %d options", opts.MaxSelections, numOptions)
+		}
+		if opts.VoteType == VoteTypeRanked {
+			return &BallotMode{MaxCount: numOptions, MaxValue: opts.MaxSelections}, nil
+		}
+		return &BallotMode{MaxCount: opts.MaxSelections, MaxValue: numOptions - 1, UniqueChoices: true}, nil
+	case VoteTypeApproval:
+		return &BallotMode{MaxCount: numOptions, MaxValue: 1}, nil
+	default:
+		return nil, fmt.Errorf("unknown vote type: %s", opts.VoteType)
+	}
+}
+
+// End returns the end date of the election that starts at the given date:
+// the end date of the options if it is set, or the start date plus the
+// duration in hours.
+func (opts *ElectionOptions) End(startDate time.Time) time.Time {
+	if opts.EndDate != nil {
+		return *opts.EndDate
+	}
+	return startDate.Add(time.Duration(opts.Duration) * time.Hour)
+}
+
+// DurationHours returns the given duration in whole hours, as required by the
+// onvote API. It rounds up the duration to ensure that the election does not
+// end before the requested end date.
+func DurationHours(duration time.Duration) int {
+	return int(math.Ceil(duration.Hours()))
 }
 
 // FrameElection creates a new election frame and returns the url to interact
@@ -42,8 +126,21 @@ func FrameElection(ctx context.Context, opts *ElectionOptions) (string, error) {
 	// create internal context
 	createCtx, cancelCreate := context.WithTimeout(ctx, createTimeout)
 	defer cancelCreate()
-	// marshal the options
-	body, err := json.Marshal(opts)
+	// validate the census before requesting the creation of the election
+	if opts.Census != nil {
+		if err := opts.Census.Validate(); err != nil {
+			return "", fmt.Errorf("invalid election census: %w", err)
+		}
+	}
+	// get the ballot mode of the election and marshal it with the options
+	ballotMode, err := opts.BallotMode()
+	if err != nil {
+		return "", fmt.Errorf("error getting the election ballot mode: %w", err)
+	}
+	body, err := json.Marshal(struct {
+		*ElectionOptions
+		BallotMode *BallotMode `json:"ballotMode"`
+	}{opts, ballotMode})
 	if err != nil {
 		return "", fmt.Errorf("error marshaling the election options: %w", err)
 	}
@@ -97,5 +194,5 @@ func FrameElection(ctx context.Context, opts *ElectionOptions) (string, error) {
 		return "", fmt.Errorf("error checking the election: %s", checkRes.Status)
 	}
 	// if the status is 200, the election has been created, compose the url and return it
-	return fmt.Sprintf("%s/%s", opts.BaseEndpoint, electionID), nil
+	return fmt.Sprintf("%s/%s", opts.BaseEndpoint, strings.TrimSpace(string(electionID))), nil
 }
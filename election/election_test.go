@@ -0,0 +1,233 @@
+package election
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestBallotMode(t *testing.T) {
+	c := qt.New(t)
+
+	options := []string{"A", "B", "C", "D"}
+	tests := []struct {
+		name     string
+		opts     *ElectionOptions
+		expected *BallotMode
+		err      bool
+	}{
+		{
+			name:     "default",
+			opts:     &ElectionOptions{Options: options},
+			expected: &BallotMode{MaxCount: 1, MaxValue: 3},
+		},
+		{
+			name:     "single choice",
+			opts:     &ElectionOptions{Options: options, VoteType: VoteTypeSingle},
+			expected: &BallotMode{MaxCount: 1, MaxValue: 3},
+		},
+		{
+			name:     "multiple choice",
+			opts:     &ElectionOptions{Options: options, VoteType: VoteTypeMultiple, MaxSelections: 2},
+			expected: &BallotMode{MaxCount: 2, MaxValue: 3, UniqueChoices: true},
+		},
+		{
+			name:     "approval",
+			opts:     &ElectionOptions{Options: options, VoteType: VoteTypeApproval},
+			expected: &BallotMode{MaxCount: 4, MaxValue: 1},
+		},
+		{
+			name:     "ranked choice",
+			opts:     &ElectionOptions{Options: options, VoteType: VoteTypeRanked, MaxSelections: 4},
+			expected: &BallotMode{MaxCount: 4, MaxValue: 4},
+		},
+		{
+			name:     "ranked choice with max selections",
+			opts:     &ElectionOptions{Options: options, VoteType: VoteTypeRanked, MaxSelections: 2},
+			expected: &BallotMode{MaxCount: 4, MaxValue: 2},
+		},
+		{
+			name: "multiple choice without max selections",
+			opts: &ElectionOptions{Options: options, VoteType: VoteTypeMultiple},
+			err:  true,
+		},
+		{
+			name: "multiple choice with too many selections",
+			opts: &ElectionOptions{Options: options, VoteType: VoteTypeMultiple, MaxSelections: 5},
+			err:  true,
+		},
+		{
+			name: "unknown vote type",
+			opts: &ElectionOptions{Options: options, VoteType: "quadratic"},
+			err:  true,
+		},
+	}
+	for _, test := range tests {
+		c.Run(test.name, func(c *qt.C) {
+			ballotMode, err := test.opts.BallotMode()
+			if test.err {
+				c.Assert(err, qt.IsNotNil)
+				return
+			}
+			c.Assert(err, qt.IsNil)
+			c.Assert(ballotMode, qt.DeepEquals, test.expected)
+		})
+	}
+}
+
+func TestCensusValidate(t *testing.T) {
+	c := qt.New(t)
+
+	contract := "0x4ed4e862860bed51a9570b96d89af5e1b0efefed"
+	tests := []struct {
+		name   string
+		census *Census
+		err    bool
+	}{
+		{name: "followers", census: &Census{Type: CensusTypeFollowers}},
+		{name: "channel", census: &Census{Type: CensusTypeChannel, Channel: "https://warpcast.com/~/channel/vocdoni"}},
+		{name: "channel without url", census: &Census{Type: CensusTypeChannel}, err: true},
+		{name: "erc20", census: &Census{Type: CensusTypeERC20, Contract: contract, Network: "base", Weighted: true}},
+		{name: "nft without network", census: &Census{Type: CensusTypeNFT, Contract: contract}, err: true},
+		{name: "nft with invalid contract", census: &Census{Type: CensusTypeNFT, Contract: "0x12", Network: "base"}, err: true},
+		{name: "weighted followers", census: &Census{Type: CensusTypeFollowers, Weighted: true}, err: true},
+		{name: "fids", census: &Census{Type: CensusTypeFIDs, FIDs: []uint64{1, 2}}},
+		{name: "empty fids", census: &Census{Type: CensusTypeFIDs}, err: true},
+		{name: "zero fid", census: &Census{Type: CensusTypeFIDs, FIDs: []uint64{0}}, err: true},
+		{name: "addresses", census: &Census{Type: CensusTypeAddresses, Addresses: []string{contract}}},
+		{name: "invalid address", census: &Census{Type: CensusTypeAddresses, Addresses: []string{"vocdoni.eth"}}, err: true},
+		{name: "addresses in fids census", census: &Census{Type: CensusTypeFIDs, FIDs: []uint64{1}, Addresses: []string{contract}}, err: true},
+		{name: "voters", census: &Census{Type: CensusTypeVoters, FIDs: []uint64{1}, Addresses: []string{contract}}},
+		{name: "empty voters", census: &Census{Type: CensusTypeVoters}, err: true},
+		{name: "voters with zero fid", census: &Census{Type: CensusTypeVoters, FIDs: []uint64{0}}, err: true},
+		{name: "unknown type", census: &Census{Type: "everyone"}, err: true},
+	}
+	for _, test := range tests {
+		c.Run(test.name, func(c *qt.C) {
+			err := test.census.Validate()
+			if test.err {
+				c.Assert(err, qt.IsNotNil)
+				return
+			}
+			c.Assert(err, qt.IsNil)
+		})
+	}
+}
+
+func TestElectionMetadata(t *testing.T) {
+	c := qt.New(t)
+
+	metadata := electionMetadata(&ElectionOptions{
+		Question:    "Question?",
+		Description: "Details",
+		Options:     []string{"Yes", "No"},
+	})
+	c.Assert(metadata.Title[defaultLanguage], qt.Equals, "Question?")
+	c.Assert(metadata.Description[defaultLanguage], qt.Equals, "Details")
+	c.Assert(metadata.Questions, qt.HasLen, 1)
+	choices := metadata.Questions[0].Choices
+	c.Assert(choices, qt.HasLen, 2)
+	c.Assert(choices[0].Title[defaultLanguage], qt.Equals, "Yes")
+	c.Assert(choices[0].Value, qt.Equals, uint32(0))
+	c.Assert(choices[1].Title[defaultLanguage], qt.Equals, "No")
+	c.Assert(choices[1].Value, qt.Equals, uint32(1))
+}
+
+func TestOnvoteCreatorStatus(t *testing.T) {
+	c := qt.New(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/elections/abcd" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		fmt.Fprint(w, `{
+			"electionId": "abcd",
+			"status": "ENDED",
+			"startDate": "2026-10-01T12:00:00Z",
+			"endDate": "2026-10-02T12:00:00Z",
+			"voteCount": 3,
+			"finalResults": true,
+			"result": [["1", "0", "2"]],
+			"census": {"maxCensusSize": 10}
+		}`)
+	}))
+	defer server.Close()
+
+	creator := &OnvoteCreator{VocdoniEndpoint: server.URL}
+	status, err := creator.Status(context.Background(), "abcd")
+	c.Assert(err, qt.IsNil)
+	c.Assert(status, qt.DeepEquals, &Status{
+		Status:    StatusEnded,
+		StartDate: time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC),
+		EndDate:   time.Date(2026, 10, 2, 12, 0, 0, 0, time.UTC),
+		VoteCount: 3,
+	})
+	results, err := creator.Results(context.Background(), "abcd")
+	c.Assert(err, qt.IsNil)
+	c.Assert(results, qt.DeepEquals, &Results{
+		Tally:      [][]uint64{{1, 0, 2}},
+		VoteCount:  3,
+		CensusSize: 10,
+		Final:      true,
+	})
+	_, err = creator.Status(context.Background(), "missing")
+	c.Assert(err, qt.ErrorIs, ErrElectionNotFound)
+	c.Assert(creator.Cancel(context.Background(), "abcd"), qt.ErrorIs, ErrCancelNotSupported)
+}
+
+func TestVocdoniElectionBlocks(t *testing.T) {
+	c := qt.New(t)
+
+	// the chain is at height 1000 and produces a block every 10 seconds
+	const height, blockTime = 1000, 10
+	now := time.Now().Unix()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var date int64
+		if _, err := fmt.Sscanf(r.URL.Path, "/chain/dateToBlock/%d", &date); err != nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		fmt.Fprintf(w, `{"height": %d}`, height+(date-now)/blockTime)
+	}))
+	defer server.Close()
+	creator := &VocdoniCreator{endpoint: server.URL}
+	ctx := context.Background()
+
+	// without start date, the election starts now and lasts an hour
+	startBlock, blockCount, err := creator.electionBlocks(ctx, &ElectionOptions{Duration: 1}, height)
+	c.Assert(err, qt.IsNil)
+	c.Assert(startBlock, qt.Equals, uint32(0))
+	c.Assert(blockCount, qt.Equals, uint32(360))
+
+	// with a start date in two hours, the blocks are counted from it
+	startDate := time.Unix(now, 0).Add(2 * time.Hour)
+	startBlock, blockCount, err = creator.electionBlocks(ctx, &ElectionOptions{
+		Duration:  1,
+		StartDate: &startDate,
+	}, height)
+	c.Assert(err, qt.IsNil)
+	c.Assert(startBlock, qt.Equals, uint32(height+720))
+	c.Assert(blockCount, qt.Equals, uint32(360))
+
+	// the exact end date is used instead of the duration
+	endDate := time.Unix(now, 0).Add(90 * time.Minute)
+	startBlock, blockCount, err = creator.electionBlocks(ctx, &ElectionOptions{
+		Duration: 2,
+		EndDate:  &endDate,
+	}, height)
+	c.Assert(err, qt.IsNil)
+	c.Assert(startBlock, qt.Equals, uint32(0))
+	c.Assert(blockCount, qt.Equals, uint32(540))
+
+	// the election must end after its start block
+	_, _, err = creator.electionBlocks(ctx, &ElectionOptions{StartDate: &startDate}, height)
+	c.Assert(err, qt.IsNotNil)
+	_, _, err = creator.electionBlocks(ctx, &ElectionOptions{Duration: 1}, height+360)
+	c.Assert(err, qt.IsNotNil)
+}
@@ -0,0 +1,13 @@
+package election
+
+import "fmt"
+
+var (
+	ErrUnsupportedCensus  = fmt.Errorf("census not supported by the election backend")
+	ErrEmptyCensus        = fmt.Errorf("empty election census")
+	ErrUnsupportedQuorum  = fmt.Errorf("quorum not supported by the election backend")
+	ErrCancelNotSupported = fmt.Errorf("election cancel not supported by the backend")
+	ErrEndNotSupported    = fmt.Errorf("election end not supported by the backend")
+	ErrElectionNotFound   = fmt.Errorf("election not found")
+	ErrElectionNotActive  = fmt.Errorf("election not active")
+)
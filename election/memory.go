@@ -0,0 +1,238 @@
+package election
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// DefaultMemoryURL is the default base url of the elections created by the
+// MemoryCreator
+const DefaultMemoryURL = "https://vote.test/elections"
+
+// memoryElection is an election stored by the MemoryCreator.
+type memoryElection struct {
+	opts      *ElectionOptions
+	mode      *BallotMode
+	startDate time.Time
+	endDate   time.Time
+	tally     [][]uint64
+	ballots   [][]int
+	votes     uint64
+	canceled  bool
+}
+
+// MemoryCreator is a deterministic in-memory Creator, intended for tests. The
+// ids of the elections are sequential and their status is calculated using
+// the Now function, which can be replaced to control the time. The votes are
+// registered with the Vote method.
+type MemoryCreator struct {
+	// BaseURL is the base url of the elections, DefaultMemoryURL if empty
+	BaseURL string
+	// CensusSize is the census size reported in the results
+	CensusSize uint64
+	// Now returns the current time, time.Now if nil
+	Now func() time.Time
+
+	mtx       sync.Mutex
+	elections map[string]*memoryElection
+	ids       []string
+}
+
+// Create stores a new election with the given options and returns it, with
+// a sequential id. It returns an error if the ballot mode of the options or
+// its census are not valid.
+func (m *MemoryCreator) Create(_ context.Context, opts *ElectionOptions) (*Election, error) {
+	mode, err := opts.BallotMode()
+	if err != nil {
+		return nil, fmt.Errorf("error getting the election ballot mode: %w", err)
+	}
+	if opts.Census != nil {
+		if err := opts.Census.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid election census: %w", err)
+		}
+	}
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	if m.elections == nil {
+		m.elections = make(map[string]*memoryElection)
+	}
+	startDate := m.now()
+	if opts.StartDate != nil {
+		startDate = *opts.StartDate
+	}
+	tally := make([][]uint64, mode.MaxCount)
+	for i := range tally {
+		tally[i] = make([]uint64, mode.MaxValue+1)
+	}
+	storedOpts := *opts
+	id := fmt.Sprintf("%d", len(m.ids)+1)
+	m.ids = append(m.ids, id)
+	m.elections[id] = &memoryElection{
+		opts:      &storedOpts,
+		mode:      mode,
+		startDate: startDate,
+		endDate:   opts.End(startDate),
+		tally:     tally,
+	}
+	return &Election{ID: id, URL: fmt.Sprintf("%s/%s", m.baseURL(), id)}, nil
+}
+
+// Status returns the status of the election with the given id.
+func (m *MemoryCreator) Status(_ context.Context, electionID string) (*Status, error) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	e, ok := m.elections[electionID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrElectionNotFound, electionID)
+	}
+	return &Status{
+		Status:    m.status(e),
+		StartDate: e.startDate,
+		EndDate:   e.endDate,
+		VoteCount: e.votes,
+	}, nil
+}
+
+// Results returns a copy of the current results of the election with the
+// given id, including its ballots, which are final if it has ended or has
+// been canceled.
+func (m *MemoryCreator) Results(_ context.Context, electionID string) (*Results, error) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	e, ok := m.elections[electionID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrElectionNotFound, electionID)
+	}
+	tally := make([][]uint64, len(e.tally))
+	for i, field := range e.tally {
+		tally[i] = append([]uint64{}, field...)
+	}
+	ballots := make([][]int, len(e.ballots))
+	for i, ballot := range e.ballots {
+		ballots[i] = append([]int{}, ballot...)
+	}
+	status := m.status(e)
+	return &Results{
+		Tally:      tally,
+		Ballots:    ballots,
+		VoteCount:  e.votes,
+		CensusSize: m.CensusSize,
+		Final:      status == StatusEnded || status == StatusCanceled,
+	}, nil
+}
+
+// Cancel cancels the election with the given id if it has not ended yet.
+func (m *MemoryCreator) Cancel(_ context.Context, electionID string) error {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	e, ok := m.elections[electionID]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrElectionNotFound, electionID)
+	}
+	if status := m.status(e); status == StatusEnded || status == StatusCanceled {
+		return fmt.Errorf("%w: %s", ErrElectionNotActive, electionID)
+	}
+	e.canceled = true
+	return nil
+}
+
+// End ends the election with the given id at the current time if it is
+// ongoing.
+func (m *MemoryCreator) End(_ context.Context, electionID string) error {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	e, ok := m.elections[electionID]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrElectionNotFound, electionID)
+	}
+	if m.status(e) != StatusOngoing {
+		return fmt.Errorf("%w: %s", ErrElectionNotActive, electionID)
+	}
+	e.endDate = m.now()
+	return nil
+}
+
+// Vote registers a ballot in the election with the given id, which contains
+// a value for every field of the ballot. It returns an error if the election
+// is not ongoing or the ballot is not valid for its ballot mode.
+func (m *MemoryCreator) Vote(electionID string, ballot ...int) error {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	e, ok := m.elections[electionID]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrElectionNotFound, electionID)
+	}
+	if m.status(e) != StatusOngoing {
+		return fmt.Errorf("%w: %s", ErrElectionNotActive, electionID)
+	}
+	if len(ballot) == 0 || len(ballot) > e.mode.MaxCount {
+		return fmt.Errorf("invalid number of ballot fields: %d", len(ballot))
+	}
+	seen := map[int]bool{}
+	for _, value := range ballot {
+		if value < 0 || value > e.mode.MaxValue {
+			return fmt.Errorf("invalid ballot value: %d", value)
+		}
+		if e.mode.UniqueChoices && seen[value] {
+			return fmt.Errorf("duplicated ballot value: %d", value)
+		}
+		seen[value] = true
+	}
+	for i, value := range ballot {
+		e.tally[i][value]++
+	}
+	e.ballots = append(e.ballots, append([]int{}, ballot...))
+	e.votes++
+	return nil
+}
+
+// Options returns a copy of the options of the election with the given id,
+// or nil if it does not exist.
+func (m *MemoryCreator) Options(electionID string) *ElectionOptions {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	e, ok := m.elections[electionID]
+	if !ok {
+		return nil
+	}
+	opts := *e.opts
+	return &opts
+}
+
+// IDs returns the ids of the created elections in creation order.
+func (m *MemoryCreator) IDs() []string {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	return append([]string{}, m.ids...)
+}
+
+// status returns the status of the given election at the current time.
+func (m *MemoryCreator) status(e *memoryElection) string {
+	now := m.now()
+	switch {
+	case e.canceled:
+		return StatusCanceled
+	case now.Before(e.startDate):
+		return StatusUpcoming
+	case !now.Before(e.endDate):
+		return StatusEnded
+	default:
+		return StatusOngoing
+	}
+}
+
+func (m *MemoryCreator) now() time.Time {
+	if m.Now != nil {
+		return m.Now()
+	}
+	return time.Now()
+}
+
+func (m *MemoryCreator) baseURL() string {
+	if m.BaseURL != "" {
+		return m.BaseURL
+	}
+	return DefaultMemoryURL
+}
@@ -0,0 +1,141 @@
+package election
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestMemoryCreator(t *testing.T) {
+	c := qt.New(t)
+
+	ctx := context.Background()
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	creator := &MemoryCreator{CensusSize: 10, Now: func() time.Time { return now }}
+
+	// create a single choice election and a scheduled one
+	e, err := creator.Create(ctx, &ElectionOptions{
+		Question: "Question?",
+		Options:  []string{"A", "B", "C"},
+		Duration: 24,
+	})
+	c.Assert(err, qt.IsNil)
+	c.Assert(e, qt.DeepEquals, &Election{ID: "1", URL: DefaultMemoryURL + "/1"})
+	startDate := now.Add(time.Hour)
+	scheduled, err := creator.Create(ctx, &ElectionOptions{
+		Question:  "Scheduled?",
+		Options:   []string{"Yes", "No"},
+		Duration:  1,
+		StartDate: &startDate,
+	})
+	c.Assert(err, qt.IsNil)
+	c.Assert(scheduled.ID, qt.Equals, "2")
+	c.Assert(creator.IDs(), qt.DeepEquals, []string{"1", "2"})
+	c.Assert(creator.Options("2").Question, qt.Equals, "Scheduled?")
+
+	// invalid ballot modes are rejected
+	_, err = creator.Create(ctx, &ElectionOptions{Options: []string{"A"}, VoteType: "quadratic"})
+	c.Assert(err, qt.IsNotNil)
+
+	// vote in the ongoing election, the scheduled one is not active yet
+	c.Assert(creator.Vote("1", 0), qt.IsNil)
+	c.Assert(creator.Vote("1", 2), qt.IsNil)
+	c.Assert(creator.Vote("1", 2), qt.IsNil)
+	c.Assert(creator.Vote("1", 3), qt.IsNotNil)
+	c.Assert(creator.Vote("1", 0, 1), qt.IsNotNil)
+	c.Assert(creator.Vote("2", 0), qt.ErrorIs, ErrElectionNotActive)
+	c.Assert(creator.Vote("3", 0), qt.ErrorIs, ErrElectionNotFound)
+
+	status, err := creator.Status(ctx, "1")
+	c.Assert(err, qt.IsNil)
+	c.Assert(status, qt.DeepEquals, &Status{
+		Status:    StatusOngoing,
+		StartDate: now,
+		EndDate:   now.Add(24 * time.Hour),
+		VoteCount: 3,
+	})
+	status, err = creator.Status(ctx, "2")
+	c.Assert(err, qt.IsNil)
+	c.Assert(status.Status, qt.Equals, StatusUpcoming)
+
+	results, err := creator.Results(ctx, "1")
+	c.Assert(err, qt.IsNil)
+	c.Assert(results, qt.DeepEquals, &Results{
+		Tally:      [][]uint64{{1, 0, 2}},
+		Ballots:    [][]int{{0}, {2}, {2}},
+		VoteCount:  3,
+		CensusSize: 10,
+	})
+
+	// cancel the scheduled election, it can not be canceled twice
+	c.Assert(creator.Cancel(ctx, "2"), qt.IsNil)
+	c.Assert(creator.Cancel(ctx, "2"), qt.ErrorIs, ErrElectionNotActive)
+	status, err = creator.Status(ctx, "2")
+	c.Assert(err, qt.IsNil)
+	c.Assert(status.Status, qt.Equals, StatusCanceled)
+
+	// the scheduled election can not be ended, it is not ongoing
+	c.Assert(creator.End(ctx, "2"), qt.ErrorIs, ErrElectionNotActive)
+	c.Assert(creator.End(ctx, "3"), qt.ErrorIs, ErrElectionNotFound)
+
+	// move the time to the end of the first election, its results are final
+	now = now.Add(24 * time.Hour)
+	status, err = creator.Status(ctx, "1")
+	c.Assert(err, qt.IsNil)
+	c.Assert(status.Status, qt.Equals, StatusEnded)
+	results, err = creator.Results(ctx, "1")
+	c.Assert(err, qt.IsNil)
+	c.Assert(results.Final, qt.IsTrue)
+	c.Assert(creator.Vote("1", 0), qt.ErrorIs, ErrElectionNotActive)
+}
+
+func TestMemoryCreatorApproval(t *testing.T) {
+	c := qt.New(t)
+
+	creator := &MemoryCreator{}
+	e, err := creator.Create(context.Background(), &ElectionOptions{
+		Question: "Approve?",
+		Options:  []string{"A", "B", "C"},
+		Duration: 1,
+		VoteType: VoteTypeApproval,
+	})
+	c.Assert(err, qt.IsNil)
+	c.Assert(creator.Vote(e.ID, 1, 0, 1), qt.IsNil)
+	c.Assert(creator.Vote(e.ID, 1, 1, 0), qt.IsNil)
+	c.Assert(creator.Vote(e.ID, 2, 0, 0), qt.IsNotNil)
+	results, err := creator.Results(context.Background(), e.ID)
+	c.Assert(err, qt.IsNil)
+	c.Assert(results.Tally, qt.DeepEquals, [][]uint64{{0, 2}, {1, 1}, {1, 1}})
+	c.Assert(results.Ballots, qt.DeepEquals, [][]int{{1, 0, 1}, {1, 1, 0}})
+}
+
+func TestMemoryCreatorEnd(t *testing.T) {
+	c := qt.New(t)
+
+	ctx := context.Background()
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	creator := &MemoryCreator{Now: func() time.Time { return now }}
+	e, err := creator.Create(ctx, &ElectionOptions{
+		Question: "Question?",
+		Options:  []string{"A", "B"},
+		Duration: 24,
+	})
+	c.Assert(err, qt.IsNil)
+	c.Assert(creator.Vote(e.ID, 1), qt.IsNil)
+
+	// ending the election makes its results final at the current time
+	now = now.Add(time.Hour)
+	c.Assert(creator.End(ctx, e.ID), qt.IsNil)
+	status, err := creator.Status(ctx, e.ID)
+	c.Assert(err, qt.IsNil)
+	c.Assert(status.Status, qt.Equals, StatusEnded)
+	c.Assert(status.EndDate, qt.Equals, now)
+	results, err := creator.Results(ctx, e.ID)
+	c.Assert(err, qt.IsNil)
+	c.Assert(results.Final, qt.IsTrue)
+	c.Assert(results.Tally, qt.DeepEquals, [][]uint64{{0, 1}})
+	c.Assert(creator.End(ctx, e.ID), qt.ErrorIs, ErrElectionNotActive)
+	c.Assert(creator.Vote(e.ID, 0), qt.ErrorIs, ErrElectionNotActive)
+}
@@ -0,0 +1,589 @@
+package election
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"go.vocdoni.io/dvote/crypto/ethereum"
+	"go.vocdoni.io/dvote/types"
+	"go.vocdoni.io/proto/build/go/models"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	// DefaultVocdoniVoteURL is the default base url of the vocdoni app page
+	// where the users can vote in the elections created directly
+	DefaultVocdoniVoteURL = "https://app.vocdoni.io/processes"
+	// defaultLanguage is the language of the election metadata texts
+	defaultLanguage = "default"
+	// vocdoni API timeouts and intervals
+	vocdoniRequestTimeout = 10 * time.Second
+	vocdoniCreateTimeout  = 2 * time.Minute
+	vocdoniCheckInterval  = 2 * time.Second
+)
+
+// vocdoni API request and response payloads, only with the fields used by
+// the VocdoniCreator. The API client and the API types of the dvote module
+// are not used because they depend on libp2p, whose protobufs conflict with
+// the hub ones, see ipfsCID.
+type vocdoniChainInfo struct {
+	ID     string `json:"chainId"`
+	Height uint32 `json:"height"`
+}
+
+type vocdoniHeight struct {
+	Height uint32 `json:"height"`
+}
+
+type vocdoniAccount struct {
+	Nonce uint32 `json:"nonce"`
+}
+
+type vocdoniCensus struct {
+	CensusID types.HexBytes `json:"censusID"`
+	URI      string         `json:"uri"`
+}
+
+type vocdoniParticipant struct {
+	Key    types.HexBytes `json:"key"`
+	Weight *types.BigInt  `json:"weight"`
+}
+
+type vocdoniParticipants struct {
+	Participants []*vocdoniParticipant `json:"participants"`
+}
+
+type vocdoniElectionCreate struct {
+	TxPayload  []byte         `json:"txPayload,omitempty"`
+	Metadata   []byte         `json:"metadata,omitempty"`
+	ElectionID types.HexBytes `json:"electionID,omitempty"`
+}
+
+type vocdoniTransaction struct {
+	Payload []byte `json:"payload"`
+}
+
+type vocdoniElectionCensus struct {
+	MaxCensusSize uint64 `json:"maxCensusSize"`
+}
+
+type vocdoniElectionInfo struct {
+	Status       string                 `json:"status"`
+	StartDate    time.Time              `json:"startDate"`
+	EndDate      time.Time              `json:"endDate"`
+	VoteCount    uint64                 `json:"voteCount"`
+	FinalResults bool                   `json:"finalResults"`
+	Results      [][]*types.BigInt      `json:"result"`
+	Census       *vocdoniElectionCensus `json:"census"`
+}
+
+type vocdoniLanguageString map[string]string
+
+type vocdoniChoice struct {
+	Title vocdoniLanguageString `json:"title"`
+	Value uint32                `json:"value"`
+}
+
+type vocdoniQuestion struct {
+	Choices     []*vocdoniChoice      `json:"choices"`
+	Description vocdoniLanguageString `json:"description"`
+	Title       vocdoniLanguageString `json:"title"`
+}
+
+type vocdoniResultsDetails struct {
+	Aggregation string `json:"aggregation"`
+	Display     string `json:"display"`
+}
+
+type vocdoniMetadata struct {
+	Title       vocdoniLanguageString `json:"title"`
+	Version     string                `json:"version"`
+	Description vocdoniLanguageString `json:"description"`
+	Questions   []*vocdoniQuestion    `json:"questions"`
+	Results     vocdoniResultsDetails `json:"results"`
+}
+
+// VocdoniCreator creates elections directly in the vocdoni chain, without the
+// onvote frame service, using the vocdoni API with the account of the
+// organization. The users vote in the vocdoni app page of every election.
+type VocdoniCreator struct {
+	endpoint string
+	token    string
+	signer   *ethereum.SignKeys
+	voteURL  string
+}
+
+// NewVocdoniCreator returns a VocdoniCreator that uses the vocdoni API of the
+// given endpoint to create the elections with the organization account of
+// the given hex private key. The vote url is the base url of the page where
+// the users vote, the id of every election is appended to it.
+func NewVocdoniCreator(endpoint, privateKey, voteURL string) (*VocdoniCreator, error) {
+	if endpoint == "" {
+		return nil, fmt.Errorf("vocdoni API endpoint not set")
+	}
+	signer := ethereum.NewSignKeys()
+	if err := signer.AddHexKey(strings.TrimPrefix(privateKey, "0x")); err != nil {
+		return nil, fmt.Errorf("error loading vocdoni account key: %w", err)
+	}
+	// the census endpoints require a bearer token to identify the owner of
+	// the censuses, so a random one is generated
+	token, err := randomToken()
+	if err != nil {
+		return nil, err
+	}
+	if voteURL == "" {
+		voteURL = DefaultVocdoniVoteURL
+	}
+	return &VocdoniCreator{
+		endpoint: strings.TrimSuffix(endpoint, "/"),
+		token:    token,
+		signer:   signer,
+		voteURL:  strings.TrimSuffix(voteURL, "/"),
+	}, nil
+}
+
+// Create creates an election with the given options in the vocdoni chain and
+// returns it with the url of the page to vote in it. The census of the
+// election is created from the addresses of the census options, so only the
+// addresses and voters censuses are supported. It waits until the election
+// is created or the context is canceled.
+func (v *VocdoniCreator) Create(ctx context.Context, opts *ElectionOptions) (*Election, error) {
+	ballotMode, err := opts.BallotMode()
+	if err != nil {
+		return nil, fmt.Errorf("error getting the election ballot mode: %w", err)
+	}
+	// create and publish the census of the election
+	censusRoot, censusURI, censusSize, err := v.newCensus(ctx, opts.Census)
+	if err != nil {
+		return nil, fmt.Errorf("error creating the election census: %w", err)
+	}
+	// calculate the blocks of the election from its start and end dates
+	chainInfo := &vocdoniChainInfo{}
+	if err := v.request(ctx, http.MethodGet, "chain/info", nil, chainInfo); err != nil {
+		return nil, fmt.Errorf("error getting the chain info: %w", err)
+	}
+	startBlock, blockCount, err := v.electionBlocks(ctx, opts, chainInfo.Height)
+	if err != nil {
+		return nil, err
+	}
+	// compose the election metadata and reference it by its ipfs CID
+	metadata, err := json.Marshal(electionMetadata(opts))
+	if err != nil {
+		return nil, fmt.Errorf("error encoding the election metadata: %w", err)
+	}
+	metadataCID, err := ipfsCID(metadata)
+	if err != nil {
+		return nil, fmt.Errorf("error calculating the election metadata CID: %w", err)
+	}
+	metadataURI := "ipfs://" + metadataCID
+	process := &models.Process{
+		EntityId:   v.signer.Address().Bytes(),
+		StartBlock: startBlock,
+		BlockCount: blockCount,
+		CensusRoot: censusRoot,
+		CensusURI:  &censusURI,
+		Status:     models.ProcessStatus_READY,
+		EnvelopeType: &models.EnvelopeType{
+			UniqueValues: ballotMode.UniqueChoices,
+		},
+		Mode: &models.ProcessMode{
+			AutoStart:     true,
+			Interruptible: true,
+		},
+		VoteOptions: &models.ProcessVoteOptions{
+			MaxCount:     uint32(ballotMode.MaxCount),
+			MaxValue:     uint32(ballotMode.MaxValue),
+			MaxTotalCost: uint32(ballotMode.MaxCount * ballotMode.MaxValue),
+			CostExponent: 1,
+		},
+		CensusOrigin:  models.CensusOrigin_OFF_CHAIN_TREE_WEIGHTED,
+		Metadata:      &metadataURI,
+		MaxCensusSize: censusSize,
+	}
+	// sign and send the new process transaction with the election metadata
+	nonce, err := v.nonce(ctx)
+	if err != nil {
+		return nil, err
+	}
+	signedTx, err := v.signTx(&models.Tx{
+		Payload: &models.Tx_NewProcess{
+			NewProcess: &models.NewProcessTx{
+				Txtype:  models.TxType_NEW_PROCESS,
+				Nonce:   nonce,
+				Process: process,
+			},
+		},
+	}, chainInfo.ID)
+	if err != nil {
+		return nil, err
+	}
+	created := &vocdoniElectionCreate{}
+	if err := v.request(ctx, http.MethodPost, "elections", &vocdoniElectionCreate{
+		TxPayload: signedTx,
+		Metadata:  metadata,
+	}, created); err != nil {
+		return nil, fmt.Errorf("error creating the election: %w", err)
+	}
+	electionID := created.ElectionID.String()
+	// wait until the election is created
+	if err := v.waitElection(ctx, electionID); err != nil {
+		return nil, fmt.Errorf("error waiting for the election creation: %w", err)
+	}
+	return &Election{
+		ID:  electionID,
+		URL: fmt.Sprintf("%s/%s", v.voteURL, electionID),
+	}, nil
+}
+
+// Status returns the status of the election with the given id.
+func (v *VocdoniCreator) Status(ctx context.Context, electionID string) (*Status, error) {
+	info, err := vocdoniElection(ctx, v.endpoint, electionID)
+	if err != nil {
+		return nil, err
+	}
+	return vocdoniStatus(info), nil
+}
+
+// Results returns the results of the election with the given id.
+func (v *VocdoniCreator) Results(ctx context.Context, electionID string) (*Results, error) {
+	info, err := vocdoniElection(ctx, v.endpoint, electionID)
+	if err != nil {
+		return nil, err
+	}
+	return vocdoniResults(info), nil
+}
+
+// Cancel cancels the election with the given id, which must have been
+// created by the organization account of the creator.
+func (v *VocdoniCreator) Cancel(ctx context.Context, electionID string) error {
+	if err := v.setStatus(ctx, electionID, models.ProcessStatus_CANCELED); err != nil {
+		return fmt.Errorf("error canceling the election: %w", err)
+	}
+	return nil
+}
+
+// End ends the election with the given id before its end date, which must
+// have been created by the organization account of the creator. The results
+// are final once the vocdoni chain has computed them.
+func (v *VocdoniCreator) End(ctx context.Context, electionID string) error {
+	if err := v.setStatus(ctx, electionID, models.ProcessStatus_ENDED); err != nil {
+		return fmt.Errorf("error ending the election: %w", err)
+	}
+	return nil
+}
+
+// setStatus sends a transaction to set the given status to the election with
+// the given id, which must be interruptible.
+func (v *VocdoniCreator) setStatus(ctx context.Context, electionID string, status models.ProcessStatus) error {
+	id, err := hex.DecodeString(strings.TrimPrefix(electionID, "0x"))
+	if err != nil {
+		return fmt.Errorf("invalid election id %s: %w", electionID, err)
+	}
+	chainInfo := &vocdoniChainInfo{}
+	if err := v.request(ctx, http.MethodGet, "chain/info", nil, chainInfo); err != nil {
+		return fmt.Errorf("error getting the chain info: %w", err)
+	}
+	nonce, err := v.nonce(ctx)
+	if err != nil {
+		return err
+	}
+	signedTx, err := v.signTx(&models.Tx{
+		Payload: &models.Tx_SetProcess{
+			SetProcess: &models.SetProcessTx{
+				Txtype:    models.TxType_SET_PROCESS_STATUS,
+				Nonce:     nonce,
+				ProcessId: id,
+				Status:    &status,
+			},
+		},
+	}, chainInfo.ID)
+	if err != nil {
+		return err
+	}
+	return v.request(ctx, http.MethodPost, "chain/transactions", &vocdoniTransaction{Payload: signedTx}, nil)
+}
+
+// newCensus creates a weighted census with the addresses of the given census
+// options, every address with a weight of 1, and publishes it. It returns the
+// root, the uri and the size of the published census.
+func (v *VocdoniCreator) newCensus(ctx context.Context, census *Census) (types.HexBytes, string, uint64, error) {
+	if census == nil {
+		return nil, "", 0, fmt.Errorf("%w: default census", ErrUnsupportedCensus)
+	}
+	if err := census.Validate(); err != nil {
+		return nil, "", 0, err
+	}
+	if census.Type != CensusTypeAddresses && census.Type != CensusTypeVoters {
+		return nil, "", 0, fmt.Errorf("%w: %s", ErrUnsupportedCensus, census.Type)
+	}
+	if len(census.Addresses) == 0 {
+		return nil, "", 0, ErrEmptyCensus
+	}
+	participants := &vocdoniParticipants{}
+	for _, address := range census.Addresses {
+		participants.Participants = append(participants.Participants, &vocdoniParticipant{
+			Key:    common.HexToAddress(address).Bytes(),
+			Weight: new(types.BigInt).SetUint64(1),
+		})
+	}
+	created := &vocdoniCensus{}
+	if err := v.request(ctx, http.MethodPost, "censuses/weighted", nil, created); err != nil {
+		return nil, "", 0, err
+	}
+	censusID := created.CensusID.String()
+	if err := v.request(ctx, http.MethodPost, "censuses/"+censusID+"/participants", participants, nil); err != nil {
+		return nil, "", 0, err
+	}
+	published := &vocdoniCensus{}
+	if err := v.request(ctx, http.MethodPost, "censuses/"+censusID+"/publish", nil, published); err != nil {
+		return nil, "", 0, err
+	}
+	return published.CensusID, published.URI, uint64(len(participants.Participants)), nil
+}
+
+// electionBlocks returns the start block and the number of blocks of an
+// election with the given options, based on the given current height of the
+// chain. If the start date is not set, the election starts when it is
+// created, so the start block is zero and the blocks are counted from the
+// current height.
+func (v *VocdoniCreator) electionBlocks(ctx context.Context, opts *ElectionOptions, currentHeight uint32) (uint32, uint32, error) {
+	var startBlock uint32
+	fromBlock, startDate := currentHeight, time.Now()
+	if opts.StartDate != nil {
+		startDate = *opts.StartDate
+		var err error
+		if startBlock, err = v.dateToHeight(ctx, startDate); err != nil {
+			return 0, 0, fmt.Errorf("error estimating the election start block: %w", err)
+		}
+		fromBlock = startBlock
+	}
+	endDate := opts.End(startDate)
+	endBlock, err := v.dateToHeight(ctx, endDate)
+	if err != nil {
+		return 0, 0, fmt.Errorf("error estimating the election end block: %w", err)
+	}
+	if endBlock <= fromBlock {
+		return 0, 0, fmt.Errorf("election end block %d is not after its start block %d", endBlock, fromBlock)
+	}
+	return startBlock, endBlock - fromBlock, nil
+}
+
+// dateToHeight returns the estimated block height of the vocdoni chain at
+// the given date.
+func (v *VocdoniCreator) dateToHeight(ctx context.Context, date time.Time) (uint32, error) {
+	height := &vocdoniHeight{}
+	if err := v.request(ctx, http.MethodGet, fmt.Sprintf("chain/dateToBlock/%d", date.Unix()), nil, height); err != nil {
+		return 0, err
+	}
+	return height.Height, nil
+}
+
+// nonce returns the current nonce of the organization account.
+func (v *VocdoniCreator) nonce(ctx context.Context) (uint32, error) {
+	account := &vocdoniAccount{}
+	if err := v.request(ctx, http.MethodGet, "accounts/"+v.signer.AddressString(), nil, account); err != nil {
+		return 0, fmt.Errorf("error getting the account info: %w", err)
+	}
+	return account.Nonce, nil
+}
+
+// signTx signs the given transaction for the given chain with the
+// organization account and returns the encoded signed transaction.
+func (v *VocdoniCreator) signTx(tx *models.Tx, chainID string) ([]byte, error) {
+	txData, err := proto.Marshal(tx)
+	if err != nil {
+		return nil, fmt.Errorf("error encoding the transaction: %w", err)
+	}
+	signature, err := v.signer.SignVocdoniTx(txData, chainID)
+	if err != nil {
+		return nil, fmt.Errorf("error signing the transaction: %w", err)
+	}
+	signedTx, err := proto.Marshal(&models.SignedTx{Tx: txData, Signature: signature})
+	if err != nil {
+		return nil, fmt.Errorf("error encoding the signed transaction: %w", err)
+	}
+	return signedTx, nil
+}
+
+// waitElection waits until the election with the given id is found in the
+// vocdoni API, which means that it has been created.
+func (v *VocdoniCreator) waitElection(ctx context.Context, electionID string) error {
+	waitCtx, cancel := context.WithTimeout(ctx, vocdoniCreateTimeout)
+	defer cancel()
+	for {
+		_, err := vocdoniElection(waitCtx, v.endpoint, electionID)
+		if err == nil {
+			return nil
+		}
+		if !errors.Is(err, ErrElectionNotFound) {
+			return err
+		}
+		select {
+		case <-waitCtx.Done():
+			return waitCtx.Err()
+		case <-time.After(vocdoniCheckInterval):
+		}
+	}
+}
+
+// request sends a request with the given method and JSON body to the given
+// path of the vocdoni API, authenticated with the creator token, and decodes
+// the JSON response into out if it is not nil.
+func (v *VocdoniCreator) request(ctx context.Context, method, path string, body, out any) error {
+	internalCtx, cancel := context.WithTimeout(ctx, vocdoniRequestTimeout)
+	defer cancel()
+	var reqBody io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("error encoding the request: %w", err)
+		}
+		reqBody = bytes.NewReader(encoded)
+	}
+	req, err := http.NewRequestWithContext(internalCtx, method, fmt.Sprintf("%s/%s", v.endpoint, path), reqBody)
+	if err != nil {
+		return fmt.Errorf("error creating the request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+v.token)
+	req.Header.Set("Content-Type", "application/json")
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("error sending the request: %w", err)
+	}
+	defer res.Body.Close()
+	resBody, err := io.ReadAll(res.Body)
+	if err != nil {
+		return fmt.Errorf("error reading the response: %w", err)
+	}
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %s: %s", res.Status, resBody)
+	}
+	if out != nil {
+		if err := json.Unmarshal(resBody, out); err != nil {
+			return fmt.Errorf("error decoding the response: %w", err)
+		}
+	}
+	return nil
+}
+
+// randomToken returns a random UUID (v4) to be used as bearer token.
+func randomToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("error generating the token: %w", err)
+	}
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]), nil
+}
+
+// electionMetadata returns the vocdoni metadata of an election with the
+// given options, with a single question whose choices are the options and
+// the description of the options as the description of the election.
+func electionMetadata(opts *ElectionOptions) *vocdoniMetadata {
+	question := &vocdoniQuestion{
+		Title:       vocdoniLanguageString{defaultLanguage: opts.Question},
+		Description: vocdoniLanguageString{defaultLanguage: ""},
+		Choices:     []*vocdoniChoice{},
+	}
+	for i, option := range opts.Options {
+		question.Choices = append(question.Choices, &vocdoniChoice{
+			Title: vocdoniLanguageString{defaultLanguage: option},
+			Value: uint32(i),
+		})
+	}
+	return &vocdoniMetadata{
+		Title:       vocdoniLanguageString{defaultLanguage: opts.Question},
+		Description: vocdoniLanguageString{defaultLanguage: opts.Description},
+		Version:     "1.0",
+		Questions:   []*vocdoniQuestion{question},
+		Results: vocdoniResultsDetails{
+			Aggregation: "discrete-values",
+			Display:     "multiple-choice",
+		},
+	}
+}
+
+// vocdoniElection gets the election with the given id from the vocdoni API
+// of the given endpoint.
+func vocdoniElection(ctx context.Context, endpoint, electionID string) (*vocdoniElectionInfo, error) {
+	internalCtx, cancel := context.WithTimeout(ctx, vocdoniRequestTimeout)
+	defer cancel()
+	electionURL := fmt.Sprintf("%s/elections/%s", strings.TrimSuffix(endpoint, "/"), electionID)
+	req, err := http.NewRequestWithContext(internalCtx, http.MethodGet, electionURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error creating the election request: %w", err)
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error getting the election: %w", err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("%w: %s", ErrElectionNotFound, electionID)
+	}
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("error getting the election: %s", res.Status)
+	}
+	info := &vocdoniElectionInfo{}
+	if err := json.NewDecoder(res.Body).Decode(info); err != nil {
+		return nil, fmt.Errorf("error decoding the election: %w", err)
+	}
+	return info, nil
+}
+
+// vocdoniStatus returns the status of the given vocdoni election.
+func vocdoniStatus(info *vocdoniElectionInfo) *Status {
+	status := &Status{
+		StartDate: info.StartDate,
+		EndDate:   info.EndDate,
+		VoteCount: info.VoteCount,
+	}
+	switch info.Status {
+	case models.ProcessStatus_CANCELED.String():
+		status.Status = StatusCanceled
+	case models.ProcessStatus_ENDED.String(), models.ProcessStatus_RESULTS.String():
+		status.Status = StatusEnded
+	default:
+		if info.StartDate.After(time.Now()) {
+			status.Status = StatusUpcoming
+		} else {
+			status.Status = StatusOngoing
+		}
+	}
+	return status
+}
+
+// vocdoniResults returns the results of the given vocdoni election.
+func vocdoniResults(info *vocdoniElectionInfo) *Results {
+	results := &Results{
+		Tally:     make([][]uint64, 0, len(info.Results)),
+		VoteCount: info.VoteCount,
+		Final:     info.FinalResults,
+	}
+	if info.Census != nil {
+		results.CensusSize = info.Census.MaxCensusSize
+	}
+	for _, field := range info.Results {
+		values := make([]uint64, 0, len(field))
+		for _, value := range field {
+			if value == nil {
+				values = append(values, 0)
+				continue
+			}
+			values = append(values, value.MathBigInt().Uint64())
+		}
+		results.Tally = append(results.Tally, values)
+	}
+	return results
+}
@@ -0,0 +1,13 @@
+package frames
+
+import "fmt"
+
+var (
+	ErrInvalidMessage   = fmt.Errorf("invalid frame action message")
+	ErrInvalidSignature = fmt.Errorf("invalid frame action signature")
+	ErrUnexpectedType   = fmt.Errorf("unexpected message type")
+	ErrURLMismatch      = fmt.Errorf("frame url mismatch")
+	ErrInvalidButton    = fmt.Errorf("invalid button index")
+	ErrUnknownSigner    = fmt.Errorf("unknown signer")
+	ErrCheckingSigner   = fmt.Errorf("error checking signer")
+)
@@ -0,0 +1,174 @@
+// Package frames verifies the actions of the farcaster frames: the signed
+// messages that the farcaster clients send to the frame servers when a user
+// presses a frame button.
+package frames
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/vocdoni/votebot/api/hub/protobufs"
+	"github.com/zeebo/blake3"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	// hashLength is the length of the blake3 hashes of the farcaster messages
+	hashLength = 20
+	// farcasterEpoch is the unix time of the farcaster epoch, January 1,
+	// 2021 UTC, from which the timestamps of the messages are counted
+	farcasterEpoch int64 = 1609459200
+)
+
+// Request is the body of the requests sent by the farcaster clients to the
+// frame servers when a frame button is pressed. The untrusted data is sent by
+// the client as is, only the trusted data, the signed message, is verified.
+type Request struct {
+	UntrustedData struct {
+		FID         uint64 `json:"fid"`
+		URL         string `json:"url"`
+		ButtonIndex uint32 `json:"buttonIndex"`
+		InputText   string `json:"inputText"`
+	} `json:"untrustedData"`
+	TrustedData struct {
+		MessageBytes string `json:"messageBytes"`
+	} `json:"trustedData"`
+}
+
+// CastID identifies a cast by the fid of its author and its hash.
+type CastID struct {
+	FID  uint64
+	Hash string
+}
+
+// FrameAction is a verified frame action: the fid of the user that pressed
+// the button, the url of the frame, the index of the button (1-based), the
+// cast that contained the frame, if any, the text input by the user, if any,
+// the time of the action and the public key of the signer.
+type FrameAction struct {
+	FID         uint64
+	URL         string
+	ButtonIndex uint32
+	CastID      *CastID
+	InputText   string
+	Timestamp   time.Time
+	Signer      ed25519.PublicKey
+}
+
+// SignerChecker checks if the given ed25519 public key is a valid signer of
+// the given fid, that is, a key registered by the user to sign messages on
+// their behalf.
+type SignerChecker interface {
+	IsSigner(ctx context.Context, fid uint64, signer ed25519.PublicKey) (bool, error)
+}
+
+// Options defines the checks of a frame action besides its signature. If URL
+// is set, the url of the frame must be the same, ignoring the trailing
+// slash. If Buttons is positive, the button index must be between 1 and it.
+// If Signers is set, the signer of the message must be a valid signer of the
+// fid of the action.
+type Options struct {
+	URL     string
+	Buttons int
+	Signers SignerChecker
+}
+
+// ParseRequest decodes the given body of a frame request and verifies its
+// trusted data, see Verify.
+func ParseRequest(ctx context.Context, body []byte, opts *Options) (*FrameAction, error) {
+	req := &Request{}
+	if err := json.Unmarshal(body, req); err != nil {
+		return nil, fmt.Errorf("%w: error decoding request: %s", ErrInvalidMessage, err)
+	}
+	return Verify(ctx, req.TrustedData.MessageBytes, opts)
+}
+
+// Verify decodes the given hex encoded farcaster message of a frame action
+// and verifies it: the blake3 hash of its data, the ed25519 signature of the
+// hash, its type, and the checks of the given options, which can be nil. It
+// returns the action or an error if the message is not valid.
+func Verify(ctx context.Context, messageBytes string, opts *Options) (*FrameAction, error) {
+	bMsg, err := hex.DecodeString(strings.TrimPrefix(messageBytes, "0x"))
+	if err != nil {
+		return nil, fmt.Errorf("%w: error decoding message: %s", ErrInvalidMessage, err)
+	}
+	msg := &protobufs.Message{}
+	if err := proto.Unmarshal(bMsg, msg); err != nil {
+		return nil, fmt.Errorf("%w: error unmarshalling message: %s", ErrInvalidMessage, err)
+	}
+	// get the message data bytes, which are included in the message or must
+	// be marshalled from the data
+	dataBytes := msg.DataBytes
+	if len(dataBytes) == 0 {
+		if msg.Data == nil {
+			return nil, fmt.Errorf("%w: message without data", ErrInvalidMessage)
+		}
+		if dataBytes, err = proto.Marshal(msg.Data); err != nil {
+			return nil, fmt.Errorf("%w: error marshalling message data: %s", ErrInvalidMessage, err)
+		}
+	}
+	data := &protobufs.MessageData{}
+	if err := proto.Unmarshal(dataBytes, data); err != nil {
+		return nil, fmt.Errorf("%w: error unmarshalling message data: %s", ErrInvalidMessage, err)
+	}
+	// verify the hash and the signature
+	if msg.HashScheme != protobufs.HashScheme_HASH_SCHEME_BLAKE3 {
+		return nil, fmt.Errorf("%w: unsupported hash scheme %s", ErrInvalidSignature, msg.HashScheme)
+	}
+	hasher := blake3.New()
+	hasher.Write(dataBytes)
+	if hash := hasher.Sum(nil)[:hashLength]; !bytes.Equal(hash, msg.Hash) {
+		return nil, fmt.Errorf("%w: hash mismatch", ErrInvalidSignature)
+	}
+	if msg.SignatureScheme != protobufs.SignatureScheme_SIGNATURE_SCHEME_ED25519 {
+		return nil, fmt.Errorf("%w: unsupported signature scheme %s", ErrInvalidSignature, msg.SignatureScheme)
+	}
+	if len(msg.Signer) != ed25519.PublicKeySize || !ed25519.Verify(msg.Signer, msg.Hash, msg.Signature) {
+		return nil, fmt.Errorf("%w: signature mismatch", ErrInvalidSignature)
+	}
+	// check the type of the message and get the action
+	body := data.GetFrameActionBody()
+	if data.Type != protobufs.MessageType_MESSAGE_TYPE_FRAME_ACTION || body == nil {
+		return nil, fmt.Errorf("%w: %s", ErrUnexpectedType, data.Type)
+	}
+	action := &FrameAction{
+		FID:         data.Fid,
+		URL:         string(body.Url),
+		ButtonIndex: body.ButtonIndex,
+		InputText:   string(body.InputText),
+		Timestamp:   time.Unix(farcasterEpoch+int64(data.Timestamp), 0).UTC(),
+		Signer:      ed25519.PublicKey(msg.Signer),
+	}
+	if body.CastId != nil {
+		action.CastID = &CastID{
+			FID:  body.CastId.Fid,
+			Hash: "0x" + hex.EncodeToString(body.CastId.Hash),
+		}
+	}
+	if opts == nil {
+		return action, nil
+	}
+	// check the action against the options
+	if opts.URL != "" && strings.TrimSuffix(action.URL, "/") != strings.TrimSuffix(opts.URL, "/") {
+		return nil, fmt.Errorf("%w: %s", ErrURLMismatch, action.URL)
+	}
+	if opts.Buttons > 0 && (action.ButtonIndex < 1 || int(action.ButtonIndex) > opts.Buttons) {
+		return nil, fmt.Errorf("%w: %d", ErrInvalidButton, action.ButtonIndex)
+	}
+	if opts.Signers != nil {
+		ok, err := opts.Signers.IsSigner(ctx, action.FID, action.Signer)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrCheckingSigner, err)
+		}
+		if !ok {
+			return nil, fmt.Errorf("%w: %x for fid %d", ErrUnknownSigner, []byte(action.Signer), action.FID)
+		}
+	}
+	return action, nil
+}
@@ -0,0 +1,190 @@
+package frames
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/vocdoni/votebot/api/hub/protobufs"
+	"github.com/zeebo/blake3"
+	"google.golang.org/protobuf/proto"
+)
+
+const testURL = "https://votebot.test/frames/1"
+
+var (
+	testKey  = ed25519.NewKeyFromSeed(make([]byte, ed25519.SeedSize))
+	otherKey = ed25519.NewKeyFromSeed([]byte("01234567890123456789012345678901"))
+)
+
+// testMessage returns a frame action message data of the given fid with the
+// given url and button.
+func testMessage(fid uint64, url string, button uint32) *protobufs.MessageData {
+	return &protobufs.MessageData{
+		Type:      protobufs.MessageType_MESSAGE_TYPE_FRAME_ACTION,
+		Fid:       fid,
+		Timestamp: 100,
+		Network:   protobufs.FarcasterNetwork_FARCASTER_NETWORK_MAINNET,
+		Body: &protobufs.MessageData_FrameActionBody{FrameActionBody: &protobufs.FrameActionBody{
+			Url:         []byte(url),
+			ButtonIndex: button,
+			CastId:      &protobufs.CastId{Fid: 2, Hash: []byte{0xca, 0x57}},
+		}},
+	}
+}
+
+// sign returns the hex encoded message with the given data, hashed and
+// signed with the given key, but with the given signer public key. The
+// modify function, if any, is applied to the message before encoding it.
+func sign(c *qt.C, data *protobufs.MessageData, key ed25519.PrivateKey, signer ed25519.PublicKey, modify func(*protobufs.Message)) string {
+	dataBytes, err := proto.Marshal(data)
+	c.Assert(err, qt.IsNil)
+	hasher := blake3.New()
+	hasher.Write(dataBytes)
+	hash := hasher.Sum(nil)[:hashLength]
+	msg := &protobufs.Message{
+		Data:            data,
+		Hash:            hash,
+		HashScheme:      protobufs.HashScheme_HASH_SCHEME_BLAKE3,
+		Signature:       ed25519.Sign(key, hash),
+		SignatureScheme: protobufs.SignatureScheme_SIGNATURE_SCHEME_ED25519,
+		Signer:          signer,
+		DataBytes:       dataBytes,
+	}
+	if modify != nil {
+		modify(msg)
+	}
+	bMsg, err := proto.Marshal(msg)
+	c.Assert(err, qt.IsNil)
+	return hex.EncodeToString(bMsg)
+}
+
+// testSigners is a SignerChecker with a fixed list of signers per fid.
+type testSigners map[uint64]ed25519.PublicKey
+
+func (t testSigners) IsSigner(_ context.Context, fid uint64, signer ed25519.PublicKey) (bool, error) {
+	key, ok := t[fid]
+	if !ok {
+		return false, fmt.Errorf("fid %d not found", fid)
+	}
+	return key.Equal(signer), nil
+}
+
+func TestVerify(t *testing.T) {
+	c := qt.New(t)
+
+	ctx := context.Background()
+	testPublic := testKey.Public().(ed25519.PublicKey)
+	otherPublic := otherKey.Public().(ed25519.PublicKey)
+	opts := &Options{
+		URL:     testURL + "/",
+		Buttons: 2,
+		Signers: testSigners{1: testPublic, 2: otherPublic},
+	}
+
+	// a valid action
+	action, err := Verify(ctx, "0x"+sign(c, testMessage(1, testURL, 2), testKey, testPublic, nil), opts)
+	c.Assert(err, qt.IsNil)
+	c.Assert(action, qt.DeepEquals, &FrameAction{
+		FID:         1,
+		URL:         testURL,
+		ButtonIndex: 2,
+		CastID:      &CastID{FID: 2, Hash: "0xca57"},
+		Timestamp:   time.Unix(farcasterEpoch+100, 0).UTC(),
+		Signer:      testPublic,
+	})
+
+	// the data is marshalled if the message does not include its bytes
+	_, err = Verify(ctx, sign(c, testMessage(1, testURL, 1), testKey, testPublic, func(m *protobufs.Message) {
+		m.DataBytes = nil
+	}), opts)
+	c.Assert(err, qt.IsNil)
+
+	// invalid actions
+	wrongType := testMessage(1, testURL, 1)
+	wrongType.Type = protobufs.MessageType_MESSAGE_TYPE_CAST_ADD
+	tests := []struct {
+		name     string
+		message  string
+		expected error
+	}{
+		{"not hex", "xyz", ErrInvalidMessage},
+		{"not a message", "0102", ErrInvalidMessage},
+		{"wrong url", sign(c, testMessage(1, "https://other.test", 1), testKey, testPublic, nil), ErrURLMismatch},
+		{"button out of range", sign(c, testMessage(1, testURL, 3), testKey, testPublic, nil), ErrInvalidButton},
+		{"no button", sign(c, testMessage(1, testURL, 0), testKey, testPublic, nil), ErrInvalidButton},
+		{"wrong type", sign(c, wrongType, testKey, testPublic, nil), ErrUnexpectedType},
+		{"wrong signer", sign(c, testMessage(1, testURL, 1), testKey, otherPublic, nil), ErrInvalidSignature},
+		{"tampered hash", sign(c, testMessage(1, testURL, 1), testKey, testPublic, func(m *protobufs.Message) {
+			m.Hash[0] ^= 0xff
+		}), ErrInvalidSignature},
+		{"tampered data", sign(c, testMessage(1, testURL, 1), testKey, testPublic, func(m *protobufs.Message) {
+			m.DataBytes[len(m.DataBytes)-1] ^= 0xff
+		}), ErrInvalidSignature},
+		{"wrong hash scheme", sign(c, testMessage(1, testURL, 1), testKey, testPublic, func(m *protobufs.Message) {
+			m.HashScheme = protobufs.HashScheme_HASH_SCHEME_NONE
+		}), ErrInvalidSignature},
+		{"unknown signer", sign(c, testMessage(2, testURL, 1), testKey, testPublic, nil), ErrUnknownSigner},
+		{"signer check error", sign(c, testMessage(3, testURL, 1), testKey, testPublic, nil), ErrCheckingSigner},
+	}
+	for _, test := range tests {
+		_, err := Verify(ctx, test.message, opts)
+		c.Assert(err, qt.ErrorIs, test.expected, qt.Commentf(test.name))
+	}
+
+	// without options only the message is verified
+	_, err = Verify(ctx, sign(c, testMessage(2, "https://other.test", 9), testKey, testPublic, nil), nil)
+	c.Assert(err, qt.IsNil)
+}
+
+func TestParseRequest(t *testing.T) {
+	c := qt.New(t)
+
+	testPublic := testKey.Public().(ed25519.PublicKey)
+	body := fmt.Sprintf(`{"untrustedData":{"fid":2,"buttonIndex":3},"trustedData":{"messageBytes":"%s"}}`,
+		sign(c, testMessage(1, testURL, 1), testKey, testPublic, nil))
+	action, err := ParseRequest(context.Background(), []byte(body), &Options{URL: testURL})
+	c.Assert(err, qt.IsNil)
+	c.Assert(action.FID, qt.Equals, uint64(1))
+	c.Assert(action.ButtonIndex, qt.Equals, uint32(1))
+
+	_, err = ParseRequest(context.Background(), []byte("{"), nil)
+	c.Assert(err, qt.ErrorIs, ErrInvalidMessage)
+}
+
+func TestHubSigners(t *testing.T) {
+	c := qt.New(t)
+
+	testPublic := testKey.Public().(ed25519.PublicKey)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.URL.Path, qt.Equals, "/v1/onChainSignersByFid")
+		c.Check(r.Header.Get("x-api-key"), qt.Equals, "secret")
+		switch r.URL.Query().Get("fid") {
+		case "1":
+			if r.URL.Query().Get("signer") == "0x"+hex.EncodeToString(testPublic) {
+				fmt.Fprint(w, `{"signerEventBody":{}}`)
+				return
+			}
+			http.Error(w, `{"errCode":"not_found"}`, http.StatusBadRequest)
+		default:
+			http.Error(w, "error", http.StatusInternalServerError)
+		}
+	}))
+	defer srv.Close()
+
+	signers := &HubSigners{Endpoint: srv.URL + "/v1/", Headers: map[string]string{"x-api-key": "secret"}}
+	ok, err := signers.IsSigner(context.Background(), 1, testPublic)
+	c.Assert(err, qt.IsNil)
+	c.Assert(ok, qt.IsTrue)
+	ok, err = signers.IsSigner(context.Background(), 1, otherKey.Public().(ed25519.PublicKey))
+	c.Assert(err, qt.IsNil)
+	c.Assert(ok, qt.IsFalse)
+	_, err = signers.IsSigner(context.Background(), 2, testPublic)
+	c.Assert(err, qt.IsNotNil)
+}
@@ -0,0 +1,58 @@
+package frames
+
+import (
+	"context"
+	"crypto/ed25519"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	// hubSignerEndpoint is the hub http API endpoint that returns the active
+	// signer of a fid with the given public key, or not found if it is not
+	hubSignerEndpoint = "onChainSignersByFid?fid=%d&signer=0x%x"
+	// hubSignerTimeout is the timeout of the requests to the hub
+	hubSignerTimeout = 10 * time.Second
+)
+
+// HubSigners is a SignerChecker that checks the signers against the onchain
+// signers list of a farcaster hub, using its http API at the given endpoint
+// and the given auth headers, if any.
+type HubSigners struct {
+	Endpoint string
+	Headers  map[string]string
+}
+
+// IsSigner returns if the given public key is an active signer of the given
+// fid according to the hub.
+func (h *HubSigners) IsSigner(ctx context.Context, fid uint64, signer ed25519.PublicKey) (bool, error) {
+	internalCtx, cancel := context.WithTimeout(ctx, hubSignerTimeout)
+	defer cancel()
+	uri := fmt.Sprintf("%s/%s", strings.TrimSuffix(h.Endpoint, "/"), fmt.Sprintf(hubSignerEndpoint, fid, []byte(signer)))
+	req, err := http.NewRequestWithContext(internalCtx, http.MethodGet, uri, nil)
+	if err != nil {
+		return false, fmt.Errorf("error creating request: %w", err)
+	}
+	for k, v := range h.Headers {
+		if k != "" && v != "" {
+			req.Header.Set(k, v)
+		}
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return false, fmt.Errorf("error getting signer: %w", err)
+	}
+	defer res.Body.Close()
+	switch res.StatusCode {
+	case http.StatusOK:
+		return true, nil
+	case http.StatusNotFound, http.StatusBadRequest:
+		// the hubs reply with a bad request error when the signer is not
+		// found
+		return false, nil
+	default:
+		return false, fmt.Errorf("error getting signer: %s", res.Status)
+	}
+}
@@ -0,0 +1,13 @@
+package frameserver
+
+import "fmt"
+
+var (
+	ErrUnsupportedVoteType = fmt.Errorf("vote type not supported by the frame server")
+	ErrInvalidNumOptions   = fmt.Errorf("invalid number of options for the frame buttons")
+	ErrInvalidAction       = fmt.Errorf("invalid frame action")
+	ErrAlreadyVoted        = fmt.Errorf("already voted")
+	ErrNotInCensus         = fmt.Errorf("voter not in the census")
+	ErrReadingState        = fmt.Errorf("error reading frame server state")
+	ErrWritingState        = fmt.Errorf("error writing frame server state")
+)
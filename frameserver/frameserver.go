@@ -0,0 +1,349 @@
+// Package frameserver implements a self-hosted election backend that serves
+// the polls as farcaster frames, with a button per option, and records the
+// votes received through the signed frame actions, one per fid.
+package frameserver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vocdoni/votebot/election"
+	"github.com/vocdoni/votebot/frames"
+)
+
+const (
+	// Path is the path where the server serves the frames, followed by the
+	// election id
+	Path = "/frames/"
+	// MaxOptions is the max number of options of a poll, one per frame
+	// button
+	MaxOptions = 4
+)
+
+// poll is an election stored by the server, with the vote of every fid as
+// the index of the chosen option. The census contains the fids that can vote,
+// anyone can vote if it is empty.
+type poll struct {
+	ID        string            `json:"id"`
+	Question  string            `json:"question"`
+	Options   []string          `json:"options"`
+	Census    []uint64          `json:"census,omitempty"`
+	StartDate time.Time         `json:"startDate"`
+	EndDate   time.Time         `json:"endDate"`
+	Canceled  bool              `json:"canceled,omitempty"`
+	Votes     map[uint64]uint32 `json:"votes"`
+}
+
+// Server is an election.Creator that hosts the elections as frames served
+// by itself, at the given public url. The polls must be single choice, with
+// up to MaxOptions options, and the census can only be the default one, where
+// anyone can vote, or a list of fids. Every fid can vote only once, through
+// the frame buttons, whose actions are verified with the frames package. If
+// the server has a path, its state is persisted to that file. It also
+// implements http.Handler to serve the frames, see ServeHTTP. It is safe for
+// concurrent use.
+type Server struct {
+	// Signers checks the signers of the frame actions, they are not checked
+	// if nil
+	Signers frames.SignerChecker
+	// Now returns the current time, time.Now if nil
+	Now func() time.Time
+
+	publicURL string
+	path      string
+	mtx       sync.Mutex
+	polls     map[string]*poll
+	lastID    uint64
+}
+
+// New creates a frame server with the given public url, where its handler is
+// reachable. If the path of a state file is provided, the polls and votes are
+// loaded from it, if it exists, and persisted to it on every change.
+func New(publicURL, path string) (*Server, error) {
+	if _, err := url.ParseRequestURI(publicURL); err != nil {
+		return nil, fmt.Errorf("invalid public url: %w", err)
+	}
+	s := &Server{
+		publicURL: strings.TrimSuffix(publicURL, "/"),
+		path:      path,
+		polls:     make(map[string]*poll),
+	}
+	if path == "" {
+		return s, nil
+	}
+	body, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, errors.Join(ErrReadingState, err)
+	}
+	polls := []*poll{}
+	if err := json.Unmarshal(body, &polls); err != nil {
+		return nil, errors.Join(ErrReadingState, err)
+	}
+	for _, p := range polls {
+		if p.Votes == nil {
+			p.Votes = make(map[uint64]uint32)
+		}
+		s.polls[p.ID] = p
+		if id, err := strconv.ParseUint(p.ID, 10, 64); err == nil && id > s.lastID {
+			s.lastID = id
+		}
+	}
+	return s, nil
+}
+
+// Create stores a new poll with the given options and returns its election,
+// with the url of its frame. It returns an error if the vote type, the number
+// of options (between 2 and MaxOptions) or the census are not supported.
+func (s *Server) Create(_ context.Context, opts *election.ElectionOptions) (*election.Election, error) {
+	if opts.VoteType != "" && opts.VoteType != election.VoteTypeSingle {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedVoteType, opts.VoteType)
+	}
+	if len(opts.Options) < 2 || len(opts.Options) > MaxOptions {
+		return nil, fmt.Errorf("%w: %d", ErrInvalidNumOptions, len(opts.Options))
+	}
+	var census []uint64
+	if opts.Census != nil {
+		if opts.Census.Type != election.CensusTypeFIDs && opts.Census.Type != election.CensusTypeVoters {
+			return nil, fmt.Errorf("%w: %s", election.ErrUnsupportedCensus, opts.Census.Type)
+		}
+		if len(opts.Census.FIDs) == 0 {
+			return nil, election.ErrEmptyCensus
+		}
+		census = append(census, opts.Census.FIDs...)
+	}
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	startDate := s.now()
+	if opts.StartDate != nil {
+		startDate = *opts.StartDate
+	}
+	s.lastID++
+	p := &poll{
+		ID:        strconv.FormatUint(s.lastID, 10),
+		Question:  opts.Question,
+		Options:   append([]string{}, opts.Options...),
+		Census:    census,
+		StartDate: startDate,
+		EndDate:   opts.End(startDate),
+		Votes:     make(map[uint64]uint32),
+	}
+	s.polls[p.ID] = p
+	if err := s.save(); err != nil {
+		delete(s.polls, p.ID)
+		return nil, err
+	}
+	return &election.Election{ID: p.ID, URL: s.frameURL(p.ID)}, nil
+}
+
+// Status returns the status of the poll with the given id.
+func (s *Server) Status(_ context.Context, electionID string) (*election.Status, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	p, ok := s.polls[electionID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", election.ErrElectionNotFound, electionID)
+	}
+	return &election.Status{
+		Status:    s.status(p),
+		StartDate: p.StartDate,
+		EndDate:   p.EndDate,
+		VoteCount: uint64(len(p.Votes)),
+	}, nil
+}
+
+// Results returns the results of the poll with the given id, as a single
+// field tally with the votes of every option, which are final if the poll
+// has ended or has been canceled.
+func (s *Server) Results(_ context.Context, electionID string) (*election.Results, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	p, ok := s.polls[electionID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", election.ErrElectionNotFound, electionID)
+	}
+	return s.results(p), nil
+}
+
+// Cancel cancels the poll with the given id if it has not ended yet, no more
+// votes are accepted after it.
+func (s *Server) Cancel(_ context.Context, electionID string) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	p, ok := s.polls[electionID]
+	if !ok {
+		return fmt.Errorf("%w: %s", election.ErrElectionNotFound, electionID)
+	}
+	if status := s.status(p); status == election.StatusEnded || status == election.StatusCanceled {
+		return fmt.Errorf("%w: %s", election.ErrElectionNotActive, electionID)
+	}
+	p.Canceled = true
+	return s.save()
+}
+
+// End ends the poll with the given id at the current time if it is ongoing,
+// no more votes are accepted after it.
+func (s *Server) End(_ context.Context, electionID string) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	p, ok := s.polls[electionID]
+	if !ok {
+		return fmt.Errorf("%w: %s", election.ErrElectionNotFound, electionID)
+	}
+	if s.status(p) != election.StatusOngoing {
+		return fmt.Errorf("%w: %s", election.ErrElectionNotActive, electionID)
+	}
+	endDate := p.EndDate
+	p.EndDate = s.now()
+	if err := s.save(); err != nil {
+		p.EndDate = endDate
+		return err
+	}
+	return nil
+}
+
+// vote records the vote of the given fid for the option of the given button
+// index (1-based) in the poll with the given id. It returns an error if the
+// poll is not ongoing, the button is not valid, the fid is not in the census
+// or it has already voted.
+func (s *Server) vote(electionID string, fid uint64, button uint32) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	p, ok := s.polls[electionID]
+	if !ok {
+		return fmt.Errorf("%w: %s", election.ErrElectionNotFound, electionID)
+	}
+	if s.status(p) != election.StatusOngoing {
+		return fmt.Errorf("%w: %s", election.ErrElectionNotActive, electionID)
+	}
+	if button < 1 || int(button) > len(p.Options) {
+		return fmt.Errorf("%w: invalid button %d", ErrInvalidAction, button)
+	}
+	if len(p.Census) > 0 && !containsFID(p.Census, fid) {
+		return fmt.Errorf("%w: %d", ErrNotInCensus, fid)
+	}
+	if _, ok := p.Votes[fid]; ok {
+		return fmt.Errorf("%w: %d", ErrAlreadyVoted, fid)
+	}
+	p.Votes[fid] = button - 1
+	if err := s.save(); err != nil {
+		delete(p.Votes, fid)
+		return err
+	}
+	return nil
+}
+
+// poll returns a copy of the poll with the given id and its current status
+// and results.
+func (s *Server) poll(electionID string) (*poll, string, *election.Results, bool) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	p, ok := s.polls[electionID]
+	if !ok {
+		return nil, "", nil, false
+	}
+	found := *p
+	return &found, s.status(p), s.results(p), true
+}
+
+// results returns the results of the given poll. It must be called with the
+// lock held.
+func (s *Server) results(p *poll) *election.Results {
+	tally := make([]uint64, len(p.Options))
+	for _, option := range p.Votes {
+		if int(option) < len(tally) {
+			tally[option]++
+		}
+	}
+	status := s.status(p)
+	return &election.Results{
+		Tally:      [][]uint64{tally},
+		VoteCount:  uint64(len(p.Votes)),
+		CensusSize: uint64(len(p.Census)),
+		Final:      status == election.StatusEnded || status == election.StatusCanceled,
+	}
+}
+
+// status returns the status of the given poll at the current time.
+func (s *Server) status(p *poll) string {
+	now := s.now()
+	switch {
+	case p.Canceled:
+		return election.StatusCanceled
+	case now.Before(p.StartDate):
+		return election.StatusUpcoming
+	case !now.Before(p.EndDate):
+		return election.StatusEnded
+	default:
+		return election.StatusOngoing
+	}
+}
+
+// save writes the polls to the state file of the server, if it has one. The
+// file is written atomically, through a temporary file that replaces it. It
+// must be called with the lock held.
+func (s *Server) save() error {
+	if s.path == "" {
+		return nil
+	}
+	polls := make([]*poll, 0, len(s.polls))
+	for _, p := range s.polls {
+		polls = append(polls, p)
+	}
+	sort.Slice(polls, func(i, j int) bool { return polls[i].StartDate.Before(polls[j].StartDate) })
+	body, err := json.MarshalIndent(polls, "", "  ")
+	if err != nil {
+		return errors.Join(ErrWritingState, err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*.tmp")
+	if err != nil {
+		return errors.Join(ErrWritingState, err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(body); err != nil {
+		tmp.Close()
+		return errors.Join(ErrWritingState, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return errors.Join(ErrWritingState, err)
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return errors.Join(ErrWritingState, err)
+	}
+	return nil
+}
+
+// frameURL returns the public url of the frame of the poll with the given
+// id.
+func (s *Server) frameURL(electionID string) string {
+	return s.publicURL + Path + url.PathEscape(electionID)
+}
+
+func (s *Server) now() time.Time {
+	if s.Now != nil {
+		return s.Now()
+	}
+	return time.Now()
+}
+
+// containsFID returns if the given list of fids contains the given one.
+func containsFID(fids []uint64, fid uint64) bool {
+	for _, f := range fids {
+		if f == fid {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,219 @@
+package frameserver
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/vocdoni/votebot/api/hub/protobufs"
+	"github.com/vocdoni/votebot/election"
+	"github.com/vocdoni/votebot/frames"
+	"github.com/zeebo/blake3"
+	"google.golang.org/protobuf/proto"
+)
+
+const testPublicURL = "https://votebot.test"
+
+// testKey is the ed25519 key used to sign the frame actions of the tests
+var testKey = ed25519.NewKeyFromSeed(make([]byte, ed25519.SeedSize))
+
+// signedAction returns the hex encoded farcaster message of a frame action
+// of the given fid, frame url and button, signed with testKey.
+func signedAction(c *qt.C, msgType protobufs.MessageType, fid uint64, frameURL string, button uint32) string {
+	data := &protobufs.MessageData{
+		Type:    msgType,
+		Fid:     fid,
+		Network: protobufs.FarcasterNetwork_FARCASTER_NETWORK_MAINNET,
+		Body: &protobufs.MessageData_FrameActionBody{FrameActionBody: &protobufs.FrameActionBody{
+			Url:         []byte(frameURL),
+			ButtonIndex: button,
+		}},
+	}
+	dataBytes, err := proto.Marshal(data)
+	c.Assert(err, qt.IsNil)
+	hasher := blake3.New()
+	hasher.Write(dataBytes)
+	// the message hash is the first 20 bytes of the blake3 digest
+	hash := hasher.Sum(nil)[:20]
+	msg, err := proto.Marshal(&protobufs.Message{
+		Data:            data,
+		Hash:            hash,
+		HashScheme:      protobufs.HashScheme_HASH_SCHEME_BLAKE3,
+		Signature:       ed25519.Sign(testKey, hash),
+		SignatureScheme: protobufs.SignatureScheme_SIGNATURE_SCHEME_ED25519,
+		Signer:          testKey.Public().(ed25519.PublicKey),
+		DataBytes:       dataBytes,
+	})
+	c.Assert(err, qt.IsNil)
+	return hex.EncodeToString(msg)
+}
+
+// postAction posts the given frame action message to the server and returns
+// the response.
+func postAction(s *Server, electionID, messageBytes string) *httptest.ResponseRecorder {
+	req := &frames.Request{}
+	req.TrustedData.MessageBytes = messageBytes
+	body, _ := json.Marshal(req)
+	res := httptest.NewRecorder()
+	s.ServeHTTP(res, httptest.NewRequest(http.MethodPost, Path+electionID, strings.NewReader(string(body))))
+	return res
+}
+
+func TestServer(t *testing.T) {
+	c := qt.New(t)
+
+	ctx := context.Background()
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	path := filepath.Join(t.TempDir(), "frames.json")
+	s, err := New(testPublicURL, path)
+	c.Assert(err, qt.IsNil)
+	s.Now = func() time.Time { return now }
+
+	// only single choice polls with up to 4 options are supported
+	_, err = s.Create(ctx, &election.ElectionOptions{Question: "Q?", Options: []string{"A", "B"}, VoteType: election.VoteTypeApproval})
+	c.Assert(err, qt.ErrorIs, ErrUnsupportedVoteType)
+	_, err = s.Create(ctx, &election.ElectionOptions{Question: "Q?", Options: []string{"A", "B", "C", "D", "E"}})
+	c.Assert(err, qt.ErrorIs, ErrInvalidNumOptions)
+	_, err = s.Create(ctx, &election.ElectionOptions{
+		Question: "Q?",
+		Options:  []string{"A", "B"},
+		Census:   &election.Census{Type: election.CensusTypeFollowers},
+	})
+	c.Assert(err, qt.ErrorIs, election.ErrUnsupportedCensus)
+
+	e, err := s.Create(ctx, &election.ElectionOptions{
+		Question: "Ship it?",
+		Options:  []string{"Yes", "No"},
+		Duration: 24,
+	})
+	c.Assert(err, qt.IsNil)
+	c.Assert(e.URL, qt.Equals, testPublicURL+Path+e.ID)
+
+	// the frame has a button per option
+	res := httptest.NewRecorder()
+	s.ServeHTTP(res, httptest.NewRequest(http.MethodGet, Path+e.ID, nil))
+	c.Assert(res.Code, qt.Equals, http.StatusOK)
+	page := res.Body.String()
+	c.Assert(page, qt.Contains, `<meta property="fc:frame" content="vNext">`)
+	c.Assert(page, qt.Contains, `<meta property="fc:frame:image" content="`+e.URL+`/ballot.png">`)
+	c.Assert(page, qt.Contains, `<meta property="fc:frame:post_url" content="`+e.URL+`">`)
+	c.Assert(page, qt.Contains, `<meta property="fc:frame:button:1" content="Yes">`)
+	c.Assert(page, qt.Contains, `<meta property="fc:frame:button:2" content="No">`)
+
+	// the votes are recorded once per fid
+	res = postAction(s, e.ID, signedAction(c, protobufs.MessageType_MESSAGE_TYPE_FRAME_ACTION, 1, e.URL, 1))
+	c.Assert(res.Code, qt.Equals, http.StatusOK)
+	c.Assert(res.Body.String(), qt.Contains, "Vote recorded")
+	c.Assert(res.Body.String(), qt.Contains, e.URL+"/results.png?t=")
+	res = postAction(s, e.ID, signedAction(c, protobufs.MessageType_MESSAGE_TYPE_FRAME_ACTION, 1, e.URL, 2))
+	c.Assert(res.Body.String(), qt.Contains, "You already voted")
+	res = postAction(s, e.ID, signedAction(c, protobufs.MessageType_MESSAGE_TYPE_FRAME_ACTION, 2, e.URL, 2))
+	c.Assert(res.Body.String(), qt.Contains, "Vote recorded")
+	res = postAction(s, e.ID, signedAction(c, protobufs.MessageType_MESSAGE_TYPE_FRAME_ACTION, 3, e.URL, 1))
+	c.Assert(res.Body.String(), qt.Contains, "Vote recorded")
+
+	// invalid actions are rejected: wrong button, frame url, message type or
+	// signature
+	res = postAction(s, e.ID, signedAction(c, protobufs.MessageType_MESSAGE_TYPE_FRAME_ACTION, 4, e.URL, 3))
+	c.Assert(res.Code, qt.Equals, http.StatusBadRequest)
+	res = postAction(s, e.ID, signedAction(c, protobufs.MessageType_MESSAGE_TYPE_FRAME_ACTION, 4, testPublicURL+Path+"42", 1))
+	c.Assert(res.Code, qt.Equals, http.StatusBadRequest)
+	res = postAction(s, e.ID, signedAction(c, protobufs.MessageType_MESSAGE_TYPE_CAST_ADD, 4, e.URL, 1))
+	c.Assert(res.Code, qt.Equals, http.StatusBadRequest)
+	tampered := signedAction(c, protobufs.MessageType_MESSAGE_TYPE_FRAME_ACTION, 4, e.URL, 1)
+	tampered = tampered[:len(tampered)-2] + "00"
+	res = postAction(s, e.ID, tampered)
+	c.Assert(res.Code, qt.Equals, http.StatusBadRequest)
+
+	results, err := s.Results(ctx, e.ID)
+	c.Assert(err, qt.IsNil)
+	c.Assert(results.Tally, qt.DeepEquals, [][]uint64{{2, 1}})
+	c.Assert(results.VoteCount, qt.Equals, uint64(3))
+	c.Assert(results.Final, qt.IsFalse)
+
+	// the images are served
+	for _, image := range []string{ballotImage, resultsImage} {
+		res = httptest.NewRecorder()
+		s.ServeHTTP(res, httptest.NewRequest(http.MethodGet, Path+e.ID+"/"+image, nil))
+		c.Assert(res.Code, qt.Equals, http.StatusOK, qt.Commentf(image))
+		c.Assert(res.Header().Get("Content-Type"), qt.Equals, "image/png", qt.Commentf(image))
+	}
+
+	// the state is persisted and no votes are accepted after the end
+	s, err = New(testPublicURL, path)
+	c.Assert(err, qt.IsNil)
+	s.Now = func() time.Time { return now.Add(25 * time.Hour) }
+	status, err := s.Status(ctx, e.ID)
+	c.Assert(err, qt.IsNil)
+	c.Assert(status.Status, qt.Equals, election.StatusEnded)
+	c.Assert(status.VoteCount, qt.Equals, uint64(3))
+	res = postAction(s, e.ID, signedAction(c, protobufs.MessageType_MESSAGE_TYPE_FRAME_ACTION, 4, e.URL, 1))
+	c.Assert(res.Body.String(), qt.Contains, "Voting is not open")
+	next, err := s.Create(ctx, &election.ElectionOptions{Question: "Q?", Options: []string{"A", "B"}})
+	c.Assert(err, qt.IsNil)
+	c.Assert(next.ID, qt.Not(qt.Equals), e.ID)
+}
+
+func TestServerEndDate(t *testing.T) {
+	c := qt.New(t)
+
+	ctx := context.Background()
+	now := time.Date(2026, 10, 2, 13, 27, 0, 0, time.UTC)
+	s, err := New(testPublicURL, "")
+	c.Assert(err, qt.IsNil)
+	s.Now = func() time.Time { return now }
+
+	// the exact end date is used instead of the duration in whole hours
+	endDate := time.Date(2026, 10, 2, 18, 0, 0, 0, time.UTC)
+	e, err := s.Create(ctx, &election.ElectionOptions{
+		Question: "Ship it?",
+		Options:  []string{"Yes", "No"},
+		Duration: election.DurationHours(endDate.Sub(now)),
+		EndDate:  &endDate,
+	})
+	c.Assert(err, qt.IsNil)
+	status, err := s.Status(ctx, e.ID)
+	c.Assert(err, qt.IsNil)
+	c.Assert(status.EndDate, qt.Equals, endDate)
+}
+
+func TestServerCensus(t *testing.T) {
+	c := qt.New(t)
+
+	ctx := context.Background()
+	s, err := New(testPublicURL, "")
+	c.Assert(err, qt.IsNil)
+	e, err := s.Create(ctx, &election.ElectionOptions{
+		Question: "Ship it?",
+		Options:  []string{"Yes", "No"},
+		Duration: 24,
+		Census:   &election.Census{Type: election.CensusTypeFIDs, FIDs: []uint64{1, 2}},
+	})
+	c.Assert(err, qt.IsNil)
+
+	res := postAction(s, e.ID, signedAction(c, protobufs.MessageType_MESSAGE_TYPE_FRAME_ACTION, 1, e.URL, 1))
+	c.Assert(res.Body.String(), qt.Contains, "Vote recorded")
+	res = postAction(s, e.ID, signedAction(c, protobufs.MessageType_MESSAGE_TYPE_FRAME_ACTION, 3, e.URL, 1))
+	c.Assert(res.Body.String(), qt.Contains, "You are not in the census")
+	results, err := s.Results(ctx, e.ID)
+	c.Assert(err, qt.IsNil)
+	c.Assert(results.VoteCount, qt.Equals, uint64(1))
+	c.Assert(results.CensusSize, qt.Equals, uint64(2))
+
+	// once ended, the poll does not accept more votes
+	c.Assert(s.End(ctx, e.ID), qt.IsNil)
+	c.Assert(s.End(ctx, e.ID), qt.ErrorIs, election.ErrElectionNotActive)
+	res = postAction(s, e.ID, signedAction(c, protobufs.MessageType_MESSAGE_TYPE_FRAME_ACTION, 2, e.URL, 1))
+	c.Assert(res.Body.String(), qt.Contains, "Voting is not open")
+	results, err = s.Results(ctx, e.ID)
+	c.Assert(err, qt.IsNil)
+	c.Assert(results.Final, qt.IsTrue)
+}
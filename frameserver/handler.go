@@ -0,0 +1,190 @@
+package frameserver
+
+import (
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/vocdoni/votebot/election"
+	"github.com/vocdoni/votebot/frames"
+	"github.com/vocdoni/votebot/results"
+	"go.vocdoni.io/dvote/log"
+)
+
+const (
+	// ballotImage and resultsImage are the names of the images of a frame,
+	// served after the frame path
+	ballotImage  = "ballot.png"
+	resultsImage = "results.png"
+	// maxRequestSize is the max size in bytes of the frame action requests
+	maxRequestSize = 16 * 1024
+)
+
+// frameTemplate is the HTML page of a frame, with the farcaster frame meta
+// tags: the image, the url where the actions are posted and the buttons.
+var frameTemplate = template.Must(template.New("frame").Funcs(template.FuncMap{
+	"inc": func(i int) int { return i + 1 },
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Question}}</title>
+<meta property="og:title" content="{{.Question}}">
+<meta property="og:image" content="{{.Image}}">
+<meta property="fc:frame" content="vNext">
+<meta property="fc:frame:image" content="{{.Image}}">
+<meta property="fc:frame:post_url" content="{{.PostURL}}">
+{{- range $i, $button := .Buttons}}
+<meta property="fc:frame:button:{{inc $i}}" content="{{$button}}">
+{{- end}}
+</head>
+<body>
+<h1>{{.Question}}</h1>
+</body>
+</html>
+`))
+
+// frame contains the data to render a frame page.
+type frame struct {
+	Question string
+	Image    string
+	PostURL  string
+	Buttons  []string
+}
+
+// ServeHTTP serves the frames of the polls at Path followed by their id:
+//   - GET: the frame page with the ballot image and a button per option, or
+//     the results image if the poll has ended.
+//   - POST: handles the frame actions, recording the vote of the user if the
+//     signed message is valid, and returns the frame with the results image
+//     and a button with the outcome of the vote.
+//
+// The images are served at the frame url followed by '/ballot.png' and
+// '/results.png'.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	electionID, image, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, Path), "/")
+	p, status, pollResults, ok := s.poll(electionID)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	switch {
+	case image != "" && (r.Method == http.MethodGet || r.Method == http.MethodHead):
+		s.serveImage(w, p, image, pollResults)
+	case image != "":
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	case r.Method == http.MethodGet || r.Method == http.MethodHead:
+		if status == election.StatusEnded || status == election.StatusCanceled {
+			s.serveFrame(w, p, resultsImage, "Voting closed")
+			return
+		}
+		s.serveFrame(w, p, ballotImage, p.Options...)
+	case r.Method == http.MethodPost:
+		s.handleAction(w, r, p)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleAction verifies the frame action of the request, including its
+// signer if the server has a signer checker, and records the vote in the
+// given poll. It returns the results frame with the outcome of the
+// vote, or an error status if the action is not valid.
+func (s *Server) handleAction(w http.ResponseWriter, r *http.Request, p *poll) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxRequestSize))
+	if err != nil {
+		http.Error(w, "error reading request", http.StatusBadRequest)
+		return
+	}
+	// the action must come from the frame of the poll and press one of its
+	// buttons
+	act, err := frames.ParseRequest(r.Context(), body, &frames.Options{
+		URL:     s.frameURL(p.ID),
+		Buttons: len(p.Options),
+		Signers: s.Signers,
+	})
+	if err != nil {
+		if errors.Is(err, frames.ErrCheckingSigner) {
+			log.Errorf("error verifying frame action: %s", err)
+			http.Error(w, "error verifying frame action", http.StatusBadGateway)
+			return
+		}
+		log.Warnw("invalid frame action", "election", p.ID, "error", err)
+		http.Error(w, "invalid frame action", http.StatusBadRequest)
+		return
+	}
+	outcome := "✅ Vote recorded"
+	switch err := s.vote(p.ID, act.FID, act.ButtonIndex); {
+	case err == nil:
+		log.Infow("frame vote recorded", "election", p.ID, "fid", act.FID)
+	case errors.Is(err, ErrAlreadyVoted):
+		outcome = "You already voted"
+	case errors.Is(err, ErrNotInCensus):
+		outcome = "You are not in the census"
+	case errors.Is(err, election.ErrElectionNotActive):
+		outcome = "Voting is not open"
+	case errors.Is(err, ErrInvalidAction):
+		http.Error(w, "invalid frame action", http.StatusBadRequest)
+		return
+	default:
+		log.Errorf("error recording frame vote: %s", err)
+		http.Error(w, "error recording vote", http.StatusInternalServerError)
+		return
+	}
+	s.serveFrame(w, p, resultsImage, outcome)
+}
+
+// serveFrame writes the frame page of the given poll with the given image
+// and buttons. The url of the results image includes the current time to
+// avoid the cached versions.
+func (s *Server) serveFrame(w http.ResponseWriter, p *poll, image string, buttons ...string) {
+	imageURL := fmt.Sprintf("%s/%s", s.frameURL(p.ID), image)
+	if image == resultsImage {
+		imageURL += fmt.Sprintf("?t=%d", s.now().Unix())
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := frameTemplate.Execute(w, &frame{
+		Question: p.Question,
+		Image:    imageURL,
+		PostURL:  s.frameURL(p.ID),
+		Buttons:  buttons,
+	}); err != nil {
+		log.Warnw("error writing frame", "election", p.ID, "error", err)
+	}
+}
+
+// serveImage writes the ballot or the results image of the given poll.
+func (s *Server) serveImage(w http.ResponseWriter, p *poll, image string, pollResults *election.Results) {
+	var data []byte
+	var err error
+	switch image {
+	case ballotImage:
+		data, err = results.BallotPNG(p.Question, p.Options, p.EndDate)
+	case resultsImage:
+		var summary *results.Summary
+		if summary, err = results.New(p.Question, p.Options, election.VoteTypeSingle, pollResults); err == nil {
+			data, err = summary.PNG(p.EndDate)
+		}
+	default:
+		http.Error(w, "image not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Errorf("error rendering frame image: %s", err)
+		http.Error(w, "error rendering image", http.StatusInternalServerError)
+		return
+	}
+	maxAge := time.Minute
+	if image == ballotImage {
+		maxAge = time.Hour
+	}
+	w.Header().Set("Content-Type", "image/png")
+	w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(maxAge.Seconds())))
+	if _, err := w.Write(data); err != nil {
+		log.Warnw("error writing frame image", "election", p.ID, "error", err)
+	}
+}
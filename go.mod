@@ -3,14 +3,30 @@ module github.com/vocdoni/votebot
 go 1.21.7
 
 require (
+	github.com/ethereum/go-ethereum v1.13.4
 	github.com/frankban/quicktest v1.14.6
+	github.com/rivo/uniseg v0.2.0
 	github.com/zeebo/blake3 v0.2.3
 	go.vocdoni.io/dvote v1.10.1
+	go.vocdoni.io/proto v1.15.4-0.20231023165811-02adcc48142a
+	golang.org/x/image v0.6.0
+	golang.org/x/text v0.14.0
 	google.golang.org/protobuf v1.32.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
+	github.com/btcsuite/btcd/btcec/v2 v2.3.2 // indirect
+	github.com/btcsuite/btcd/chaincfg/chainhash v1.1.0 // indirect
+	github.com/decred/dcrd/dcrec/secp256k1/v4 v4.2.0 // indirect
+	github.com/glendc/go-external-ip v0.1.0 // indirect
 	github.com/google/go-cmp v0.6.0 // indirect
+	github.com/holiman/uint256 v1.2.3 // indirect
+	github.com/iden3/go-iden3-crypto v0.0.13 // indirect
+	github.com/iden3/go-rapidsnark/prover v0.0.9 // indirect
+	github.com/iden3/go-rapidsnark/types v0.0.2 // indirect
+	github.com/iden3/go-rapidsnark/verifier v0.0.3 // indirect
+	github.com/iden3/go-rapidsnark/witness v0.0.3 // indirect
 	github.com/klauspost/cpuid/v2 v2.2.6 // indirect
 	github.com/kr/pretty v0.3.1 // indirect
 	github.com/kr/text v0.2.0 // indirect
@@ -18,5 +34,8 @@ require (
 	github.com/mattn/go-isatty v0.0.20 // indirect
 	github.com/rogpeppe/go-internal v1.10.0 // indirect
 	github.com/rs/zerolog v1.32.0 // indirect
+	github.com/wasmerio/wasmer-go v1.0.4 // indirect
+	golang.org/x/crypto v0.15.0 // indirect
 	golang.org/x/sys v0.17.0 // indirect
+	gopkg.in/check.v1 v1.0.0-20201130134442-10cb98267c6c // indirect
 )
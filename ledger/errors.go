@@ -0,0 +1,12 @@
+package ledger
+
+import "fmt"
+
+var (
+	ErrEntryNotFound      = fmt.Errorf("entry not found")
+	ErrEntryAlreadyExists = fmt.Errorf("entry already exists")
+	ErrEntryWithoutHash   = fmt.Errorf("entry without cast hash")
+	ErrStaleEntry         = fmt.Errorf("entry updated since it was read")
+	ErrReadingFile        = fmt.Errorf("error reading ledger file")
+	ErrWritingFile        = fmt.Errorf("error writing ledger file")
+)
@@ -0,0 +1,218 @@
+package ledger
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Entry represents a poll created by the bot, it links the cast that requested
+// the poll with its author, the reply of the bot and the resulting election
+// and frame. It also contains the question, the options and the vote type of
+// the poll to present its results. If the poll was requested from a channel,
+// it also includes the channel url and the hash of the announcement cast, if
+// any. If the poll has a scheduled start, the start date is set and the
+// StartNotified flag tracks if the start of the voting has been notified. The
+// ResultsNotified flag tracks if the final results have been published. The
+// revision is increased by the ledger on every update, see Ledger.Update.
+type Entry struct {
+	Author          uint64    `json:"author"`
+	CastHash        string    `json:"castHash"`
+	ReplyHash       string    `json:"replyHash"`
+	AnnounceHash    string    `json:"announceHash,omitempty"`
+	ElectionID      string    `json:"electionId,omitempty"`
+	FrameURL        string    `json:"frameUrl"`
+	ParentURL       string    `json:"parentUrl,omitempty"`
+	Question        string    `json:"question"`
+	Options         []string  `json:"options,omitempty"`
+	VoteType        string    `json:"voteType,omitempty"`
+	CreatedAt       time.Time `json:"createdAt"`
+	StartDate       time.Time `json:"startDate,omitempty"`
+	EndDate         time.Time `json:"endDate"`
+	StartNotified   bool      `json:"startNotified,omitempty"`
+	ResultsNotified bool      `json:"resultsNotified,omitempty"`
+	Revision        uint64    `json:"revision,omitempty"`
+}
+
+// Ledger keeps track of the polls created by the bot, indexed by the hash of
+// the cast that requested them. It stores and returns copies of the entries,
+// so they must be updated through the ledger. If it has a path, every change
+// is persisted to the file. It is safe for concurrent use.
+type Ledger struct {
+	mtx     sync.RWMutex
+	entries map[string]*Entry
+	path    string
+}
+
+// New creates a new empty ledger.
+func New() *Ledger {
+	return &Ledger{
+		entries: make(map[string]*Entry),
+	}
+}
+
+// Open creates a ledger persisted in the JSON file of the given path. If the
+// file exists, the ledger is initialized with the entries stored in it. Every
+// change of the ledger rewrites the file, so the polls survive the restarts of
+// the bot.
+func Open(path string) (*Ledger, error) {
+	l := New()
+	l.path = path
+	body, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return l, nil
+	}
+	if err != nil {
+		return nil, errors.Join(ErrReadingFile, err)
+	}
+	entries := []*Entry{}
+	if err := json.Unmarshal(body, &entries); err != nil {
+		return nil, errors.Join(ErrReadingFile, err)
+	}
+	for _, entry := range entries {
+		if entry.CastHash == "" {
+			return nil, errors.Join(ErrReadingFile, ErrEntryWithoutHash)
+		}
+		l.entries[entry.CastHash] = entry
+	}
+	return l, nil
+}
+
+// Add stores the given entry in the ledger. It returns an error if there is
+// already an entry for the same cast hash or if it can not be persisted.
+func (l *Ledger) Add(entry *Entry) error {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	if _, ok := l.entries[entry.CastHash]; ok {
+		return ErrEntryAlreadyExists
+	}
+	stored := *entry
+	l.entries[entry.CastHash] = &stored
+	return l.save()
+}
+
+// Update replaces the stored entry with the same cast hash as the given one,
+// which must have the revision of the stored entry, and increases the
+// revision of both. It returns ErrStaleEntry if the stored entry has been
+// updated since the given copy was got, so the copy must be got again to
+// apply the change to the current entry. It also returns an error if the
+// entry does not exist or if the change can not be persisted.
+func (l *Ledger) Update(entry *Entry) error {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	current, ok := l.entries[entry.CastHash]
+	if !ok {
+		return ErrEntryNotFound
+	}
+	if current.Revision != entry.Revision {
+		return ErrStaleEntry
+	}
+	stored := *entry
+	stored.Revision++
+	l.entries[entry.CastHash] = &stored
+	entry.Revision = stored.Revision
+	return l.save()
+}
+
+// Get returns the entry referenced by the given string, which can be the hash
+// of the cast that requested the poll, the hash of the bot reply, the frame
+// url or the election id. It returns an error if no entry matches.
+func (l *Ledger) Get(ref string) (*Entry, error) {
+	l.mtx.RLock()
+	defer l.mtx.RUnlock()
+	if entry, ok := l.entries[ref]; ok {
+		found := *entry
+		return &found, nil
+	}
+	for _, entry := range l.entries {
+		if entry.ReplyHash == ref || entry.FrameURL == ref || (entry.ElectionID != "" && entry.ElectionID == ref) {
+			found := *entry
+			return &found, nil
+		}
+	}
+	return nil, ErrEntryNotFound
+}
+
+// Filter returns the entries that satisfy the given function.
+func (l *Ledger) Filter(fn func(*Entry) bool) []*Entry {
+	l.mtx.RLock()
+	defer l.mtx.RUnlock()
+	entries := []*Entry{}
+	for _, entry := range l.entries {
+		if fn(entry) {
+			found := *entry
+			entries = append(entries, &found)
+		}
+	}
+	return entries
+}
+
+// LastByAuthor returns the most recent entry created by the given author. It
+// returns an error if the author has no entries.
+func (l *Ledger) LastByAuthor(fid uint64) (*Entry, error) {
+	l.mtx.RLock()
+	defer l.mtx.RUnlock()
+	var last *Entry
+	for _, entry := range l.entries {
+		if entry.Author != fid {
+			continue
+		}
+		if last == nil || entry.CreatedAt.After(last.CreatedAt) {
+			last = entry
+		}
+	}
+	if last == nil {
+		return nil, ErrEntryNotFound
+	}
+	found := *last
+	return &found, nil
+}
+
+// Delete removes the entry requested by the cast with the given hash. It
+// returns an error if the entry does not exist or if the change can not be
+// persisted.
+func (l *Ledger) Delete(castHash string) error {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	if _, ok := l.entries[castHash]; !ok {
+		return ErrEntryNotFound
+	}
+	delete(l.entries, castHash)
+	return l.save()
+}
+
+// save writes the entries of the ledger to its file, if it has one. The file
+// is written atomically, through a temporary file that replaces it. It must
+// be called with the lock held.
+func (l *Ledger) save() error {
+	if l.path == "" {
+		return nil
+	}
+	entries := make([]*Entry, 0, len(l.entries))
+	for _, entry := range l.entries {
+		entries = append(entries, entry)
+	}
+	body, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return errors.Join(ErrWritingFile, err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(l.path), filepath.Base(l.path)+".*.tmp")
+	if err != nil {
+		return errors.Join(ErrWritingFile, err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(body); err != nil {
+		tmp.Close()
+		return errors.Join(ErrWritingFile, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return errors.Join(ErrWritingFile, err)
+	}
+	if err := os.Rename(tmp.Name(), l.path); err != nil {
+		return errors.Join(ErrWritingFile, err)
+	}
+	return nil
+}
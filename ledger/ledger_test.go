@@ -0,0 +1,86 @@
+package ledger
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestOpen(t *testing.T) {
+	c := qt.New(t)
+
+	path := filepath.Join(t.TempDir(), "polls.json")
+	endDate := time.Date(2026, 10, 2, 12, 0, 0, 0, time.UTC)
+
+	// a new ledger is created if the file does not exist
+	l, err := Open(path)
+	c.Assert(err, qt.IsNil)
+	c.Assert(l.Add(&Entry{
+		Author:   1,
+		CastHash: "0xcast1",
+		Question: "Ship it?",
+		Options:  []string{"Yes", "No"},
+		EndDate:  endDate,
+	}), qt.IsNil)
+	c.Assert(l.Add(&Entry{Author: 2, CastHash: "0xcast2"}), qt.IsNil)
+	c.Assert(l.Delete("0xcast2"), qt.IsNil)
+	entry, err := l.Get("0xcast1")
+	c.Assert(err, qt.IsNil)
+	entry.ResultsNotified = true
+	c.Assert(l.Update(entry), qt.IsNil)
+
+	// the changes are persisted and loaded again
+	l, err = Open(path)
+	c.Assert(err, qt.IsNil)
+	entry, err = l.Get("0xcast1")
+	c.Assert(err, qt.IsNil)
+	c.Assert(entry.Options, qt.DeepEquals, []string{"Yes", "No"})
+	c.Assert(entry.EndDate.Equal(endDate), qt.IsTrue)
+	c.Assert(entry.ResultsNotified, qt.IsTrue)
+	_, err = l.Get("0xcast2")
+	c.Assert(err, qt.ErrorIs, ErrEntryNotFound)
+
+	// invalid files are rejected
+	c.Assert(os.WriteFile(path, []byte("{"), 0o600), qt.IsNil)
+	_, err = Open(path)
+	c.Assert(err, qt.ErrorIs, ErrReadingFile)
+	c.Assert(os.WriteFile(path, []byte(`[{"author":1}]`), 0o600), qt.IsNil)
+	_, err = Open(path)
+	c.Assert(err, qt.ErrorIs, ErrEntryWithoutHash)
+}
+
+func TestUpdate(t *testing.T) {
+	c := qt.New(t)
+
+	l := New()
+	c.Assert(l.Update(&Entry{CastHash: "0xcast1"}), qt.ErrorIs, ErrEntryNotFound)
+	c.Assert(l.Add(&Entry{CastHash: "0xcast1", EndDate: time.Now().Add(time.Hour)}), qt.IsNil)
+
+	// two copies of the same entry are updated, the second one is stale
+	first, err := l.Get("0xcast1")
+	c.Assert(err, qt.IsNil)
+	second, err := l.Get("0xcast1")
+	c.Assert(err, qt.IsNil)
+	closed := time.Now()
+	first.EndDate = closed
+	c.Assert(l.Update(first), qt.IsNil)
+	c.Assert(first.Revision, qt.Equals, uint64(1))
+	second.ResultsNotified = true
+	c.Assert(l.Update(second), qt.ErrorIs, ErrStaleEntry)
+	entry, err := l.Get("0xcast1")
+	c.Assert(err, qt.IsNil)
+	c.Assert(entry.EndDate.Equal(closed), qt.IsTrue)
+	c.Assert(entry.ResultsNotified, qt.IsFalse)
+
+	// the updated copy can be updated again, and a fresh copy too
+	first.ResultsNotified = true
+	c.Assert(l.Update(first), qt.IsNil)
+	entry, err = l.Get("0xcast1")
+	c.Assert(err, qt.IsNil)
+	c.Assert(entry.Revision, qt.Equals, uint64(2))
+	c.Assert(entry.EndDate.Equal(closed), qt.IsTrue)
+	c.Assert(entry.ResultsNotified, qt.IsTrue)
+}
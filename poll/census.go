@@ -0,0 +1,234 @@
+package poll
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// CensusType represents the type of census of a poll, which defines who can
+// vote in it.
+type CensusType string
+
+const (
+	// CensusTypeFollowers includes the followers of the poll author
+	CensusTypeFollowers CensusType = "followers"
+	// CensusTypeChannel includes the members of a channel
+	CensusTypeChannel CensusType = "channel"
+	// CensusTypeERC20 includes the holders of an ERC-20 token
+	CensusTypeERC20 CensusType = "erc20"
+	// CensusTypeNFT includes the holders of an NFT collection
+	CensusTypeNFT CensusType = "nft"
+	// CensusTypeFIDs includes an explicit list of FIDs
+	CensusTypeFIDs CensusType = "fids"
+	// CensusTypeAddresses includes an explicit list of addresses
+	CensusTypeAddresses CensusType = "addresses"
+	// CensusTypeUsernames includes an explicit list of usernames
+	CensusTypeUsernames CensusType = "usernames"
+	// CensusTypeFile includes the voters listed in a CSV or JSON file
+	CensusTypeFile CensusType = "file"
+)
+
+const (
+	// weightedKeyword is the keyword to weight the votes of a token census
+	// by the balance of the voters
+	weightedKeyword = "weighted"
+	// DefaultNetwork is the network of the token censuses when it is not set
+	DefaultNetwork = "ethereum"
+	// channelBaseURL is the base url of the channels referenced by name
+	channelBaseURL = "https://warpcast.com/~/channel/"
+)
+
+var (
+	// censusTypes contains the supported names of every census type
+	censusTypes = map[string]CensusType{
+		"followers": CensusTypeFollowers,
+		"channel":   CensusTypeChannel,
+		"erc20":     CensusTypeERC20,
+		"erc-20":    CensusTypeERC20,
+		"nft":       CensusTypeNFT,
+		"erc721":    CensusTypeNFT,
+		"erc-721":   CensusTypeNFT,
+		"fids":      CensusTypeFIDs,
+		"addresses": CensusTypeAddresses,
+		"usernames": CensusTypeUsernames,
+		"file":      CensusTypeFile,
+	}
+	// networks contains the supported networks of the token censuses
+	networks = map[string]bool{
+		"ethereum": true,
+		"base":     true,
+		"optimism": true,
+		"arbitrum": true,
+		"polygon":  true,
+	}
+	// addressRgx matches an hex encoded ethereum address
+	addressRgx = regexp.MustCompile(`^0x[0-9a-fA-F]{40}$`)
+	// channelNameRgx matches a channel name
+	channelNameRgx = regexp.MustCompile(`^[a-z0-9-]+$`)
+	// usernameRgx matches a farcaster username, including ens names
+	usernameRgx = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,15}(\.eth)?$`)
+)
+
+// Census represents the census of a poll, which defines who can vote in it.
+// The fields used depend on its type:
+//   - followers: no other field.
+//   - channel: the url of the channel, which is empty if the census refers to
+//     the channel where the poll is created.
+//   - erc20 and nft: the contract address, its network and if the votes are
+//     weighted by the balance of the voters.
+//   - fids: the list of FIDs.
+//   - addresses: the list of addresses.
+//   - usernames: the list of usernames, without the @ prefix.
+//   - file: the url of the file, which is empty if the file is embedded in
+//     the cast of the poll.
+type Census struct {
+	Type      CensusType
+	Channel   string
+	Contract  string
+	Network   string
+	Weighted  bool
+	FIDs      []uint64
+	Addresses []string
+	Usernames []string
+	URL       string
+}
+
+// ParseCensus parses a census definition, which starts with its type
+// followed by its arguments separated by spaces or commas:
+//   - followers
+//   - channel [<name or url>]
+//   - erc20 <contract> [<network>] [weighted]
+//   - nft <contract> [<network>] [weighted]
+//   - fids <fid> [<fid>...]
+//   - addresses <address> [<address>...]
+//   - [usernames] @<username> [@<username>...]
+//   - file [<url>], or just the url of the file
+//
+// The supported networks are ethereum (by default), base, optimism, arbitrum
+// and polygon.
+func ParseCensus(value string) (*Census, error) {
+	fields := strings.Fields(strings.ReplaceAll(value, ",", " "))
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("%w: empty census", ErrParsingCensus)
+	}
+	// the explicit lists of usernames and the urls of census files can be
+	// set without the census type
+	if strings.HasPrefix(fields[0], "@") {
+		fields = append([]string{string(CensusTypeUsernames)}, fields...)
+	} else if isURL(fields[0]) {
+		fields = append([]string{string(CensusTypeFile)}, fields...)
+	}
+	censusType, ok := censusTypes[strings.ToLower(fields[0])]
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown census type '%s'", ErrParsingCensus, fields[0])
+	}
+	census := &Census{Type: censusType}
+	args := fields[1:]
+	switch censusType {
+	case CensusTypeFollowers:
+		if len(args) > 0 {
+			return nil, fmt.Errorf("%w: unexpected '%s'", ErrParsingCensus, strings.Join(args, " "))
+		}
+	case CensusTypeChannel:
+		if len(args) > 1 {
+			return nil, fmt.Errorf("%w: unexpected '%s'", ErrParsingCensus, strings.Join(args[1:], " "))
+		}
+		if len(args) == 1 {
+			channel := strings.ToLower(args[0])
+			switch {
+			case strings.HasPrefix(channel, "https://"):
+				census.Channel = args[0]
+			case channelNameRgx.MatchString(strings.TrimPrefix(channel, "/")):
+				census.Channel = channelBaseURL + strings.TrimPrefix(channel, "/")
+			default:
+				return nil, fmt.Errorf("%w: invalid channel '%s'", ErrParsingCensus, args[0])
+			}
+		}
+	case CensusTypeERC20, CensusTypeNFT:
+		if len(args) == 0 {
+			return nil, fmt.Errorf("%w: contract address not set", ErrParsingCensus)
+		}
+		if !addressRgx.MatchString(args[0]) {
+			return nil, fmt.Errorf("%w: invalid contract address '%s'", ErrParsingCensus, args[0])
+		}
+		census.Contract = strings.ToLower(args[0])
+		census.Network = DefaultNetwork
+		networkSet := false
+		for _, arg := range args[1:] {
+			arg = strings.ToLower(arg)
+			switch {
+			case arg == weightedKeyword && !census.Weighted:
+				census.Weighted = true
+			case networks[arg] && !networkSet:
+				census.Network = arg
+				networkSet = true
+			default:
+				return nil, fmt.Errorf("%w: unexpected '%s'", ErrParsingCensus, arg)
+			}
+		}
+	case CensusTypeFIDs:
+		if len(args) == 0 {
+			return nil, fmt.Errorf("%w: no fids set", ErrParsingCensus)
+		}
+		seen := map[uint64]bool{}
+		for _, arg := range args {
+			fid, err := strconv.ParseUint(arg, 10, 64)
+			if err != nil || fid == 0 {
+				return nil, fmt.Errorf("%w: invalid fid '%s'", ErrParsingCensus, arg)
+			}
+			if !seen[fid] {
+				census.FIDs = append(census.FIDs, fid)
+				seen[fid] = true
+			}
+		}
+	case CensusTypeAddresses:
+		if len(args) == 0 {
+			return nil, fmt.Errorf("%w: no addresses set", ErrParsingCensus)
+		}
+		seen := map[string]bool{}
+		for _, arg := range args {
+			if !addressRgx.MatchString(arg) {
+				return nil, fmt.Errorf("%w: invalid address '%s'", ErrParsingCensus, arg)
+			}
+			address := strings.ToLower(arg)
+			if !seen[address] {
+				census.Addresses = append(census.Addresses, address)
+				seen[address] = true
+			}
+		}
+	case CensusTypeUsernames:
+		if len(args) == 0 {
+			return nil, fmt.Errorf("%w: no usernames set", ErrParsingCensus)
+		}
+		seen := map[string]bool{}
+		for _, arg := range args {
+			username := strings.ToLower(strings.TrimPrefix(arg, "@"))
+			if !usernameRgx.MatchString(username) {
+				return nil, fmt.Errorf("%w: invalid username '%s'", ErrParsingCensus, arg)
+			}
+			if !seen[username] {
+				census.Usernames = append(census.Usernames, username)
+				seen[username] = true
+			}
+		}
+	case CensusTypeFile:
+		if len(args) > 1 {
+			return nil, fmt.Errorf("%w: unexpected '%s'", ErrParsingCensus, strings.Join(args[1:], " "))
+		}
+		if len(args) == 1 {
+			if !isURL(args[0]) {
+				return nil, fmt.Errorf("%w: invalid file url '%s'", ErrParsingCensus, args[0])
+			}
+			census.URL = args[0]
+		}
+	}
+	return census, nil
+}
+
+// isURL returns if the given value is an http or https url.
+func isURL(value string) bool {
+	lower := strings.ToLower(value)
+	return strings.HasPrefix(lower, "https://") || strings.HasPrefix(lower, "http://")
+}
@@ -0,0 +1,240 @@
+package poll
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Limits represents the overrides of the limits of a PollConfig, the zero
+// values keep the limits of the config as they are.
+type Limits struct {
+	MinOptions      int
+	MaxOptions      int
+	MinDuration     time.Duration
+	MaxDuration     time.Duration
+	DefaultDuration time.Duration
+	MaxOptionLength int
+}
+
+// Tier represents a group of users, identified by their FIDs, with their own
+// poll limits, which override the limits of the channel where the polls are
+// created.
+type Tier struct {
+	Name   string
+	FIDs   []uint64
+	Limits Limits
+}
+
+// Tiers is a list of tiers of users.
+type Tiers []*Tier
+
+// Find returns the first tier that includes the given FID or nil if no tier
+// includes it.
+func (t Tiers) Find(fid uint64) *Tier {
+	for _, tier := range t {
+		if slices.Contains(tier.FIDs, fid) {
+			return tier
+		}
+	}
+	return nil
+}
+
+// jsonLimits is the representation of the limits in the config file, it
+// allows to define the durations as human readable strings.
+type jsonLimits struct {
+	MinOptions      int    `json:"minOptions" yaml:"minOptions"`
+	MaxOptions      int    `json:"maxOptions" yaml:"maxOptions"`
+	MinDuration     string `json:"minDuration" yaml:"minDuration"`
+	MaxDuration     string `json:"maxDuration" yaml:"maxDuration"`
+	DefaultDuration string `json:"defaultDuration" yaml:"defaultDuration"`
+	MaxOptionLength int    `json:"maxOptionLength" yaml:"maxOptionLength"`
+}
+
+type jsonTier struct {
+	Name       string   `json:"name" yaml:"name"`
+	FIDs       []uint64 `json:"fids" yaml:"fids"`
+	jsonLimits `yaml:",inline"`
+}
+
+type configFile struct {
+	jsonLimits `yaml:",inline"`
+	Tiers      []*jsonTier `json:"tiers" yaml:"tiers"`
+}
+
+// LoadConfig reads the poll config from the file in the given path and
+// returns the given base config with the limits of the file applied, and the
+// tiers of users defined in it. The file can be a YAML file, if its extension
+// is '.yaml' or '.yml', or a JSON file otherwise, and should follow the
+// format:
+//
+//	minOptions: 2
+//	maxOptions: 4
+//	minDuration: 1h
+//	maxDuration: 52w
+//	defaultDuration: 1d
+//	maxOptionLength: 32
+//	tiers:
+//	  - name: pro
+//	    fids: [1, 2, 3]
+//	    maxDuration: 104w
+//
+// Every limit is optional. The resulting config and the config of every tier
+// are validated, see PollConfig.Validate.
+func LoadConfig(path string, base PollConfig) (PollConfig, Tiers, error) {
+	body, err := os.ReadFile(path)
+	if err != nil {
+		return base, nil, errors.Join(ErrReadingConfig, err)
+	}
+	file := &configFile{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(body, file)
+	default:
+		err = json.Unmarshal(body, file)
+	}
+	if err != nil {
+		return base, nil, errors.Join(ErrInvalidConfig, err)
+	}
+	limits, err := file.limits()
+	if err != nil {
+		return base, nil, err
+	}
+	config := base.Override(limits)
+	if err := config.Validate(); err != nil {
+		return base, nil, err
+	}
+	tiers := make(Tiers, 0, len(file.Tiers))
+	for _, t := range file.Tiers {
+		if t.Name == "" {
+			return base, nil, fmt.Errorf("%w: tier name not set", ErrInvalidConfig)
+		}
+		tier := &Tier{Name: t.Name, FIDs: t.FIDs}
+		if tier.Limits, err = t.limits(); err != nil {
+			return base, nil, fmt.Errorf("tier %s: %w", t.Name, err)
+		}
+		if err := config.Override(tier.Limits).Validate(); err != nil {
+			return base, nil, fmt.Errorf("tier %s: %w", t.Name, err)
+		}
+		tiers = append(tiers, tier)
+	}
+	return config, tiers, nil
+}
+
+// limits parses the limits of the config file.
+func (l jsonLimits) limits() (Limits, error) {
+	if l.MinOptions < 0 || l.MaxOptions < 0 || l.MaxOptionLength < 0 {
+		return Limits{}, fmt.Errorf("%w: negative number of options or option length", ErrInvalidConfig)
+	}
+	limits := Limits{MinOptions: l.MinOptions, MaxOptions: l.MaxOptions, MaxOptionLength: l.MaxOptionLength}
+	for _, duration := range []struct {
+		value  string
+		target *time.Duration
+	}{
+		{l.MinDuration, &limits.MinDuration},
+		{l.MaxDuration, &limits.MaxDuration},
+		{l.DefaultDuration, &limits.DefaultDuration},
+	} {
+		if duration.value == "" {
+			continue
+		}
+		var err error
+		if *duration.target, err = ParseDuration(duration.value); err != nil {
+			return Limits{}, fmt.Errorf("%w: %w", ErrInvalidConfig, err)
+		}
+	}
+	return limits, nil
+}
+
+// Override returns a copy of the config with the given limits applied, the
+// zero limits are ignored.
+func (c PollConfig) Override(limits Limits) PollConfig {
+	if limits.MinOptions != 0 {
+		c.MinOptions = limits.MinOptions
+	}
+	if limits.MaxOptions != 0 {
+		c.MaxOptions = limits.MaxOptions
+	}
+	if limits.MinDuration != 0 {
+		c.MinDuration = limits.MinDuration
+	}
+	if limits.MaxDuration != 0 {
+		c.MaxDuration = limits.MaxDuration
+	}
+	if limits.DefaultDuration != 0 {
+		c.DefaultDuration = limits.DefaultDuration
+	}
+	if limits.MaxOptionLength != 0 {
+		c.MaxOptionLength = limits.MaxOptionLength
+	}
+	return c
+}
+
+// Validate returns an error if the limits of the config are not consistent:
+// the min number of options must be at least one and not greater than the
+// max number of options, and the default duration must be between the min
+// and max durations, which must be positive. The options of every template
+// must also fit in the config, see checkTemplates.
+func (c PollConfig) Validate() error {
+	if c.MinOptions < 1 || c.MinOptions > c.MaxOptions {
+		return fmt.Errorf("%w: the min number of options %d must be between 1 and the max number of options %d",
+			ErrInvalidConfig, c.MinOptions, c.MaxOptions)
+	}
+	if c.MinDuration <= 0 || c.MinDuration > c.DefaultDuration || c.DefaultDuration > c.MaxDuration {
+		return fmt.Errorf("%w: the default duration %s must be between %s and %s", ErrInvalidConfig,
+			FormatDuration(c.DefaultDuration), FormatDuration(c.MinDuration), FormatDuration(c.MaxDuration))
+	}
+	return c.checkTemplates()
+}
+
+// checkTemplates returns an error if any template of the config has more
+// options than the max number of options, or any of its options is empty,
+// too long or duplicated, so the polls that use it would always fail.
+func (c PollConfig) checkTemplates() error {
+	for name, template := range c.Templates {
+		if len(template.Options) > c.MaxOptions {
+			return fmt.Errorf("%w: %s has %d options, the max is %d",
+				ErrInvalidTemplate, name, len(template.Options), c.MaxOptions)
+		}
+		checker := newOptionsChecker(c)
+		for _, option := range template.Options {
+			if _, err := checker.check(option); err != nil {
+				return fmt.Errorf("%w: %s: %w", ErrInvalidTemplate, name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// FormatDuration returns the given duration in the short format supported by
+// ParseDuration, such as '1w 2d' or '1h 30m', rounded to seconds.
+func FormatDuration(duration time.Duration) string {
+	duration = duration.Round(time.Second)
+	if duration <= 0 {
+		return "0s"
+	}
+	parts := []string{}
+	for _, unit := range []struct {
+		name  string
+		value time.Duration
+	}{
+		{"w", week},
+		{"d", day},
+		{"h", time.Hour},
+		{"m", time.Minute},
+		{"s", time.Second},
+	} {
+		if count := duration / unit.value; count > 0 {
+			parts = append(parts, fmt.Sprintf("%d%s", count, unit.name))
+			duration -= count * unit.value
+		}
+	}
+	return strings.Join(parts, " ")
+}
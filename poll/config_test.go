@@ -0,0 +1,114 @@
+package poll
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+)
+
+const configYAML = `minOptions: 2
+maxOptions: 3
+maxDuration: 4w
+defaultDuration: 2d
+tiers:
+  - name: pro
+    fids: [1, 2]
+    maxOptions: 6
+    maxDuration: 52w
+  - name: trial
+    fids: [3]
+    minDuration: 2h
+`
+
+func TestLoadConfig(t *testing.T) {
+	c := qt.New(t)
+
+	dir := t.TempDir()
+	yamlPath := filepath.Join(dir, "poll.yaml")
+	c.Assert(os.WriteFile(yamlPath, []byte(configYAML), 0o600), qt.IsNil)
+	config, tiers, err := LoadConfig(yamlPath, DefaultConfig)
+	c.Assert(err, qt.IsNil)
+	c.Assert(config, qt.DeepEquals, PollConfig{
+		MinOptions:      2,
+		MaxOptions:      3,
+		MinDuration:     time.Hour,
+		MaxDuration:     4 * week,
+		DefaultDuration: 2 * day,
+		MaxOptionLength: DefaultMaxOptionLength,
+	})
+	c.Assert(tiers, qt.DeepEquals, Tiers{
+		{Name: "pro", FIDs: []uint64{1, 2}, Limits: Limits{MaxOptions: 6, MaxDuration: 52 * week}},
+		{Name: "trial", FIDs: []uint64{3}, Limits: Limits{MinDuration: 2 * time.Hour}},
+	})
+	c.Assert(tiers.Find(2).Name, qt.Equals, "pro")
+	c.Assert(tiers.Find(4), qt.IsNil)
+
+	jsonPath := filepath.Join(dir, "poll.json")
+	c.Assert(os.WriteFile(jsonPath, []byte(`{"maxOptions":6,"tiers":[{"name":"pro","fids":[1],"minOptions":3}]}`), 0o600), qt.IsNil)
+	config, tiers, err = LoadConfig(jsonPath, DefaultConfig)
+	c.Assert(err, qt.IsNil)
+	c.Assert(config.MaxOptions, qt.Equals, 6)
+	c.Assert(config.Override(tiers.Find(1).Limits).MinOptions, qt.Equals, 3)
+
+	_, _, err = LoadConfig(filepath.Join(dir, "missing.json"), DefaultConfig)
+	c.Assert(err, qt.ErrorIs, ErrReadingConfig)
+
+	for name, content := range map[string]string{
+		"invalid json":              `{`,
+		"negative options":          `{"maxOptions":-1}`,
+		"negative option length":    `{"maxOptionLength":-1}`,
+		"invalid duration":          `{"maxDuration":"forever"}`,
+		"min options above max":     `{"minOptions":5}`,
+		"default above max":         `{"defaultDuration":"2w","maxDuration":"1w"}`,
+		"tier without name":         `{"tiers":[{"fids":[1]}]}`,
+		"inconsistent tier":         `{"tiers":[{"name":"pro","fids":[1],"maxOptions":1}]}`,
+		"tier default below min":    `{"tiers":[{"name":"pro","fids":[1],"minDuration":"2d"}]}`,
+		"tier with invalid options": `{"tiers":[{"name":"pro","fids":[1],"minOptions":-2}]}`,
+	} {
+		c.Assert(os.WriteFile(jsonPath, []byte(content), 0o600), qt.IsNil)
+		_, _, err = LoadConfig(jsonPath, DefaultConfig)
+		c.Assert(err, qt.ErrorIs, ErrInvalidConfig, qt.Commentf(name))
+	}
+}
+
+func TestPollConfigValidate(t *testing.T) {
+	c := qt.New(t)
+
+	c.Assert(DefaultConfig.Validate(), qt.IsNil)
+	c.Assert(DefaultConfig.Override(Limits{MinOptions: 4}).Validate(), qt.IsNil)
+	c.Assert(DefaultConfig.Override(Limits{MinOptions: 5}).Validate(), qt.ErrorIs, ErrInvalidConfig)
+	c.Assert(DefaultConfig.Override(Limits{MaxDuration: 12 * time.Hour}).Validate(), qt.ErrorIs, ErrInvalidConfig)
+	c.Assert(DefaultConfig.Override(Limits{MinDuration: 2 * day}).Validate(), qt.ErrorIs, ErrInvalidConfig)
+	c.Assert(PollConfig{}.Validate(), qt.ErrorIs, ErrInvalidConfig)
+
+	config := DefaultConfig
+	config.Templates = map[string]*Template{"colours": {Name: "colours", Options: []string{"Red", "Green", "Blue"}}}
+	c.Assert(config.Validate(), qt.IsNil)
+	c.Assert(config.Override(Limits{MaxOptions: 2, MinOptions: 1}).Validate(), qt.ErrorIs, ErrInvalidTemplate)
+	c.Assert(config.Override(Limits{MaxOptionLength: 4}).Validate(), qt.ErrorIs, ErrOptionTooLong)
+	config.Templates["colours"].Options = []string{"Red", "RED"}
+	c.Assert(config.Validate(), qt.ErrorIs, ErrDuplicatedOption)
+}
+
+func TestFormatDuration(t *testing.T) {
+	c := qt.New(t)
+
+	for duration, expected := range map[time.Duration]string{
+		0:                                   "0s",
+		90 * time.Minute:                    "1h 30m",
+		day:                                 "1d",
+		DefaultConfig.MaxDuration:           "52w 1d",
+		week + 2*day + 3*time.Hour:          "1w 2d 3h",
+		time.Minute + 1500*time.Millisecond: "1m 2s",
+	} {
+		c.Assert(FormatDuration(duration), qt.Equals, expected)
+		if duration > 0 {
+			parsed, err := ParseDuration(expected)
+			c.Assert(err, qt.IsNil)
+			c.Assert(parsed, qt.Equals, duration.Round(time.Second))
+		}
+	}
+}
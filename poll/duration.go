@@ -0,0 +1,161 @@
+package poll
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	day  = 24 * time.Hour
+	week = 7 * day
+	// untilPrefix is the optional prefix of an absolute end date
+	untilPrefix = "until "
+	// utcSuffix is the optional suffix of an absolute end date, every end
+	// date is parsed in UTC
+	utcSuffix = " utc"
+)
+
+var (
+	// durationUnitRgx matches a number followed by a unit at the start of a
+	// relative duration, such as '3d', '1.5 hours' or '30m'
+	durationUnitRgx = regexp.MustCompile(`^(\d+(?:\.\d+)?)\s*(weeks|week|w|days|day|d|hours|hour|hrs|hr|h|minutes|minute|mins|min|m|seconds|second|secs|sec|s)\s*`)
+	// isoDurationRgx matches an ISO-8601 duration without years and months,
+	// such as 'P1W', 'P3DT12H' or 'PT90M'
+	isoDurationRgx = regexp.MustCompile(`^P(?:(\d+)W)?(?:(\d+)D)?(?:T(?:(\d+)H)?(?:(\d+)M)?(?:(\d+)S)?)?$`)
+	// weekdayRgx matches a weekday with an optional time, such as 'friday',
+	// 'fri 18:00' or 'Friday 9:30'
+	weekdayRgx = regexp.MustCompile(`^([a-z]+)(?:\s+(\d{1,2}):(\d{2}))?$`)
+	// durationUnits contains the duration of every supported unit
+	durationUnits = map[string]time.Duration{
+		"weeks": week, "week": week, "w": week,
+		"days": day, "day": day, "d": day,
+		"hours": time.Hour, "hour": time.Hour, "hrs": time.Hour, "hr": time.Hour, "h": time.Hour,
+		"minutes": time.Minute, "minute": time.Minute, "mins": time.Minute, "min": time.Minute, "m": time.Minute,
+		"seconds": time.Second, "second": time.Second, "secs": time.Second, "sec": time.Second, "s": time.Second,
+	}
+	// endDateLayouts contains the supported layouts of absolute end dates
+	endDateLayouts = []string{
+		time.RFC3339,
+		"2006-01-02T15:04",
+		"2006-01-02 15:04:05",
+		"2006-01-02 15:04",
+		"2006-01-02",
+	}
+	// weekdays contains the supported weekday names and abbreviations
+	weekdays = map[string]time.Weekday{
+		"sunday": time.Sunday, "sun": time.Sunday,
+		"monday": time.Monday, "mon": time.Monday,
+		"tuesday": time.Tuesday, "tue": time.Tuesday,
+		"wednesday": time.Wednesday, "wed": time.Wednesday,
+		"thursday": time.Thursday, "thu": time.Thursday,
+		"friday": time.Friday, "fri": time.Friday,
+		"saturday": time.Saturday, "sat": time.Saturday,
+	}
+)
+
+// ParseDuration parses a relative duration. It supports the Go duration format
+// ('72h', '1h30m') extended with days and weeks ('3d', '1w'), combined units
+// separated or not by spaces ('1w 2d', '1d12h'), long unit names ('3 days')
+// and ISO-8601 durations without years and months ('P3D', 'P1DT12H').
+func ParseDuration(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, fmt.Errorf("%w: empty duration", ErrParsingDuration)
+	}
+	// try to parse it as an ISO-8601 duration
+	if upper := strings.ToUpper(s); strings.HasPrefix(upper, "P") {
+		return parseISODuration(upper)
+	}
+	// parse every number and unit pair
+	lower := strings.ToLower(s)
+	var duration time.Duration
+	for lower != "" {
+		match := durationUnitRgx.FindStringSubmatch(lower)
+		if match == nil {
+			return 0, fmt.Errorf("%w: invalid duration '%s'", ErrParsingDuration, s)
+		}
+		value, err := strconv.ParseFloat(match[1], 64)
+		if err != nil {
+			return 0, fmt.Errorf("%w: invalid duration value '%s'", ErrParsingDuration, match[1])
+		}
+		duration += time.Duration(value * float64(durationUnits[match[2]]))
+		lower = lower[len(match[0]):]
+	}
+	return duration, nil
+}
+
+// parseISODuration parses an ISO-8601 duration, it does not support years and
+// months because their duration is ambiguous.
+func parseISODuration(s string) (time.Duration, error) {
+	match := isoDurationRgx.FindStringSubmatch(s)
+	if match == nil || s == "P" || strings.HasSuffix(s, "T") {
+		return 0, fmt.Errorf("%w: invalid ISO-8601 duration '%s'", ErrParsingDuration, s)
+	}
+	var duration time.Duration
+	for i, unit := range []time.Duration{week, day, time.Hour, time.Minute, time.Second} {
+		if match[i+1] == "" {
+			continue
+		}
+		value, err := strconv.Atoi(match[i+1])
+		if err != nil {
+			return 0, fmt.Errorf("%w: invalid ISO-8601 duration value '%s'", ErrParsingDuration, match[i+1])
+		}
+		duration += time.Duration(value) * unit
+	}
+	return duration, nil
+}
+
+// ParseEndDate parses an absolute end date, optionally prefixed by 'until'
+// and suffixed by 'UTC'. It supports RFC3339 dates, dates with time
+// ('2026-11-01 18:00'), dates without time ('2026-11-01', at 00:00) and
+// weekdays with an optional time ('friday 18:00'), which refer to the next
+// occurrence of the weekday after the given current time. Every date without
+// timezone is parsed in UTC.
+func ParseEndDate(s string, now time.Time) (time.Time, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	s = strings.TrimSpace(strings.TrimPrefix(s, untilPrefix))
+	s = strings.TrimSpace(strings.TrimSuffix(s, utcSuffix))
+	for _, layout := range endDateLayouts {
+		// the date layouts require uppercase 'T' and 'Z'
+		if date, err := time.ParseInLocation(layout, strings.ToUpper(s), time.UTC); err == nil {
+			return date, nil
+		}
+	}
+	// try to parse it as a weekday with an optional time
+	match := weekdayRgx.FindStringSubmatch(s)
+	if match == nil {
+		return time.Time{}, fmt.Errorf("%w: invalid end date '%s'", ErrParsingDuration, s)
+	}
+	weekday, ok := weekdays[match[1]]
+	if !ok {
+		return time.Time{}, fmt.Errorf("%w: invalid weekday '%s'", ErrParsingDuration, match[1])
+	}
+	hour, min := 0, 0
+	if match[2] != "" {
+		hour, _ = strconv.Atoi(match[2])
+		min, _ = strconv.Atoi(match[3])
+		if hour > 23 || min > 59 {
+			return time.Time{}, fmt.Errorf("%w: invalid time '%s:%s'", ErrParsingDuration, match[2], match[3])
+		}
+	}
+	now = now.UTC()
+	date := time.Date(now.Year(), now.Month(), now.Day(), hour, min, 0, 0, time.UTC)
+	date = date.AddDate(0, 0, (int(weekday)-int(now.Weekday())+7)%7)
+	if !date.After(now) {
+		date = date.AddDate(0, 0, 7)
+	}
+	return date, nil
+}
+
+// ParseDeadline parses a relative duration or an absolute end date (see
+// ParseDuration and ParseEndDate) and returns the resulting end date from the
+// given current time.
+func ParseDeadline(s string, now time.Time) (time.Time, error) {
+	if duration, err := ParseDuration(s); err == nil {
+		return now.Add(duration), nil
+	}
+	return ParseEndDate(s, now)
+}
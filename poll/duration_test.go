@@ -0,0 +1,106 @@
+package poll
+
+import (
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestParseDuration(t *testing.T) {
+	c := qt.New(t)
+
+	tests := []struct {
+		input    string
+		expected time.Duration
+		err      bool
+	}{
+		{input: "72h", expected: 72 * time.Hour},
+		{input: "1h30m", expected: 90 * time.Minute},
+		{input: "3d", expected: 3 * day},
+		{input: "1w", expected: week},
+		{input: "1w2d", expected: week + 2*day},
+		{input: "1w 2d 3h", expected: week + 2*day + 3*time.Hour},
+		{input: "1.5d", expected: 36 * time.Hour},
+		{input: "3 days", expected: 3 * day},
+		{input: "2 Weeks", expected: 2 * week},
+		{input: "1 day 12 hours", expected: 36 * time.Hour},
+		{input: "90 mins", expected: 90 * time.Minute},
+		{input: "P3D", expected: 3 * day},
+		{input: "P1W", expected: week},
+		{input: "P1DT12H", expected: 36 * time.Hour},
+		{input: "PT90M", expected: 90 * time.Minute},
+		{input: "pt36h", expected: 36 * time.Hour},
+		{input: "", err: true},
+		{input: "24", err: true},
+		{input: "3x", err: true},
+		{input: "3dx", err: true},
+		{input: "-3d", err: true},
+		{input: "P", err: true},
+		{input: "PT", err: true},
+		{input: "P1M", err: true},
+		{input: "P1Y", err: true},
+	}
+	for _, test := range tests {
+		duration, err := ParseDuration(test.input)
+		if test.err {
+			c.Assert(err, qt.ErrorIs, ErrParsingDuration, qt.Commentf("input: %q", test.input))
+			continue
+		}
+		c.Assert(err, qt.IsNil, qt.Commentf("input: %q", test.input))
+		c.Assert(duration, qt.Equals, test.expected, qt.Commentf("input: %q", test.input))
+	}
+}
+
+func TestParseEndDate(t *testing.T) {
+	c := qt.New(t)
+
+	// Wednesday, 2026-10-21 10:00 UTC
+	now := time.Date(2026, 10, 21, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		input    string
+		expected time.Time
+		err      bool
+	}{
+		{input: "2026-11-01T12:00:00Z", expected: time.Date(2026, 11, 1, 12, 0, 0, 0, time.UTC)},
+		{input: "2026-11-01T12:00:00+02:00", expected: time.Date(2026, 11, 1, 10, 0, 0, 0, time.UTC)},
+		{input: "2026-11-01 12:00", expected: time.Date(2026, 11, 1, 12, 0, 0, 0, time.UTC)},
+		{input: "2026-11-01 12:00 UTC", expected: time.Date(2026, 11, 1, 12, 0, 0, 0, time.UTC)},
+		{input: "2026-11-01T12:00", expected: time.Date(2026, 11, 1, 12, 0, 0, 0, time.UTC)},
+		{input: "2026-11-01", expected: time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)},
+		{input: "until 2026-11-01 12:00", expected: time.Date(2026, 11, 1, 12, 0, 0, 0, time.UTC)},
+		{input: "until Friday 18:00 UTC", expected: time.Date(2026, 10, 23, 18, 0, 0, 0, time.UTC)},
+		{input: "fri", expected: time.Date(2026, 10, 23, 0, 0, 0, 0, time.UTC)},
+		{input: "wednesday 12:00", expected: time.Date(2026, 10, 21, 12, 0, 0, 0, time.UTC)},
+		{input: "wednesday 9:30", expected: time.Date(2026, 10, 28, 9, 30, 0, 0, time.UTC)},
+		{input: "until monday", expected: time.Date(2026, 10, 26, 0, 0, 0, 0, time.UTC)},
+		{input: "someday", err: true},
+		{input: "friday 25:00", err: true},
+		{input: "2026-13-01", err: true},
+	}
+	for _, test := range tests {
+		date, err := ParseEndDate(test.input, now)
+		if test.err {
+			c.Assert(err, qt.ErrorIs, ErrParsingDuration, qt.Commentf("input: %q", test.input))
+			continue
+		}
+		c.Assert(err, qt.IsNil, qt.Commentf("input: %q", test.input))
+		c.Assert(date.Equal(test.expected), qt.IsTrue, qt.Commentf("input: %q, got: %s", test.input, date))
+	}
+}
+
+func TestParseDeadline(t *testing.T) {
+	c := qt.New(t)
+
+	now := time.Date(2026, 10, 21, 10, 0, 0, 0, time.UTC)
+	deadline, err := ParseDeadline("3d", now)
+	c.Assert(err, qt.IsNil)
+	c.Assert(deadline, qt.Equals, now.Add(3*day))
+
+	deadline, err = ParseDeadline("until friday 18:00", now)
+	c.Assert(err, qt.IsNil)
+	c.Assert(deadline, qt.Equals, time.Date(2026, 10, 23, 18, 0, 0, 0, time.UTC))
+
+	_, err = ParseDeadline("soon", now)
+	c.Assert(err, qt.ErrorIs, ErrParsingDuration)
+}
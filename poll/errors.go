@@ -6,6 +6,40 @@ var (
 	ErrUnrecognisedCommand  = fmt.Errorf("unrecognised command")
 	ErrQuestionNotSet       = fmt.Errorf("question content not set")
 	ErrParsingDuration      = fmt.Errorf("error parsing duration")
+	ErrDurationOutOfRange   = fmt.Errorf("duration out of range")
+	ErrParsingStartDate     = fmt.Errorf("error parsing start date")
+	ErrParsingQuorum        = fmt.Errorf("error parsing quorum")
+	ErrParsingType          = fmt.Errorf("error parsing vote type")
+	ErrParsingCensus        = fmt.Errorf("error parsing census")
+	ErrInvalidMaxSelections = fmt.Errorf("invalid max number of selections")
 	ErrMinOptionsNotReached = fmt.Errorf("min number of options not reached")
 	ErrMaxOptionsReached    = fmt.Errorf("max number of options reached")
+	ErrEmptyOption          = fmt.Errorf("empty option")
+	ErrDuplicatedOption     = fmt.Errorf("duplicated option")
+	ErrOptionTooLong        = fmt.Errorf("option too long")
+	ErrEmptyHeaderValue     = fmt.Errorf("empty header value")
+	ErrDuplicatedHeader     = fmt.Errorf("duplicated header")
+	ErrUnexpectedOption     = fmt.Errorf("unexpected option after the duration")
+	ErrUnexpectedText       = fmt.Errorf("unexpected text after the duration")
+	ErrReadingTemplates     = fmt.Errorf("error reading poll templates")
+	ErrInvalidTemplate      = fmt.Errorf("invalid poll template")
+	ErrReadingConfig        = fmt.Errorf("error reading poll config")
+	ErrInvalidConfig        = fmt.Errorf("invalid poll config")
 )
+
+// SyntaxError wraps an error found parsing a poll message with the line and
+// the column (both 1-based) where it was found, to help the users to fix
+// their messages. It unwraps to the original error.
+type SyntaxError struct {
+	Line   int
+	Column int
+	Err    error
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("line %d, column %d: %s", e.Line, e.Column, e.Err)
+}
+
+func (e *SyntaxError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,181 @@
+package poll
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	// questionSuffix ends the question, the text after it in the command
+	// line is the inline options or duration, and the line after it can
+	// contain the inline options
+	questionSuffix = "?"
+	// optionSeparator separates the inline options
+	optionSeparator = "|"
+	// firstNumberedOption starts the numbered inline options
+	firstNumberedOption = "1)"
+	// abstainKeyword adds the abstain option to the poll when it ends the
+	// command line
+	abstainKeyword = "abstain"
+	// abstainOption is the option added by the abstain keyword
+	abstainOption = "Abstain"
+)
+
+var (
+	// binaryOptions are the options of the polls that set the question in
+	// the command line without any option
+	binaryOptions = []string{"Yes", "No"}
+	// numberedOptionRgx matches the number of a numbered inline option, such
+	// as '2)', at the start of the text or after a space
+	numberedOptionRgx = regexp.MustCompile(`(?:^|\s)(\d+)\)`)
+)
+
+// inlinePoll represents the content of the command line of a poll message:
+// the template named after the command, the question, the inline options or
+// duration, and if the abstain option must be added.
+type inlinePoll struct {
+	template     *Template
+	question     string
+	optionTokens []*token
+	duration     *token
+	abstain      bool
+}
+
+// parseCommand parses the value of the given command token with the format:
+// !poll <template*> <question>? <options* | duration* abstain*>
+// The template is used if the first word is the name of a template of the
+// config, otherwise it is part of the question. The text after the last
+// question mark is the inline options, see splitOptions, or the inline
+// duration, optionally followed by the abstain keyword. If the value has no
+// question mark, the whole value is the question.
+func parseCommand(tok *token, config PollConfig) (*inlinePoll, error) {
+	inline := &inlinePoll{}
+	value, valueColumn := tok.value, tok.valueColumn
+	name := strings.Fields(value)[0]
+	if inline.template = config.template(name); inline.template != nil {
+		value, valueColumn = trimValue(value, len(name), valueColumn)
+	}
+	end := strings.LastIndex(value, questionSuffix)
+	if end < 0 {
+		inline.question = value
+		return inline, nil
+	}
+	inline.question = value[:end+len(questionSuffix)]
+	rest, restColumn := trimValue(value, end+len(questionSuffix), valueColumn)
+	if inline.optionTokens = splitOptions(rest, tok.line, restColumn); inline.optionTokens != nil {
+		return inline, nil
+	}
+	if fields := strings.Fields(rest); len(fields) > 0 && strings.EqualFold(fields[len(fields)-1], abstainKeyword) {
+		inline.abstain = true
+		rest = strings.TrimSpace(rest[:len(rest)-len(abstainKeyword)])
+	}
+	if rest != "" {
+		inline.duration = &token{
+			kind:        tokenText,
+			line:        tok.line,
+			column:      restColumn,
+			key:         headerDuration,
+			value:       rest,
+			valueColumn: restColumn,
+		}
+		if err := checkValue(inline.duration); err != nil {
+			return nil, err
+		}
+	}
+	return inline, nil
+}
+
+// splitQuestion splits the given text line, which starts at the given column,
+// at its last question mark, if the text after it is a list of inline
+// options. It returns the question, including the question mark, and the
+// tokens of the options, or nil if the line does not include them.
+func splitQuestion(text string, line, column int) (string, []*token) {
+	end := strings.LastIndex(text, questionSuffix)
+	if end < 0 {
+		return "", nil
+	}
+	rest, restColumn := trimValue(text, end+len(questionSuffix), column)
+	optionTokens := splitOptions(rest, line, restColumn)
+	if optionTokens == nil {
+		return "", nil
+	}
+	return text[:end+len(questionSuffix)], optionTokens
+}
+
+// splitOptions splits the given text, which starts at the given line and
+// column, in a list of inline options and returns a token for every option.
+// The options can be numbered ('1) Red 2) Blue 3) Green'), when the text
+// starts with the first number, or separated by '|' ('Red | Blue | Green').
+// The numbers must follow the sequence, so any other number is part of the
+// options text. If the text is not a list of options, it returns nil.
+func splitOptions(text string, line, column int) []*token {
+	// calculate the byte offsets where every option starts and ends
+	var bounds [][2]int
+	switch {
+	case strings.HasPrefix(text, firstNumberedOption):
+		next := 1
+		for _, match := range numberedOptionRgx.FindAllStringSubmatchIndex(text, -1) {
+			if number, err := strconv.Atoi(text[match[2]:match[3]]); err != nil || number != next {
+				continue
+			}
+			if len(bounds) > 0 {
+				bounds[len(bounds)-1][1] = match[2]
+			}
+			bounds = append(bounds, [2]int{match[1], len(text)})
+			next++
+		}
+	case strings.Contains(text, optionSeparator):
+		start := 0
+		for _, part := range strings.Split(text, optionSeparator) {
+			bounds = append(bounds, [2]int{start, start + len(part)})
+			start += len(part) + len(optionSeparator)
+		}
+	default:
+		return nil
+	}
+	tokens := make([]*token, 0, len(bounds))
+	for _, bound := range bounds {
+		value, valueColumn := trimValue(text[:bound[1]], bound[0], column)
+		tokens = append(tokens, &token{
+			kind:        tokenOption,
+			line:        line,
+			column:      valueColumn,
+			value:       value,
+			valueColumn: valueColumn,
+		})
+	}
+	return tokens
+}
+
+// extraOptions returns the options that must be added to the given options
+// of the message: the ones of the template if the message has none. If there
+// are no options and the question is set in the command line, the poll is a
+// yes/no poll, with the abstain option if it is required to reach the min
+// number of options of the config. The abstain option is also added if the
+// keyword is set and the options do not include it yet.
+func (p *inlinePoll) extraOptions(options []string, config PollConfig) []string {
+	extra := []string{}
+	if len(options) == 0 && p.template != nil {
+		extra = append(extra, p.template.Options...)
+	}
+	if len(options) == 0 && len(extra) == 0 && p.question != "" {
+		extra = append(extra, binaryOptions...)
+		p.abstain = p.abstain || config.MinOptions > len(binaryOptions)
+	}
+	if p.abstain && !containsFold(options, abstainOption) && !containsFold(extra, abstainOption) {
+		extra = append(extra, abstainOption)
+	}
+	return extra
+}
+
+// containsFold returns if the given list contains the given value, ignoring
+// the case.
+func containsFold(list []string, value string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, value) {
+			return true
+		}
+	}
+	return false
+}
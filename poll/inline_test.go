@@ -0,0 +1,343 @@
+package poll
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestParseStringInline(t *testing.T) {
+	c := qt.New(t)
+
+	now := time.Date(2026, 10, 21, 10, 0, 0, 0, time.UTC)
+	timeNow = func() time.Time { return now }
+	defer func() { timeNow = time.Now }()
+
+	threeOptions := DefaultConfig
+	threeOptions.MinOptions = 3
+	tests := []struct {
+		name     string
+		message  string
+		config   *PollConfig
+		expected *Poll
+		err      error
+		line     int
+		column   int
+	}{
+		{
+			name:    "yes/no poll",
+			message: "!poll Should we merge PR #42?",
+			expected: &Poll{
+				Question:      "Should we merge PR #42?",
+				Options:       []string{"Yes", "No"},
+				Duration:      DefaultConfig.DefaultDuration,
+				EndDate:       now.Add(DefaultConfig.DefaultDuration),
+				Type:          VoteTypeSingle,
+				MaxSelections: 1,
+			},
+		},
+		{
+			name:    "yes/no poll with duration",
+			message: "!poll Should we merge PR #42? 48h",
+			expected: &Poll{
+				Question:      "Should we merge PR #42?",
+				Options:       []string{"Yes", "No"},
+				Duration:      48 * time.Hour,
+				EndDate:       now.Add(48 * time.Hour),
+				Type:          VoteTypeSingle,
+				MaxSelections: 1,
+			},
+		},
+		{
+			name:    "yes/no poll with end date and abstain",
+			message: "!poll Is it ready? Really? until 2026-10-23 10:00 Abstain",
+			expected: &Poll{
+				Question:      "Is it ready? Really?",
+				Options:       []string{"Yes", "No", "Abstain"},
+				Duration:      48 * time.Hour,
+				EndDate:       now.Add(48 * time.Hour),
+				Type:          VoteTypeSingle,
+				MaxSelections: 1,
+			},
+		},
+		{
+			name:    "abstain required by the min options",
+			message: "!poll Should we merge PR #42? 2d",
+			config:  &threeOptions,
+			expected: &Poll{
+				Question:      "Should we merge PR #42?",
+				Options:       []string{"Yes", "No", "Abstain"},
+				Duration:      48 * time.Hour,
+				EndDate:       now.Add(48 * time.Hour),
+				Type:          VoteTypeSingle,
+				MaxSelections: 1,
+			},
+		},
+		{
+			name:    "question without question mark and headers",
+			message: "!poll Merge PR #42\nquorum: 10\n",
+			expected: &Poll{
+				Question:      "Merge PR #42",
+				Options:       []string{"Yes", "No"},
+				Duration:      DefaultConfig.DefaultDuration,
+				EndDate:       now.Add(DefaultConfig.DefaultDuration),
+				Type:          VoteTypeSingle,
+				MaxSelections: 1,
+				Quorum:        Quorum{Votes: 10},
+			},
+		},
+		{
+			name:    "options and positional duration",
+			message: "!poll Which colour?\n- Red\n- Blue\n3d\n",
+			expected: &Poll{
+				Question:      "Which colour?",
+				Options:       []string{"Red", "Blue"},
+				Duration:      72 * time.Hour,
+				EndDate:       now.Add(72 * time.Hour),
+				Type:          VoteTypeSingle,
+				MaxSelections: 1,
+			},
+		},
+		{
+			name:    "abstain added to the options",
+			message: "!poll Which colour? abstain\n- Red\n- Blue\n",
+			expected: &Poll{
+				Question:      "Which colour?",
+				Options:       []string{"Red", "Blue", "Abstain"},
+				Duration:      DefaultConfig.DefaultDuration,
+				EndDate:       now.Add(DefaultConfig.DefaultDuration),
+				Type:          VoteTypeSingle,
+				MaxSelections: 1,
+			},
+		},
+		{
+			name:    "invalid inline duration",
+			message: "!poll Should we merge PR #42? soon",
+			err:     ErrParsingDuration,
+			line:    1,
+			column:  31,
+		},
+		{
+			name:    "inline duration out of range",
+			message: "!poll Should we merge? 10m",
+			err:     ErrDurationOutOfRange,
+			line:    1,
+			column:  24,
+		},
+		{
+			name:    "inline and positional durations",
+			message: "!poll Should we merge PR #42? 2d\n3d\n",
+			err:     ErrUnexpectedText,
+			line:    2,
+			column:  1,
+		},
+		{
+			name:    "options after the inline duration",
+			message: "!poll Which colour? 2d\n- Red\n- Blue\n",
+			err:     ErrUnexpectedOption,
+			line:    2,
+			column:  1,
+		},
+		{
+			name:    "too many options with abstain",
+			message: "!poll Which colour? abstain\n- Red\n- Blue\n- Green\n- Yellow\n",
+			err:     ErrMaxOptionsReached,
+			line:    1,
+			column:  7,
+		},
+		{
+			name:    "options separated in the command line",
+			message: "!poll Which colour? Red |Blue| Green\n3d\n",
+			expected: &Poll{
+				Question:      "Which colour?",
+				Options:       []string{"Red", "Blue", "Green"},
+				Duration:      72 * time.Hour,
+				EndDate:       now.Add(72 * time.Hour),
+				Type:          VoteTypeSingle,
+				MaxSelections: 1,
+			},
+		},
+		{
+			name:    "numbered options after the question",
+			message: "!poll\nduration: 2d\nWhich is best? Really? 1) Option 3) A 2) B\n",
+			expected: &Poll{
+				Question:      "Which is best? Really?",
+				Options:       []string{"Option 3) A", "B"},
+				Duration:      48 * time.Hour,
+				EndDate:       now.Add(48 * time.Hour),
+				Type:          VoteTypeSingle,
+				MaxSelections: 1,
+			},
+		},
+		{
+			name:    "options in the line after the question",
+			message: "!poll\nThe new logo\nWhich colour?\n1) Red 2) Blue\n3d\n",
+			expected: &Poll{
+				Question:      "The new logo\nWhich colour?",
+				Options:       []string{"Red", "Blue"},
+				Duration:      72 * time.Hour,
+				EndDate:       now.Add(72 * time.Hour),
+				Type:          VoteTypeSingle,
+				MaxSelections: 1,
+			},
+		},
+		{
+			name:    "separators without question mark",
+			message: "!poll\nRed | Blue\n- Yes\n- No\n",
+			expected: &Poll{
+				Question:      "Red | Blue",
+				Options:       []string{"Yes", "No"},
+				Duration:      DefaultConfig.DefaultDuration,
+				EndDate:       now.Add(DefaultConfig.DefaultDuration),
+				Type:          VoteTypeSingle,
+				MaxSelections: 1,
+			},
+		},
+		{
+			name:    "too many inline options",
+			message: "!poll\nWhich colour? A | B | C | D | É | F\n",
+			err:     ErrMaxOptionsReached,
+			line:    2,
+			column:  31,
+		},
+		{
+			name:    "not enough inline options",
+			message: "!poll Which colour? 1) Red\n",
+			err:     ErrMinOptionsNotReached,
+		},
+		{
+			name:    "inline options after the inline duration",
+			message: "!poll Which colour? 2d\nRed | Blue\n",
+			err:     ErrUnexpectedText,
+			line:    2,
+			column:  1,
+		},
+		{
+			name:    "dash options after the duration",
+			message: "!poll\nWhich colour? Red | Blue\n2d\n- Green\n",
+			err:     ErrUnexpectedOption,
+			line:    4,
+			column:  1,
+		},
+		{
+			name:    "question in the command line and in the next line",
+			message: "!poll\nWhich colour?\n!poll Which colour?\n",
+			err:     ErrUnexpectedText,
+			line:    3,
+			column:  1,
+		},
+	}
+	for _, test := range tests {
+		c.Run(test.name, func(c *qt.C) {
+			config := DefaultConfig
+			if test.config != nil {
+				config = *test.config
+			}
+			poll, err := ParseString(test.message, config)
+			if test.err != nil {
+				c.Assert(err, qt.ErrorIs, test.err)
+				c.Assert(poll, qt.IsNil)
+				syntaxErr := &SyntaxError{}
+				if test.line == 0 {
+					c.Assert(errors.As(err, &syntaxErr), qt.IsFalse)
+					return
+				}
+				c.Assert(errors.As(err, &syntaxErr), qt.IsTrue)
+				c.Assert(syntaxErr.Line, qt.Equals, test.line)
+				c.Assert(syntaxErr.Column, qt.Equals, test.column)
+				return
+			}
+			c.Assert(err, qt.IsNil)
+			c.Assert(poll, qt.DeepEquals, test.expected)
+		})
+	}
+}
+
+func FuzzParseString(f *testing.F) {
+	for _, message := range []string{
+		correctMessage,
+		"!poll\nduration: 2d\ntype: multiple 2\nQuestion?\n- A\n- B\n- C\n",
+		"!poll Should we merge PR #42? 48h abstain",
+		"!poll Which colour? Red | Blue | Green",
+		"!poll\nWhich colour?\n1) Red 2) Blue 3) Green\n3d",
+		"!poll\n?\n1)|\n|",
+	} {
+		f.Add(message)
+	}
+	f.Fuzz(func(t *testing.T, message string) {
+		poll, err := ParseString(message, DefaultConfig)
+		if err != nil {
+			// the syntax errors must point to a position of the message
+			syntaxErr := &SyntaxError{}
+			if errors.As(err, &syntaxErr) {
+				lines := strings.Count(message, "\n") + 1
+				if syntaxErr.Line < 1 || syntaxErr.Line > lines || syntaxErr.Column < 1 {
+					t.Fatalf("invalid error position for %q: %s", message, err)
+				}
+			}
+			return
+		}
+		if poll.Question == "" {
+			t.Fatalf("empty question for %q", message)
+		}
+		if len(poll.Options) < DefaultConfig.MinOptions || len(poll.Options) > DefaultConfig.MaxOptions {
+			t.Fatalf("invalid number of options for %q: %d", message, len(poll.Options))
+		}
+	})
+}
+
+func FuzzParseStringInlineOptions(f *testing.F) {
+	now := time.Date(2026, 10, 21, 10, 0, 0, 0, time.UTC)
+	timeNow = func() time.Time { return now }
+	f.Cleanup(func() { timeNow = time.Now })
+
+	f.Add("Which colour", "Red", "Blue", "Green")
+	f.Add("Pick one", "1", "2) or 3)", "-x")
+	f.Add("Qué", "Sí", "No", "")
+	f.Fuzz(func(t *testing.T, question, a, b, c string) {
+		// skip the questions that are not a single text line and the
+		// options that can not be written inline, or that would start a dash
+		// option in the line after the question
+		if tokens := tokenize(question); len(tokens) != 1 || tokens[0].kind != tokenText ||
+			tokens[0].value != question || strings.ContainsAny(question, "?|\r") {
+			t.Skip()
+		}
+		if strings.HasPrefix(a, optionPrefix) {
+			t.Skip()
+		}
+		options := []string{a, b}
+		if c != "" {
+			options = append(options, c)
+		}
+		for _, option := range options {
+			if option == "" || strings.TrimSpace(option) != option || strings.ContainsAny(option, "\n\r?|") ||
+				numberedOptionRgx.MatchString(" "+option) {
+				t.Skip()
+			}
+		}
+		numbered := make([]string, 0, len(options))
+		for i, option := range options {
+			numbered = append(numbered, fmt.Sprintf("%d) %s", i+1, option))
+		}
+		// every inline form must be parsed as the dash form
+		expected, expectedErr := ParseString("!poll\n"+question+"?\n- "+strings.Join(options, "\n- ")+"\n", DefaultConfig)
+		for _, message := range []string{
+			"!poll " + question + "? " + strings.Join(options, " | "),
+			"!poll\n" + question + "? " + strings.Join(numbered, " "),
+			"!poll\n" + question + "?\n" + strings.Join(options, "|"),
+		} {
+			poll, err := ParseString(message, DefaultConfig)
+			if (err == nil) != (expectedErr == nil) {
+				t.Fatalf("unexpected result for %q: %v, expected %v", message, err, expectedErr)
+			}
+			if err == nil && !reflect.DeepEqual(poll, expected) {
+				t.Fatalf("unexpected poll for %q: %+v, expected %+v", message, poll, expected)
+			}
+		}
+	})
+}
@@ -0,0 +1,131 @@
+package poll
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/rivo/uniseg"
+	"golang.org/x/text/cases"
+	"golang.org/x/text/unicode/norm"
+)
+
+const (
+	// DefaultMaxOptionLength is the default max number of characters of an
+	// option, the options are the labels of the frame buttons, which are
+	// truncated by the frame clients if they are longer. The characters are
+	// counted as they are displayed (grapheme clusters), so every emoji or
+	// letter with accents counts as one, whatever its number of code points
+	DefaultMaxOptionLength = 32
+	// maxQuotedLength is the max number of characters of an option quoted in
+	// an error, the longer options are shortened
+	maxQuotedLength = 20
+)
+
+var (
+	// optionFolder folds the case of the options to detect the duplicated
+	// ones
+	optionFolder = cases.Fold()
+	// defaultIgnorable are the default ignorable code points, which are not
+	// displayed, such as the variation selectors, the zero width joiners and
+	// the soft hyphens. The tag characters are not included because they
+	// identify the subdivision flags, such as the flags of England and
+	// Scotland.
+	defaultIgnorable = &unicode.RangeTable{
+		R16: []unicode.Range16{
+			{Lo: 0x00ad, Hi: 0x00ad, Stride: 1},
+			{Lo: 0x034f, Hi: 0x034f, Stride: 1},
+			{Lo: 0x061c, Hi: 0x061c, Stride: 1},
+			{Lo: 0x115f, Hi: 0x1160, Stride: 1},
+			{Lo: 0x17b4, Hi: 0x17b5, Stride: 1},
+			{Lo: 0x180b, Hi: 0x180f, Stride: 1},
+			{Lo: 0x200b, Hi: 0x200f, Stride: 1},
+			{Lo: 0x202a, Hi: 0x202e, Stride: 1},
+			{Lo: 0x2060, Hi: 0x206f, Stride: 1},
+			{Lo: 0x3164, Hi: 0x3164, Stride: 1},
+			{Lo: 0xfe00, Hi: 0xfe0f, Stride: 1},
+			{Lo: 0xfeff, Hi: 0xfeff, Stride: 1},
+			{Lo: 0xffa0, Hi: 0xffa0, Stride: 1},
+			{Lo: 0xfff0, Hi: 0xfff8, Stride: 1},
+		},
+		R32: []unicode.Range32{
+			{Lo: 0x1bca0, Hi: 0x1bca3, Stride: 1},
+			{Lo: 0x1d173, Hi: 0x1d17a, Stride: 1},
+			{Lo: 0xe0000, Hi: 0xe001f, Stride: 1},
+			{Lo: 0xe0080, Hi: 0xe0fff, Stride: 1},
+		},
+		LatinOffset: 1,
+	}
+	// emojiModifiers are the skin tone modifiers of the emojis
+	emojiModifiers = &unicode.RangeTable{
+		R32: []unicode.Range32{{Lo: 0x1f3fb, Hi: 0x1f3ff, Stride: 1}},
+	}
+)
+
+// optionsChecker checks the options of a poll one by one, it keeps the
+// options already checked to detect the duplicated ones.
+type optionsChecker struct {
+	maxLength int
+	seen      map[string]string
+}
+
+// newOptionsChecker returns an options checker with the max option length of
+// the given config.
+func newOptionsChecker(config PollConfig) *optionsChecker {
+	return &optionsChecker{maxLength: config.MaxOptionLength, seen: map[string]string{}}
+}
+
+// check normalizes the given option to the NFC form and returns it. It
+// returns an error if the option is empty or only contains invisible
+// characters, if it has more characters than the max length, or if it is
+// the same as a previous option, see optionKey.
+func (c *optionsChecker) check(option string) (string, error) {
+	option = norm.NFC.String(option)
+	if strings.TrimFunc(option, isInvisible) == "" {
+		return "", ErrEmptyOption
+	}
+	if length := uniseg.GraphemeClusterCount(option); c.maxLength > 0 && length > c.maxLength {
+		return "", fmt.Errorf("%w: '%s' has %d characters, counting every emoji or accented letter as one, the max is %d",
+			ErrOptionTooLong, quoteOption(option), length, c.maxLength)
+	}
+	key := optionKey(option)
+	if previous, ok := c.seen[key]; ok {
+		return "", fmt.Errorf("%w: '%s' repeats '%s'", ErrDuplicatedOption, quoteOption(option), quoteOption(previous))
+	}
+	c.seen[key] = option
+	return option, nil
+}
+
+// optionKey returns the key of the given option to detect the duplicated
+// options: the option with the case folded and without the default
+// ignorable code points and the skin tone modifiers, so the variants of the
+// same text or emoji have the same key.
+func optionKey(option string) string {
+	key := strings.Map(func(r rune) rune {
+		if unicode.In(r, defaultIgnorable, emojiModifiers) {
+			return -1
+		}
+		return r
+	}, optionFolder.String(option))
+	return norm.NFC.String(key)
+}
+
+// isInvisible returns if the given rune is a space, a default ignorable code
+// point or it is not printed, such as the zero width spaces.
+func isInvisible(r rune) bool {
+	return unicode.IsSpace(r) || !unicode.IsGraphic(r) || unicode.Is(defaultIgnorable, r)
+}
+
+// quoteOption returns the given option shortened to be quoted in an error,
+// without splitting its characters.
+func quoteOption(option string) string {
+	if uniseg.GraphemeClusterCount(option) <= maxQuotedLength {
+		return option
+	}
+	graphemes := uniseg.NewGraphemes(option)
+	for i := 0; i < maxQuotedLength-1; i++ {
+		graphemes.Next()
+	}
+	_, end := graphemes.Positions()
+	return option[:end] + "…"
+}
@@ -0,0 +1,190 @@
+package poll
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+const (
+	// familyEmoji is an emoji of five code points joined by zero width
+	// joiners, which is displayed as a single character
+	familyEmoji = "\U0001f468\u200d\U0001f469\u200d\U0001f467"
+	// englandFlag and scotlandFlag are subdivision flags, which only differ
+	// in their tag characters
+	englandFlag  = "\U0001f3f4\U000e0067\U000e0062\U000e0065\U000e006e\U000e0067\U000e007f"
+	scotlandFlag = "\U0001f3f4\U000e0067\U000e0062\U000e0073\U000e0063\U000e0074\U000e007f"
+)
+
+func TestParseStringOptions(t *testing.T) {
+	c := qt.New(t)
+
+	tests := []struct {
+		name     string
+		message  string
+		config   *PollConfig
+		expected []string
+		err      error
+		line     int
+		column   int
+	}{
+		{
+			name:     "normalized options",
+			message:  "!poll\nQuestion?\n- Cafe\u0301\n- Caf\u00e9 au lait\n",
+			expected: []string{"Caf\u00e9", "Caf\u00e9 au lait"},
+		},
+		{
+			name:     "options of the max length",
+			message:  "!poll\nQuestion?\n- " + strings.Repeat("é", DefaultMaxOptionLength) + "\n- B\n",
+			expected: []string{strings.Repeat("é", DefaultMaxOptionLength), "B"},
+		},
+		{
+			name:     "no max length",
+			message:  "!poll\nQuestion?\n- " + strings.Repeat("a", 100) + "\n- B\n",
+			config:   &PollConfig{MinOptions: 2, MaxOptions: 4, MinDuration: 1, MaxDuration: day, DefaultDuration: day},
+			expected: []string{strings.Repeat("a", 100), "B"},
+		},
+		{
+			name:    "empty option",
+			message: "!poll\nQuestion?\n- Yes\n- \n- No\n",
+			err:     ErrEmptyOption,
+			line:    4,
+			column:  2,
+		},
+		{
+			name:    "invisible option",
+			message: "!poll\nQuestion?\n- Yes\n- \u200b\n",
+			err:     ErrEmptyOption,
+			line:    4,
+			column:  3,
+		},
+		{
+			name:    "empty inline option",
+			message: "!poll Question? Yes | | No",
+			err:     ErrEmptyOption,
+			line:    1,
+			column:  23,
+		},
+		{
+			name:    "duplicated option",
+			message: "!poll\nQuestion?\n- Red\n-  red \n",
+			err:     ErrDuplicatedOption,
+			line:    4,
+			column:  4,
+		},
+		{
+			name:    "duplicated option with another normalization",
+			message: "!poll\nQuestion?\n1) CAF\u00c9 2) cafe\u0301\n",
+			err:     ErrDuplicatedOption,
+			line:    3,
+			column:  12,
+		},
+		{
+			name:    "duplicated option with case folding",
+			message: "!poll\nQuestion?\n- Straße\n- STRASSE\n",
+			err:     ErrDuplicatedOption,
+			line:    4,
+			column:  3,
+		},
+		{
+			name:    "duplicated emoji with variation selector",
+			message: "!poll\nQuestion?\n- \u2764\n- \u2764\ufe0f\n",
+			err:     ErrDuplicatedOption,
+			line:    4,
+			column:  3,
+		},
+		{
+			name:    "duplicated emoji with skin tone",
+			message: "!poll\nQuestion?\n1) \U0001f44d\U0001f3fd 2) \U0001f44d\n",
+			err:     ErrDuplicatedOption,
+			line:    3,
+			column:  10,
+		},
+		{
+			name:    "duplicated emoji with zero width joiner",
+			message: "!poll\nQuestion?\n- \U0001f469\u200d\U0001f4bb\n- \U0001f469\U0001f4bb\n",
+			err:     ErrDuplicatedOption,
+			line:    4,
+			column:  3,
+		},
+		{
+			name:    "duplicated option with soft hyphen",
+			message: "!poll\nQuestion?\n- Red\n- Re\u00add\n",
+			err:     ErrDuplicatedOption,
+			line:    4,
+			column:  3,
+		},
+		{
+			name:     "different subdivision flags",
+			message:  "!poll\nQuestion?\n- " + englandFlag + "\n- " + scotlandFlag + "\n",
+			expected: []string{englandFlag, scotlandFlag},
+		},
+		{
+			name:    "variation selector option",
+			message: "!poll\nQuestion?\n- Yes\n- \ufe0f\n",
+			err:     ErrEmptyOption,
+			line:    4,
+			column:  3,
+		},
+		{
+			name:     "emojis of the max length",
+			message:  "!poll\nQuestion?\n- " + strings.Repeat(familyEmoji, DefaultMaxOptionLength) + "\n- B\n",
+			expected: []string{strings.Repeat(familyEmoji, DefaultMaxOptionLength), "B"},
+		},
+		{
+			name:    "emojis too long",
+			message: "!poll\nQuestion?\n- " + strings.Repeat(familyEmoji, DefaultMaxOptionLength+1) + "\n- B\n",
+			err:     ErrOptionTooLong,
+			line:    3,
+			column:  3,
+		},
+		{
+			name:    "duplicated template option",
+			message: "!poll dup Question?",
+			err:     ErrDuplicatedOption,
+			line:    1,
+			column:  7,
+		},
+		{
+			name:    "option too long",
+			message: "!poll\nQuestion?\n- Yes\n- " + strings.Repeat("a", DefaultMaxOptionLength+1) + "\n",
+			err:     ErrOptionTooLong,
+			line:    4,
+			column:  3,
+		},
+	}
+	for _, test := range tests {
+		c.Run(test.name, func(c *qt.C) {
+			config := DefaultConfig
+			if test.config != nil {
+				config = *test.config
+			}
+			config.Templates = map[string]*Template{"dup": {Name: "dup", Options: []string{"Yes", "YES"}}}
+			poll, err := ParseString(test.message, config)
+			if test.err != nil {
+				c.Assert(err, qt.ErrorIs, test.err)
+				syntaxErr := &SyntaxError{}
+				c.Assert(errors.As(err, &syntaxErr), qt.IsTrue)
+				c.Assert(syntaxErr.Line, qt.Equals, test.line)
+				c.Assert(syntaxErr.Column, qt.Equals, test.column)
+				return
+			}
+			c.Assert(err, qt.IsNil)
+			c.Assert(poll.Options, qt.DeepEquals, test.expected)
+		})
+	}
+}
+
+func TestOptionErrorText(t *testing.T) {
+	c := qt.New(t)
+
+	_, err := ParseString("!poll\nQuestion?\n- Yes\n- "+strings.Repeat("ab", 20)+"\n", DefaultConfig)
+	c.Assert(err, qt.ErrorMatches, "line 4, column 3: option too long: 'abababababababababa…' has 40 characters, "+
+		"counting every emoji or accented letter as one, the max is 32")
+
+	_, err = ParseString("!poll\nQuestion?\n- "+strings.Repeat(familyEmoji, 40)+"\n- B\n", DefaultConfig)
+	c.Assert(err, qt.ErrorMatches, "line 3, column 3: option too long: '"+strings.Repeat(familyEmoji, 19)+"…' has 40 characters, "+
+		"counting every emoji or accented letter as one, the max is 32")
+}
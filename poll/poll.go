@@ -1,15 +1,17 @@
 package poll
 
 import (
-	"bufio"
 	"errors"
 	"fmt"
+	"math"
+	"strconv"
 	"strings"
 	"time"
 )
 
 const (
 	optionPrefix    = "-"
+	percentSuffix   = "%"
 	lineBreakSuffix = "\n"
 )
 
@@ -19,122 +21,498 @@ var DefaultConfig = PollConfig{
 	MinDuration:     time.Hour,
 	MaxDuration:     time.Hour * 8760, // 1 year
 	DefaultDuration: time.Hour * 24,
+	MaxOptionLength: DefaultMaxOptionLength,
 }
 
+// PollConfig defines the limits of the polls and their default duration. The
+// max option length is the max number of characters of every option, zero
+// means no limit. It also contains the templates that the polls can use,
+// indexed by their lowercased name, see Template.
 type PollConfig struct {
 	MinOptions      int
 	MaxOptions      int
 	MinDuration     time.Duration
 	MaxDuration     time.Duration
 	DefaultDuration time.Duration
+	MaxOptionLength int
+	Templates       map[string]*Template
 }
 
-// Poll represents a poll with a question, options and duration
+// timeNow returns the current time, it allows to mock the current time in
+// the tests
+var timeNow = time.Now
+
+// VoteType represents the type of vote of a poll, which defines how many
+// options each voter can choose.
+type VoteType string
+
+const (
+	// VoteTypeSingle allows to choose only one option
+	VoteTypeSingle VoteType = "single"
+	// VoteTypeMultiple allows to choose up to a max number of options
+	VoteTypeMultiple VoteType = "multiple"
+	// VoteTypeApproval allows to approve or not every option
+	VoteTypeApproval VoteType = "approval"
+	// VoteTypeRanked allows to rank up to a max number of options by
+	// preference
+	VoteTypeRanked VoteType = "ranked"
+)
+
+// voteTypes contains the supported names of every vote type
+var voteTypes = map[string]VoteType{
+	"single":          VoteTypeSingle,
+	"single-choice":   VoteTypeSingle,
+	"multiple":        VoteTypeMultiple,
+	"multiple-choice": VoteTypeMultiple,
+	"approval":        VoteTypeApproval,
+	"ranked":          VoteTypeRanked,
+	"ranked-choice":   VoteTypeRanked,
+}
+
+// Poll represents a poll with a question, options, duration, start date and
+// end date. The duration is the time between the start of the poll and its
+// end date. If the start date is zero, the poll starts when it is created. It
+// also includes the type of vote with the max number of options that every
+// voter can choose, and the optional metadata of the poll: description,
+// quorum, and census, which is nil if the default census must be used.
 type Poll struct {
-	Question string
-	Options  []string
-	Duration time.Duration
+	Question      string
+	Options       []string
+	Duration      time.Duration
+	StartDate     time.Time
+	EndDate       time.Time
+	Type          VoteType
+	MaxSelections int
+	Description   string
+	Quorum        Quorum
+	Census        *Census
+}
+
+// Quorum represents the minimum participation required for a poll, as an
+// absolute number of votes or as a percentage of the census. A zero quorum
+// means that no minimum participation is required.
+type Quorum struct {
+	Votes   uint64
+	Percent float64
+}
+
+// IsZero returns if no minimum participation is required.
+func (q Quorum) IsZero() bool {
+	return q.Votes == 0 && q.Percent == 0
+}
+
+func (q Quorum) String() string {
+	if q.Percent > 0 {
+		return strconv.FormatFloat(q.Percent, 'f', -1, 64) + percentSuffix
+	}
+	return strconv.FormatUint(q.Votes, 10)
 }
 
 // ParseString parses a string message and returns a Poll struct with the
-// question, options and duration. The message should follow the format:
+// question, options, duration and metadata. The message should follow the
+// format:
 // !poll
+// <header*>: <value>
 // <question>
 // - <option 1>
 // - <option 2>
 // - <option 3*>
 // - <option 4*>
 // <duration*>
-// The duration is optional and by default is 24 hours. If the message does not
-// follow the format, an error is returned.
+// The headers are optional key-value lines that can be placed anywhere after
+// the command. The supported keys are:
+//   - duration: the duration of the poll, an alternative to the positional
+//     duration after the options.
+//   - starts: the start date of the poll, the poll starts when it is created
+//     if it is not set. The relative durations are counted from it.
+//   - census: the census of the poll, see ParseCensus.
+//   - type: the type of vote, 'single' (by default), 'multiple' with an
+//     optional max number of selections ('multiple 2', all the options by
+//     default), 'approval' or 'ranked' with an optional max number of ranked
+//     options ('ranked 3', all the options by default).
+//   - description: a description of the poll.
+//   - quorum: the minimum participation, as a number of votes ('50') or as a
+//     percentage of the census ('20%').
+//
+// The question can also be set in the command line, optionally followed by
+// the duration and the 'abstain' keyword after its question mark ('!poll
+// Should we merge PR #42? 48h'). If the message has no options, it is a yes/no
+// poll, with an abstain option if the keyword is set or the min number of
+// options of the config requires it. The question can be preceded by the name
+// of a template of the config ('!poll yesno Should we ship v2?'), then the
+// options and the headers of the template are used if the message does not
+// set them. If the question is set in the command line, the text lines after
+// it are the positional duration.
+//
+// The options can also be written in a single line, separated by '|' ('Red |
+// Blue | Green') or numbered ('1) Red 2) Blue 3) Green'), after the question
+// mark that ends the question, in the same line or in the next one ('!poll
+// Which colour? Red | Blue | Green'). They are checked like the options of
+// the dash form.
+//
+// Every option is normalized to the NFC form and it can not be empty, longer
+// than the max option length of the config or the same as another option
+// ignoring the case.
+//
+// The duration is optional and by default is 24 hours. It can be a relative
+// duration ('72h', '3d', '1w 2d', 'P3D') or an absolute end date
+// ('2026-11-01 18:00', 'until friday 18:00 UTC'), see ParseDeadline. The start
+// date accepts the same formats. If the message does not follow the format, an
+// error is returned, which is a *SyntaxError with the position of the problem
+// when it can be located.
 func ParseString(message string, config PollConfig) (*Poll, error) {
+	return ParseStringWithQuestion(message, "", config)
+}
+
+// ParseStringWithQuestion parses a string message like ParseString, but if the
+// message does not include a question, the fallback question provided is
+// used instead. It allows to create polls about the content of another cast.
+func ParseStringWithQuestion(message, fallbackQuestion string, config PollConfig) (*Poll, error) {
 	// create a flag to check if the command has been recognised
 	recognisedCommand := false
-	// create vars to store the question, options and duration
+	// create vars to store the question, options, the positional duration,
+	// the headers and the content of the command line, if any, with its
+	// token
 	var question string
 	var options []string
-	var duration time.Duration = config.DefaultDuration
-	// poll message follows the format:
-	// !poll
-	// <question>
-	// - <option 1>
-	// - <option 2>
-	// - <option 3*>
-	// - <option 4*>
-	// <duration*>
-
-	// create a new reader from the message content and a new scanner from
-	// the reader
-	reader := strings.NewReader(message)
-	scanner := bufio.NewScanner(reader)
-	// read every line from the message
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
-		// if the line is empty, continue
-		if line == "" {
-			continue
+	var durationToken *token
+	headers := map[string]*token{}
+	var inline *inlinePoll
+	var commandToken *token
+	// addOptions adds the options of the given tokens, written in the dash
+	// form or inline, the options are not allowed after the positional
+	// duration, their number is limited by the config and every option is
+	// normalized and checked by the options checker
+	checker := newOptionsChecker(config)
+	addOptions := func(tokens ...*token) error {
+		for _, tok := range tokens {
+			if durationToken != nil {
+				return syntaxError(tok.line, tok.column, ErrUnexpectedOption)
+			}
+			if len(options) >= config.MaxOptions {
+				return syntaxError(tok.line, tok.column, fmt.Errorf("%w: %d", ErrMaxOptionsReached, config.MaxOptions))
+			}
+			option, err := checker.check(tok.value)
+			if err != nil {
+				return syntaxError(tok.line, tok.valueColumn, err)
+			}
+			options = append(options, option)
 		}
-		// if the line contains the command, set the flag and continue
-		if line == "!poll" {
+		return nil
+	}
+	// classify every token of the message
+	tokens := tokenize(message)
+	for i, tok := range tokens {
+		// if the token is the command, set the flag and, if it is followed by
+		// a value, parse the template, question and duration that it includes
+		if tok.kind == tokenCommand {
 			recognisedCommand = true
+			if tok.value == "" {
+				continue
+			}
+			if inline != nil || question != "" || len(options) > 0 {
+				return nil, syntaxError(tok.line, tok.column, ErrUnexpectedText)
+			}
+			var err error
+			if inline, err = parseCommand(tok, config); err != nil {
+				return nil, err
+			}
+			if inline.question != "" {
+				question = inline.question + lineBreakSuffix
+			}
+			if err := addOptions(inline.optionTokens...); err != nil {
+				return nil, err
+			}
+			durationToken = inline.duration
+			commandToken = tok
 			continue
 		}
-		// if the line is not a command, and the command has not been
+		// if the token is not a command, and the command has not been
 		// recognised, return an error
 		if !recognisedCommand {
 			return nil, ErrUnrecognisedCommand
 		}
-		// line is a <question> if:
-		//  - it not starts with a dash
-		//  - any question has been set
-		// line is a <option-n> if:
-		//  - it starts with a dash
-		//  - the question has been set
-		//  - the number of options is less than the max number of options
-		// line is a <duration> if:
-		//  - it not starts with a dash
-		//  - the question has been set
-		//  - at least the min number of options has been set
-		//  - by default, the duration is 24 hours
-		startWithDash := strings.HasPrefix(line, optionPrefix)
-		numOfQuestions := len(options)
-		if !startWithDash {
-			// if the line is a question append it to the question and continue
-			if numOfQuestions == 0 {
-				question += fmt.Sprintf("%s%s", line, lineBreakSuffix)
+		// a header with an invalid value that is followed by the options,
+		// instead of the question, is the question, to keep the questions
+		// that start with a header key, such as 'Type: of fruit you like?'
+		if tok.kind == tokenHeader && question == "" && len(options) == 0 && tok.value != "" &&
+			(i == len(tokens)-1 || tokens[i+1].kind == tokenOption) && checkHeaderValue(tok.key, tok.value) != nil {
+			tok = tok.asText()
+		}
+		switch tok.kind {
+		case tokenHeader:
+			// every header can be set only once, and the duration header is
+			// not compatible with the positional duration
+			_, duplicated := headers[tok.key]
+			if duplicated || (tok.key == headerDuration && durationToken != nil) {
+				return nil, syntaxError(tok.line, tok.column, fmt.Errorf("%w: %s", ErrDuplicatedHeader, tok.key))
+			}
+			if tok.value == "" {
+				return nil, syntaxError(tok.line, tok.valueColumn, fmt.Errorf("%w: %s", ErrEmptyHeaderValue, tok.key))
+			}
+			if err := checkValue(tok); err != nil {
+				return nil, err
+			}
+			headers[tok.key] = tok
+		case tokenOption:
+			if err := addOptions(tok); err != nil {
+				return nil, err
+			}
+		case tokenText:
+			// the line after the question mark that ends the question can
+			// be a list of inline options
+			if len(options) == 0 && durationToken == nil && strings.HasSuffix(question, questionSuffix+lineBreakSuffix) {
+				if optionTokens := splitOptions(tok.value, tok.line, tok.column); optionTokens != nil {
+					if err := addOptions(optionTokens...); err != nil {
+						return nil, err
+					}
+					continue
+				}
+			}
+			// the text before the options is the question, unless the
+			// question has been set in the command line, and it can end
+			// with a question mark followed by the inline options
+			if len(options) == 0 && (inline == nil || inline.question == "") {
+				text, optionTokens := splitQuestion(tok.value, tok.line, tok.column)
+				if optionTokens == nil {
+					question += fmt.Sprintf("%s%s", tok.value, lineBreakSuffix)
+					continue
+				}
+				question += fmt.Sprintf("%s%s", text, lineBreakSuffix)
+				if err := addOptions(optionTokens...); err != nil {
+					return nil, err
+				}
 				continue
 			}
-			// if the line is a duration, try to parse it, if it fails, return
-			// an error, otherwise, break the loop and return the result
-			var err error
-			if duration, err = time.ParseDuration(line); err != nil {
-				return nil, errors.Join(ErrParsingDuration, err)
+			// the text after the options is the positional duration, which
+			// can be set only once and is not compatible with the duration
+			// header
+			if durationToken != nil {
+				return nil, syntaxError(tok.line, tok.column, ErrUnexpectedText)
 			}
-			if duration < config.MinDuration || duration > config.MaxDuration {
-				return nil, fmt.Errorf("duration out of range: %w", ErrParsingDuration)
+			if _, ok := headers[headerDuration]; ok {
+				return nil, syntaxError(tok.line, tok.column, fmt.Errorf("%w: %s", ErrDuplicatedHeader, headerDuration))
 			}
-			break
+			tok.key = headerDuration
+			if err := checkValue(tok); err != nil {
+				return nil, err
+			}
+			durationToken = tok
 		}
-		// if the line is an option and the number of options is greater than
-		// the max number of options, return an error
-		if numOfQuestions >= config.MaxOptions {
-			return nil, fmt.Errorf("%w: %d", ErrMaxOptionsReached, config.MaxOptions)
+	}
+	if !recognisedCommand {
+		return nil, ErrUnrecognisedCommand
+	}
+	// complete the options with the ones of the command line and use the
+	// headers of the template that the message does not set, located at the
+	// command value to report their errors
+	if inline != nil {
+		for _, option := range inline.extraOptions(options, config) {
+			if len(options) >= config.MaxOptions {
+				return nil, syntaxError(commandToken.line, commandToken.valueColumn,
+					fmt.Errorf("%w: %d", ErrMaxOptionsReached, config.MaxOptions))
+			}
+			checked, err := checker.check(option)
+			if err != nil {
+				return nil, syntaxError(commandToken.line, commandToken.valueColumn, err)
+			}
+			options = append(options, checked)
 		}
-		// append the option to the options
-		optionText := strings.TrimSpace(strings.TrimPrefix(line, optionPrefix))
-		options = append(options, optionText)
 	}
-	// check poll content
+	if inline != nil && inline.template != nil {
+		for key, value := range inline.template.headers() {
+			if _, ok := headers[key]; ok || (key == headerDuration && durationToken != nil) {
+				continue
+			}
+			headers[key] = &token{
+				kind:        tokenHeader,
+				line:        commandToken.line,
+				column:      commandToken.column,
+				key:         key,
+				value:       value,
+				valueColumn: commandToken.valueColumn,
+			}
+		}
+	}
+	// check poll content, using the fallback question if no question has been
+	// set
+	if question == "" {
+		question = strings.TrimSpace(fallbackQuestion)
+	}
 	if question == "" {
 		return nil, ErrQuestionNotSet
 	}
 	if len(options) < config.MinOptions {
 		return nil, fmt.Errorf("%w: %d", ErrMinOptionsNotReached, config.MinOptions)
 	}
-	// return the results
-	return &Poll{
+	poll := &Poll{
 		Question: strings.TrimSuffix(question, lineBreakSuffix),
 		Options:  options,
-		Duration: duration,
-	}, nil
+	}
+	if durationToken == nil {
+		durationToken = headers[headerDuration]
+	}
+	if err := poll.setDates(headers[headerStarts], durationToken, config); err != nil {
+		return nil, err
+	}
+	if tok, ok := headers[headerQuorum]; ok {
+		var err error
+		if poll.Quorum, err = parseQuorum(tok.value); err != nil {
+			return nil, syntaxError(tok.line, tok.valueColumn, err)
+		}
+	}
+	if tok, ok := headers[headerDescription]; ok {
+		poll.Description = tok.value
+	}
+	if tok, ok := headers[headerCensus]; ok {
+		var err error
+		if poll.Census, err = ParseCensus(tok.value); err != nil {
+			return nil, syntaxError(tok.line, tok.valueColumn, err)
+		}
+	}
+	poll.Type, poll.MaxSelections = VoteTypeSingle, 1
+	if tok, ok := headers[headerType]; ok {
+		var err error
+		if poll.Type, poll.MaxSelections, err = parseVoteType(tok.value, len(options)); err != nil {
+			return nil, syntaxError(tok.line, tok.valueColumn, err)
+		}
+	}
+	return poll, nil
+}
+
+// setDates parses the start date and the duration or end date of the poll
+// from the given tokens, which can be nil. The start date must be in the
+// future and, if it is set, the relative durations are counted from it. If
+// the duration is not set, the default one of the config is used.
+func (p *Poll) setDates(startToken, durationToken *token, config PollConfig) error {
+	now := timeNow()
+	votingStart := now
+	if startToken != nil {
+		startDate, err := ParseDeadline(startToken.value, now)
+		if err != nil {
+			return syntaxError(startToken.line, startToken.valueColumn, errors.Join(ErrParsingStartDate, err))
+		}
+		if !startDate.After(now) {
+			return syntaxError(startToken.line, startToken.valueColumn,
+				fmt.Errorf("%w: start date must be in the future", ErrParsingStartDate))
+		}
+		p.StartDate = startDate
+		votingStart = startDate
+	}
+	p.EndDate = votingStart.Add(config.DefaultDuration)
+	if durationToken != nil {
+		var err error
+		if p.EndDate, err = ParseDeadline(durationToken.value, votingStart); err != nil {
+			return syntaxError(durationToken.line, durationToken.valueColumn, err)
+		}
+		if err := config.checkDuration(p.EndDate.Sub(votingStart)); err != nil {
+			return syntaxError(durationToken.line, durationToken.valueColumn, err)
+		}
+	}
+	p.Duration = p.EndDate.Sub(votingStart)
+	return nil
+}
+
+// checkValue checks the syntax of the value of the given token according to
+// its key, to report the errors in the order they appear in the message. The
+// values are parsed again once the whole message has been read, because some
+// of them depend on others.
+func checkValue(tok *token) error {
+	if err := checkHeaderValue(tok.key, tok.value); err != nil {
+		return syntaxError(tok.line, tok.valueColumn, err)
+	}
+	return nil
+}
+
+// checkHeaderValue checks the syntax of the given value of a header key.
+func checkHeaderValue(key, value string) error {
+	var err error
+	switch key {
+	case headerDuration:
+		_, err = ParseDeadline(value, timeNow())
+	case headerStarts:
+		if _, err = ParseDeadline(value, timeNow()); err != nil {
+			err = errors.Join(ErrParsingStartDate, err)
+		}
+	case headerQuorum:
+		_, err = parseQuorum(value)
+	case headerCensus:
+		_, err = ParseCensus(value)
+	case headerType:
+		// the number of options is unknown yet, so the max number of
+		// selections is not checked
+		_, _, err = parseVoteType(value, math.MaxInt)
+	}
+	return err
+}
+
+// parseQuorum parses a quorum as a positive number of votes ('50') or as a
+// percentage of the census between 0 and 100 ('20%', '12.5%').
+func parseQuorum(value string) (Quorum, error) {
+	if strings.HasSuffix(value, percentSuffix) {
+		number := strings.TrimSpace(strings.TrimSuffix(value, percentSuffix))
+		percent, err := strconv.ParseFloat(number, 64)
+		if err != nil || percent <= 0 || percent > 100 {
+			return Quorum{}, fmt.Errorf("%w: invalid percentage '%s'", ErrParsingQuorum, value)
+		}
+		return Quorum{Percent: percent}, nil
+	}
+	votes, err := strconv.ParseUint(value, 10, 64)
+	if err != nil || votes == 0 {
+		return Quorum{}, fmt.Errorf("%w: invalid number of votes '%s'", ErrParsingQuorum, value)
+	}
+	return Quorum{Votes: votes}, nil
+}
+
+// parseVoteType parses a vote type and its max number of selections according
+// to the given number of options. The multiple choice and ranked choice types
+// accept a max number of selections between 1 and the number of options,
+// which is the default value. The single choice type allows only one
+// selection and the approval type allows to select every option.
+func parseVoteType(value string, numOptions int) (VoteType, int, error) {
+	fields := strings.Fields(strings.ToLower(value))
+	voteType, ok := voteTypes[fields[0]]
+	if !ok {
+		return "", 0, fmt.Errorf("%w: unknown type '%s'", ErrParsingType, fields[0])
+	}
+	switch voteType {
+	case VoteTypeMultiple, VoteTypeRanked:
+		if len(fields) > 2 {
+			return "", 0, fmt.Errorf("%w: unexpected '%s'", ErrParsingType, strings.Join(fields[2:], " "))
+		}
+		if len(fields) == 1 {
+			return voteType, numOptions, nil
+		}
+		maxSelections, err := strconv.Atoi(fields[1])
+		if err != nil || maxSelections < 1 || maxSelections > numOptions {
+			return "", 0, fmt.Errorf("%w: '%s' must be a number between 1 and the number of options",
+				ErrInvalidMaxSelections, fields[1])
+		}
+		return voteType, maxSelections, nil
+	case VoteTypeApproval:
+		if len(fields) > 1 {
+			return "", 0, fmt.Errorf("%w: unexpected '%s'", ErrParsingType, strings.Join(fields[1:], " "))
+		}
+		return voteType, numOptions, nil
+	default:
+		if len(fields) > 1 {
+			return "", 0, fmt.Errorf("%w: unexpected '%s'", ErrParsingType, strings.Join(fields[1:], " "))
+		}
+		return voteType, 1, nil
+	}
+}
+
+// syntaxError returns a new SyntaxError with the given position and error.
+func syntaxError(line, column int, err error) error {
+	return &SyntaxError{Line: line, Column: column, Err: err}
+}
+
+// checkDuration returns an error if the given duration is out of the range
+// defined by the min and max durations of the config.
+func (c PollConfig) checkDuration(duration time.Duration) error {
+	if duration < c.MinDuration || duration > c.MaxDuration {
+		return fmt.Errorf("%w (%s - %s): %w", ErrDurationOutOfRange,
+			FormatDuration(c.MinDuration), FormatDuration(c.MaxDuration), ErrParsingDuration)
+	}
+	return nil
 }
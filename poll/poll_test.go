@@ -1,6 +1,8 @@
 package poll
 
 import (
+	"errors"
+	"strings"
 	"testing"
 	"time"
 
@@ -41,6 +43,10 @@ What is your favourite colour?
 - Blue
 24
 )
+`
+	noQuestionMessage = `!poll
+- Red
+- Blue
 `
 	nonDefaultDurationMessage = `!poll
 What is your favourite colour?
@@ -100,3 +106,572 @@ func TestParseString(t *testing.T) {
 	_, err = ParseString(invalidDurationMessage, DefaultConfig)
 	c.Assert(err, qt.ErrorIs, ErrParsingDuration)
 }
+
+func TestParseStringWithQuestion(t *testing.T) {
+	c := qt.New(t)
+
+	fallbackQuestion := "Is red better than blue? (by @someone)"
+	fallbackPoll, err := ParseStringWithQuestion(noQuestionMessage, fallbackQuestion, DefaultConfig)
+	c.Assert(err, qt.IsNil)
+	c.Assert(fallbackPoll.Question, qt.Equals, fallbackQuestion)
+	c.Assert(fallbackPoll.Options, qt.ContentEquals, []string{"Red", "Blue"})
+
+	// the question of the message takes precedence over the fallback one
+	correctPoll, err := ParseStringWithQuestion(correctMessage, fallbackQuestion, DefaultConfig)
+	c.Assert(err, qt.IsNil)
+	c.Assert(correctPoll.Question, qt.Equals, expectedCorrectPoll.Question)
+
+	_, err = ParseStringWithQuestion(noQuestionMessage, "", DefaultConfig)
+	c.Assert(err, qt.ErrorIs, ErrQuestionNotSet)
+	_, err = ParseString(noQuestionMessage, DefaultConfig)
+	c.Assert(err, qt.ErrorIs, ErrQuestionNotSet)
+}
+
+func TestParseStringEndDate(t *testing.T) {
+	c := qt.New(t)
+
+	now := time.Date(2026, 10, 21, 10, 0, 0, 0, time.UTC)
+	timeNow = func() time.Time { return now }
+	defer func() { timeNow = time.Now }()
+
+	defaultPoll, err := ParseString(noDurationMessage, DefaultConfig)
+	c.Assert(err, qt.IsNil)
+	c.Assert(defaultPoll.EndDate, qt.Equals, now.Add(DefaultConfig.DefaultDuration))
+	c.Assert(defaultPoll.Duration, qt.Equals, DefaultConfig.DefaultDuration)
+
+	daysPoll, err := ParseString("!poll\nQuestion?\n- Yes\n- No\n3d\n", DefaultConfig)
+	c.Assert(err, qt.IsNil)
+	c.Assert(daysPoll.Duration, qt.Equals, 72*time.Hour)
+	c.Assert(daysPoll.EndDate, qt.Equals, now.Add(72*time.Hour))
+
+	endDatePoll, err := ParseString("!poll\nQuestion?\n- Yes\n- No\nuntil 2026-10-23 18:00 UTC\n", DefaultConfig)
+	c.Assert(err, qt.IsNil)
+	c.Assert(endDatePoll.EndDate, qt.Equals, time.Date(2026, 10, 23, 18, 0, 0, 0, time.UTC))
+	c.Assert(endDatePoll.Duration, qt.Equals, 56*time.Hour)
+
+	_, err = ParseString("!poll\nQuestion?\n- Yes\n- No\n30m\n", DefaultConfig)
+	c.Assert(err, qt.ErrorIs, ErrDurationOutOfRange)
+	_, err = ParseString("!poll\nQuestion?\n- Yes\n- No\n2026-10-20\n", DefaultConfig)
+	c.Assert(err, qt.ErrorIs, ErrDurationOutOfRange)
+	_, err = ParseString("!poll\nQuestion?\n- Yes\n- No\n2y\n", DefaultConfig)
+	c.Assert(err, qt.ErrorIs, ErrParsingDuration)
+}
+
+func TestParseStringStartDate(t *testing.T) {
+	c := qt.New(t)
+
+	now := time.Date(2026, 10, 21, 10, 0, 0, 0, time.UTC)
+	timeNow = func() time.Time { return now }
+	defer func() { timeNow = time.Now }()
+
+	startDate := time.Date(2026, 11, 1, 12, 0, 0, 0, time.UTC)
+	scheduledPoll, err := ParseString("!poll\nQuestion?\n- Yes\n- No\nstarts: 2026-11-01 12:00\n2d\n", DefaultConfig)
+	c.Assert(err, qt.IsNil)
+	c.Assert(scheduledPoll.StartDate, qt.Equals, startDate)
+	c.Assert(scheduledPoll.EndDate, qt.Equals, startDate.Add(48*time.Hour))
+	c.Assert(scheduledPoll.Duration, qt.Equals, 48*time.Hour)
+
+	// the start line can be placed before the question and the default
+	// duration is counted from the start date
+	defaultDurationPoll, err := ParseString("!poll\nStarts: in 1d\nQuestion?\n- Yes\n- No\n", DefaultConfig)
+	c.Assert(err, qt.ErrorIs, ErrParsingStartDate)
+	c.Assert(defaultDurationPoll, qt.IsNil)
+	defaultDurationPoll, err = ParseString("!poll\nStarts: 1d\nQuestion?\n- Yes\n- No\n", DefaultConfig)
+	c.Assert(err, qt.IsNil)
+	c.Assert(defaultDurationPoll.Question, qt.Equals, "Question?")
+	c.Assert(defaultDurationPoll.StartDate, qt.Equals, now.Add(24*time.Hour))
+	c.Assert(defaultDurationPoll.EndDate, qt.Equals, now.Add(24*time.Hour+DefaultConfig.DefaultDuration))
+
+	// absolute end dates must be after the start date
+	_, err = ParseString("!poll\nQuestion?\n- Yes\n- No\nstarts: 2026-11-01 12:00\n2026-10-31\n", DefaultConfig)
+	c.Assert(err, qt.ErrorIs, ErrDurationOutOfRange)
+	// start dates must be in the future
+	_, err = ParseString("!poll\nQuestion?\n- Yes\n- No\nstarts: 2026-10-20\n", DefaultConfig)
+	c.Assert(err, qt.ErrorIs, ErrParsingStartDate)
+
+	// polls without start line start when they are created
+	immediatePoll, err := ParseString(correctMessage, DefaultConfig)
+	c.Assert(err, qt.IsNil)
+	c.Assert(immediatePoll.StartDate.IsZero(), qt.IsTrue)
+}
+
+func TestParseStringHeaders(t *testing.T) {
+	c := qt.New(t)
+
+	now := time.Date(2026, 10, 21, 10, 0, 0, 0, time.UTC)
+	timeNow = func() time.Time { return now }
+	defer func() { timeNow = time.Now }()
+
+	tests := []struct {
+		name     string
+		message  string
+		expected *Poll
+		err      error
+		line     int
+		column   int
+	}{
+		{
+			name:    "legacy format",
+			message: "!poll\nQuestion?\n- Yes\n- No\n3d\n",
+			expected: &Poll{
+				Question:      "Question?",
+				Options:       []string{"Yes", "No"},
+				Duration:      72 * time.Hour,
+				EndDate:       now.Add(72 * time.Hour),
+				Type:          VoteTypeSingle,
+				MaxSelections: 1,
+			},
+		},
+		{
+			name:    "multiline question",
+			message: "!poll\nFirst line\nSecond line\n- Yes\n- No\n",
+			expected: &Poll{
+				Question:      "First line\nSecond line",
+				Options:       []string{"Yes", "No"},
+				Duration:      DefaultConfig.DefaultDuration,
+				EndDate:       now.Add(DefaultConfig.DefaultDuration),
+				Type:          VoteTypeSingle,
+				MaxSelections: 1,
+			},
+		},
+		{
+			name:    "duration header",
+			message: "!poll\nduration: 1w\nQuestion?\n- Yes\n- No\n",
+			expected: &Poll{
+				Question:      "Question?",
+				Options:       []string{"Yes", "No"},
+				Duration:      7 * 24 * time.Hour,
+				EndDate:       now.Add(7 * 24 * time.Hour),
+				Type:          VoteTypeSingle,
+				MaxSelections: 1,
+			},
+		},
+		{
+			name: "every header",
+			message: "!poll\nDescription: Weekly ship or no-ship\nType: single\nCensus: followers\n" +
+				"Quorum: 20%\nStarts: 2026-11-01 12:00\nDuration: 2d\nShould we ship?\n- Ship\n- No ship\n",
+			expected: &Poll{
+				Question:      "Should we ship?",
+				Options:       []string{"Ship", "No ship"},
+				Duration:      48 * time.Hour,
+				StartDate:     time.Date(2026, 11, 1, 12, 0, 0, 0, time.UTC),
+				EndDate:       time.Date(2026, 11, 3, 12, 0, 0, 0, time.UTC),
+				Type:          VoteTypeSingle,
+				MaxSelections: 1,
+				Description:   "Weekly ship or no-ship",
+				Quorum:        Quorum{Percent: 20},
+				Census:        &Census{Type: CensusTypeFollowers},
+			},
+		},
+		{
+			name:    "headers after the options",
+			message: "!poll\nQuestion?\n- Yes\n- No\nquorum: 50\ndescription: after options\n",
+			expected: &Poll{
+				Question:      "Question?",
+				Options:       []string{"Yes", "No"},
+				Duration:      DefaultConfig.DefaultDuration,
+				EndDate:       now.Add(DefaultConfig.DefaultDuration),
+				Type:          VoteTypeSingle,
+				MaxSelections: 1,
+				Description:   "after options",
+				Quorum:        Quorum{Votes: 50},
+			},
+		},
+		{
+			name:    "headers after the positional duration",
+			message: "!poll\nQuestion?\n- Yes\n- No\n2d\nquorum: 50\n",
+			expected: &Poll{
+				Question:      "Question?",
+				Options:       []string{"Yes", "No"},
+				Duration:      48 * time.Hour,
+				EndDate:       now.Add(48 * time.Hour),
+				Type:          VoteTypeSingle,
+				MaxSelections: 1,
+				Quorum:        Quorum{Votes: 50},
+			},
+		},
+		{
+			name:    "unknown keys are part of the question",
+			message: "!poll\nNote: this is a question?\n- Yes\n- No\n",
+			expected: &Poll{
+				Question:      "Note: this is a question?",
+				Options:       []string{"Yes", "No"},
+				Duration:      DefaultConfig.DefaultDuration,
+				EndDate:       now.Add(DefaultConfig.DefaultDuration),
+				Type:          VoteTypeSingle,
+				MaxSelections: 1,
+			},
+		},
+		{
+			name:    "question starting with the type key",
+			message: "!poll\nType: of fruit you like?\n- Apple\n- Pear\n",
+			expected: &Poll{
+				Question:      "Type: of fruit you like?",
+				Options:       []string{"Apple", "Pear"},
+				Duration:      DefaultConfig.DefaultDuration,
+				EndDate:       now.Add(DefaultConfig.DefaultDuration),
+				Type:          VoteTypeSingle,
+				MaxSelections: 1,
+			},
+		},
+		{
+			name:    "question starting with the duration key",
+			message: "!poll\nDuration: how long should meetings be?\n- 30m\n- 1h\n",
+			expected: &Poll{
+				Question:      "Duration: how long should meetings be?",
+				Options:       []string{"30m", "1h"},
+				Duration:      DefaultConfig.DefaultDuration,
+				EndDate:       now.Add(DefaultConfig.DefaultDuration),
+				Type:          VoteTypeSingle,
+				MaxSelections: 1,
+			},
+		},
+		{
+			name:    "indented lines and spaces around the colon",
+			message: "  !poll\n  duration :  2d \n\tQuestion?\n  - Yes\n  -No\n",
+			expected: &Poll{
+				Question:      "Question?",
+				Options:       []string{"Yes", "No"},
+				Duration:      48 * time.Hour,
+				EndDate:       now.Add(48 * time.Hour),
+				Type:          VoteTypeSingle,
+				MaxSelections: 1,
+			},
+		},
+		{
+			name:    "text before the command",
+			message: "Hello\n!poll\nQuestion?\n- Yes\n- No\n",
+			err:     ErrUnrecognisedCommand,
+		},
+		{
+			name:    "no command",
+			message: "Question?\n- Yes\n- No\n",
+			err:     ErrUnrecognisedCommand,
+		},
+		{
+			name:    "empty message",
+			message: "",
+			err:     ErrUnrecognisedCommand,
+		},
+		{
+			name:    "no question",
+			message: "!poll\nduration: 2d\n- Yes\n- No\n",
+			err:     ErrQuestionNotSet,
+		},
+		{
+			name:    "not enough options",
+			message: "!poll\nQuestion?\n- Yes\n",
+			err:     ErrMinOptionsNotReached,
+		},
+		{
+			name:    "too many options",
+			message: "!poll\nQuestion?\n- A\n- B\n- C\n- D\n  - E\n",
+			err:     ErrMaxOptionsReached,
+			line:    7,
+			column:  3,
+		},
+		{
+			name:    "invalid positional duration",
+			message: "!poll\nQuestion?\n- Yes\n- No\nsoon\n",
+			err:     ErrParsingDuration,
+			line:    5,
+			column:  1,
+		},
+		{
+			name:    "invalid duration header",
+			message: "!poll\nduration: soon\nQuestion?\n- Yes\n- No\n",
+			err:     ErrParsingDuration,
+			line:    2,
+			column:  11,
+		},
+		{
+			name:    "unsupported duration unit",
+			message: "!poll\nQuestion?\n- Yes\n- No\nduration: 2y\n",
+			err:     ErrParsingDuration,
+			line:    5,
+			column:  11,
+		},
+		{
+			name:    "duration above the maximum",
+			message: "!poll\nQuestion?\n- Yes\n- No\nduration: 400d\n",
+			err:     ErrDurationOutOfRange,
+			line:    5,
+			column:  11,
+		},
+		{
+			name:    "duration below the minimum",
+			message: "!poll\nQuestion?\n- Yes\n- No\nduration: 10m\n",
+			err:     ErrDurationOutOfRange,
+			line:    5,
+			column:  11,
+		},
+		{
+			name:    "invalid start date",
+			message: "!poll\nQuestion?\nstarts: whenever\n- Yes\n- No\n",
+			err:     ErrParsingStartDate,
+			line:    3,
+			column:  9,
+		},
+		{
+			name:    "start date in the past",
+			message: "!poll\nQuestion?\n- Yes\n- No\nstarts: 2026-10-01\n",
+			err:     ErrParsingStartDate,
+			line:    5,
+			column:  9,
+		},
+		{
+			name:    "invalid quorum",
+			message: "!poll\nQuestion?\n- Yes\n- No\nquorum: many\n",
+			err:     ErrParsingQuorum,
+			line:    5,
+			column:  9,
+		},
+		{
+			name:    "invalid quorum percentage",
+			message: "!poll\nQuestion?\n- Yes\n- No\nquorum: 120%\n",
+			err:     ErrParsingQuorum,
+			line:    5,
+			column:  9,
+		},
+		{
+			name:    "empty header",
+			message: "!poll\nQuestion?\ncensus:\n- Yes\n- No\n",
+			err:     ErrEmptyHeaderValue,
+			line:    3,
+			column:  8,
+		},
+		{
+			name:    "duplicated header",
+			message: "!poll\ntype: single\nQuestion?\n- Yes\n- No\ntype: approval\n",
+			err:     ErrDuplicatedHeader,
+			line:    6,
+			column:  1,
+		},
+		{
+			name:    "duration header and positional duration",
+			message: "!poll\nduration: 2d\nQuestion?\n- Yes\n- No\n3d\n",
+			err:     ErrDuplicatedHeader,
+			line:    6,
+			column:  1,
+		},
+		{
+			name:    "positional duration and duration header",
+			message: "!poll\nQuestion?\n- Yes\n- No\n3d\nduration: 2d\n",
+			err:     ErrDuplicatedHeader,
+			line:    6,
+			column:  1,
+		},
+		{
+			name:    "option after the duration",
+			message: "!poll\nQuestion?\n- Yes\n- No\n3d\n- Maybe\n",
+			err:     ErrUnexpectedOption,
+			line:    6,
+			column:  1,
+		},
+		{
+			name:    "text after the duration",
+			message: "!poll\nQuestion?\n- Yes\n- No\n3d\nthanks!\n",
+			err:     ErrUnexpectedText,
+			line:    6,
+			column:  1,
+		},
+		{
+			name:    "columns count runes",
+			message: "!poll\nQuestion?\n- Yes\n- No\n🗳️ quorum\n",
+			err:     ErrParsingDuration,
+			line:    5,
+			column:  1,
+		},
+	}
+	for _, test := range tests {
+		c.Run(test.name, func(c *qt.C) {
+			poll, err := ParseString(test.message, DefaultConfig)
+			if test.err != nil {
+				c.Assert(err, qt.ErrorIs, test.err)
+				c.Assert(poll, qt.IsNil)
+				syntaxErr := &SyntaxError{}
+				if test.line == 0 {
+					c.Assert(errors.As(err, &syntaxErr), qt.IsFalse)
+					return
+				}
+				c.Assert(errors.As(err, &syntaxErr), qt.IsTrue)
+				c.Assert(syntaxErr.Line, qt.Equals, test.line)
+				c.Assert(syntaxErr.Column, qt.Equals, test.column)
+				return
+			}
+			c.Assert(err, qt.IsNil)
+			c.Assert(poll, qt.DeepEquals, test.expected)
+		})
+	}
+}
+
+func TestTokenize(t *testing.T) {
+	c := qt.New(t)
+
+	tokens := tokenize("  !poll\n\nduration:  3d\n  ¿Qué?\n -  Sí\n!poll  yesno Ship?\n!pollster\n")
+	c.Assert(tokens, qt.HasLen, 6)
+	c.Assert(*tokens[0], qt.Equals, token{kind: tokenCommand, line: 1, column: 3, value: "", text: "!poll", valueColumn: 8})
+	c.Assert(*tokens[1], qt.Equals, token{kind: tokenHeader, line: 3, column: 1, key: "duration", value: "3d", text: "duration:  3d", valueColumn: 12})
+	c.Assert(*tokens[2], qt.Equals, token{kind: tokenText, line: 4, column: 3, value: "¿Qué?", text: "¿Qué?", valueColumn: 3})
+	c.Assert(*tokens[3], qt.Equals, token{kind: tokenOption, line: 5, column: 2, value: "Sí", text: "-  Sí", valueColumn: 5})
+	c.Assert(*tokens[4], qt.Equals, token{kind: tokenCommand, line: 6, column: 1, value: "yesno Ship?", text: "!poll  yesno Ship?", valueColumn: 8})
+	c.Assert(*tokens[5], qt.Equals, token{kind: tokenText, line: 7, column: 1, value: "!pollster", text: "!pollster", valueColumn: 1})
+}
+
+func TestParseStringVoteType(t *testing.T) {
+	c := qt.New(t)
+
+	tests := []struct {
+		name          string
+		header        string
+		voteType      VoteType
+		maxSelections int
+		err           error
+	}{
+		{name: "default", header: "", voteType: VoteTypeSingle, maxSelections: 1},
+		{name: "single", header: "type: single", voteType: VoteTypeSingle, maxSelections: 1},
+		{name: "single choice alias", header: "type: Single-Choice", voteType: VoteTypeSingle, maxSelections: 1},
+		{name: "multiple with max", header: "type: multiple 2", voteType: VoteTypeMultiple, maxSelections: 2},
+		{name: "multiple without max", header: "type: multiple", voteType: VoteTypeMultiple, maxSelections: 4},
+		{name: "multiple choice alias", header: "type: multiple-choice 3", voteType: VoteTypeMultiple, maxSelections: 3},
+		{name: "approval", header: "type: approval", voteType: VoteTypeApproval, maxSelections: 4},
+		{name: "ranked", header: "type: ranked", voteType: VoteTypeRanked, maxSelections: 4},
+		{name: "ranked with max", header: "type: ranked-choice 3", voteType: VoteTypeRanked, maxSelections: 3},
+		{name: "ranked with invalid max", header: "type: ranked 6", err: ErrInvalidMaxSelections},
+		{name: "unknown type", header: "type: quadratic", err: ErrParsingType},
+		{name: "single with max", header: "type: single 2", err: ErrParsingType},
+		{name: "approval with max", header: "type: approval 2", err: ErrParsingType},
+		{name: "multiple with extra fields", header: "type: multiple 2 3", err: ErrParsingType},
+		{name: "multiple with invalid max", header: "type: multiple two", err: ErrInvalidMaxSelections},
+		{name: "multiple with zero max", header: "type: multiple 0", err: ErrInvalidMaxSelections},
+		{name: "multiple with max above options", header: "type: multiple 5", err: ErrInvalidMaxSelections},
+	}
+	for _, test := range tests {
+		c.Run(test.name, func(c *qt.C) {
+			message := "!poll\n" + test.header + "\nPick up to 2 of these 4\n- A\n- B\n- C\n- D\n"
+			poll, err := ParseString(message, DefaultConfig)
+			if test.err != nil {
+				c.Assert(err, qt.ErrorIs, test.err)
+				return
+			}
+			c.Assert(err, qt.IsNil)
+			c.Assert(poll.Type, qt.Equals, test.voteType)
+			c.Assert(poll.MaxSelections, qt.Equals, test.maxSelections)
+		})
+	}
+}
+
+func TestParseCensus(t *testing.T) {
+	c := qt.New(t)
+
+	contract := "0x4ed4E862860beD51a9570b96d89aF5E1B0Efefed"
+	address := "0x71C7656EC7ab88b098defB751B7401B5f6d8976F"
+	tests := []struct {
+		name     string
+		value    string
+		expected *Census
+		err      error
+	}{
+		{name: "followers", value: "followers", expected: &Census{Type: CensusTypeFollowers}},
+		{name: "followers with args", value: "followers 10", err: ErrParsingCensus},
+		{name: "current channel", value: "channel", expected: &Census{Type: CensusTypeChannel}},
+		{
+			name:     "channel by name",
+			value:    "Channel /vocdoni",
+			expected: &Census{Type: CensusTypeChannel, Channel: "https://warpcast.com/~/channel/vocdoni"},
+		},
+		{
+			name:     "channel by url",
+			value:    "channel https://warpcast.com/~/channel/vocdoni",
+			expected: &Census{Type: CensusTypeChannel, Channel: "https://warpcast.com/~/channel/vocdoni"},
+		},
+		{name: "invalid channel", value: "channel vo$doni", err: ErrParsingCensus},
+		{
+			name:     "erc20",
+			value:    "erc20 " + contract,
+			expected: &Census{Type: CensusTypeERC20, Contract: strings.ToLower(contract), Network: DefaultNetwork},
+		},
+		{
+			name:  "weighted erc20 on base",
+			value: "ERC20 " + contract + " base weighted",
+			expected: &Census{
+				Type:     CensusTypeERC20,
+				Contract: strings.ToLower(contract),
+				Network:  "base",
+				Weighted: true,
+			},
+		},
+		{
+			name:  "weighted nft",
+			value: "nft " + contract + " weighted",
+			expected: &Census{
+				Type:     CensusTypeNFT,
+				Contract: strings.ToLower(contract),
+				Network:  DefaultNetwork,
+				Weighted: true,
+			},
+		},
+		{name: "token without contract", value: "erc20", err: ErrParsingCensus},
+		{name: "token with invalid contract", value: "nft 0x1234", err: ErrParsingCensus},
+		{name: "token with unknown network", value: "erc20 " + contract + " solana", err: ErrParsingCensus},
+		{name: "token with duplicated network", value: "erc20 " + contract + " base polygon", err: ErrParsingCensus},
+		{
+			name:     "fids",
+			value:    "fids 1, 2 3,2",
+			expected: &Census{Type: CensusTypeFIDs, FIDs: []uint64{1, 2, 3}},
+		},
+		{name: "no fids", value: "fids", err: ErrParsingCensus},
+		{name: "invalid fid", value: "fids 1 two", err: ErrParsingCensus},
+		{name: "zero fid", value: "fids 0", err: ErrParsingCensus},
+		{
+			name:     "addresses",
+			value:    "addresses " + address + ", " + strings.ToLower(address),
+			expected: &Census{Type: CensusTypeAddresses, Addresses: []string{strings.ToLower(address)}},
+		},
+		{name: "invalid address", value: "addresses " + address + " 0xzz", err: ErrParsingCensus},
+		{
+			name:     "inline usernames",
+			value:    "@Alice, @bob.eth @alice",
+			expected: &Census{Type: CensusTypeUsernames, Usernames: []string{"alice", "bob.eth"}},
+		},
+		{
+			name:     "usernames with type",
+			value:    "usernames @alice bob",
+			expected: &Census{Type: CensusTypeUsernames, Usernames: []string{"alice", "bob"}},
+		},
+		{name: "no usernames", value: "usernames", err: ErrParsingCensus},
+		{name: "invalid username", value: "@alice @b!ob", err: ErrParsingCensus},
+		{
+			name:     "file url",
+			value:    "https://example.com/census.csv",
+			expected: &Census{Type: CensusTypeFile, URL: "https://example.com/census.csv"},
+		},
+		{
+			name:     "file with url",
+			value:    "file https://example.com/census.json",
+			expected: &Census{Type: CensusTypeFile, URL: "https://example.com/census.json"},
+		},
+		{name: "embedded file", value: "file", expected: &Census{Type: CensusTypeFile}},
+		{name: "file with invalid url", value: "file census.csv", err: ErrParsingCensus},
+		{name: "unknown type", value: "everyone", err: ErrParsingCensus},
+	}
+	for _, test := range tests {
+		c.Run(test.name, func(c *qt.C) {
+			census, err := ParseCensus(test.value)
+			if test.err != nil {
+				c.Assert(err, qt.ErrorIs, test.err)
+				return
+			}
+			c.Assert(err, qt.IsNil)
+			c.Assert(census, qt.DeepEquals, test.expected)
+		})
+	}
+
+	c.Run("census header", func(c *qt.C) {
+		_, err := ParseString("!poll\ncensus: nft 0x1234\nQuestion?\n- Yes\n- No\n", DefaultConfig)
+		c.Assert(err, qt.ErrorIs, ErrParsingCensus)
+		var syntaxErr *SyntaxError
+		c.Assert(errors.As(err, &syntaxErr), qt.IsTrue)
+		c.Assert(syntaxErr.Line, qt.Equals, 2)
+	})
+}
@@ -0,0 +1,122 @@
+package poll
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Template represents a named set of defaults for the polls that are created
+// often with the same options or settings. A poll uses a template when the
+// name of the template follows the command in the first line of its message,
+// such as '!poll yesno Should we ship v2?'. The options of the template are
+// used if the message does not include any (a yes/no poll if the template
+// does not include any either), and the duration, census, type,
+// description and quorum are used as the value of the headers that are not
+// set in the message. They follow the same format as the headers.
+type Template struct {
+	Name        string   `json:"name" yaml:"name"`
+	Options     []string `json:"options" yaml:"options"`
+	Duration    string   `json:"duration" yaml:"duration"`
+	Census      string   `json:"census" yaml:"census"`
+	Type        string   `json:"type" yaml:"type"`
+	Description string   `json:"description" yaml:"description"`
+	Quorum      string   `json:"quorum" yaml:"quorum"`
+}
+
+type templatesFile struct {
+	Templates []*Template `json:"templates" yaml:"templates"`
+}
+
+// LoadTemplates reads the poll templates from the file in the given path and
+// returns them indexed by their lowercased name, ready to be set in the
+// PollConfig. The file can be a YAML file, if its extension is '.yaml' or
+// '.yml', or a JSON file otherwise, and should follow the format:
+//
+//	templates:
+//	  - name: yesno
+//	    options: ["Yes", "No", "Abstain"]
+//	    duration: 2d
+//	  - name: ship
+//	    options: ["Ship", "No ship"]
+//	    duration: until friday 18:00 UTC
+//	    census: channel
+//
+// The values of every template are checked with the same rules as the poll
+// headers, so errors are reported when the file is loaded.
+func LoadTemplates(path string) (map[string]*Template, error) {
+	body, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Join(ErrReadingTemplates, err)
+	}
+	file := &templatesFile{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(body, file)
+	default:
+		err = json.Unmarshal(body, file)
+	}
+	if err != nil {
+		return nil, errors.Join(ErrInvalidTemplate, err)
+	}
+	templates := make(map[string]*Template, len(file.Templates))
+	for _, t := range file.Templates {
+		if err := t.check(); err != nil {
+			return nil, err
+		}
+		name := strings.ToLower(strings.TrimSpace(t.Name))
+		if _, ok := templates[name]; ok {
+			return nil, fmt.Errorf("%w: duplicated name %s", ErrInvalidTemplate, name)
+		}
+		templates[name] = t
+	}
+	return templates, nil
+}
+
+// check returns an error if the name of the template is not a single word or
+// any of its options or values is not valid.
+func (t *Template) check() error {
+	if len(strings.Fields(t.Name)) != 1 {
+		return fmt.Errorf("%w: the name must be a single word: '%s'", ErrInvalidTemplate, t.Name)
+	}
+	for _, option := range t.Options {
+		if strings.TrimSpace(option) == "" {
+			return fmt.Errorf("%w: empty option in %s", ErrInvalidTemplate, t.Name)
+		}
+	}
+	for key, value := range t.headers() {
+		if err := checkHeaderValue(key, value); err != nil {
+			return fmt.Errorf("%w: invalid %s in %s: %w", ErrInvalidTemplate, key, t.Name, err)
+		}
+	}
+	return nil
+}
+
+// headers returns the values of the template that are set indexed by the
+// header key that they replace.
+func (t *Template) headers() map[string]string {
+	headers := map[string]string{}
+	for key, value := range map[string]string{
+		headerDuration:    t.Duration,
+		headerCensus:      t.Census,
+		headerType:        t.Type,
+		headerDescription: t.Description,
+		headerQuorum:      t.Quorum,
+	} {
+		if value = strings.TrimSpace(value); value != "" {
+			headers[key] = value
+		}
+	}
+	return headers
+}
+
+// template returns the template of the config with the given name, ignoring
+// the case, or nil if there is no template with that name.
+func (c PollConfig) template(name string) *Template {
+	return c.Templates[strings.ToLower(name)]
+}
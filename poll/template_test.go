@@ -0,0 +1,245 @@
+package poll
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+)
+
+const templatesYAML = `templates:
+  - name: YesNo
+    options: ["Yes", "No", "Abstain"]
+    duration: 2d
+  - name: ship
+    options: ["Ship", "No ship"]
+    census: channel
+    type: single
+    description: Weekly release poll
+  - name: weekly
+    duration: 1w
+    quorum: 20%
+`
+
+func TestLoadTemplates(t *testing.T) {
+	c := qt.New(t)
+
+	dir := t.TempDir()
+	yamlPath := filepath.Join(dir, "templates.yml")
+	c.Assert(os.WriteFile(yamlPath, []byte(templatesYAML), 0o600), qt.IsNil)
+	templates, err := LoadTemplates(yamlPath)
+	c.Assert(err, qt.IsNil)
+	c.Assert(templates, qt.HasLen, 3)
+	c.Assert(templates["yesno"], qt.DeepEquals, &Template{
+		Name:     "YesNo",
+		Options:  []string{"Yes", "No", "Abstain"},
+		Duration: "2d",
+	})
+	c.Assert(templates["ship"].Census, qt.Equals, "channel")
+	c.Assert(templates["weekly"].Options, qt.HasLen, 0)
+
+	jsonPath := filepath.Join(dir, "templates.json")
+	c.Assert(os.WriteFile(jsonPath, []byte(`{"templates":[{"name":"yesno","options":["Yes","No"]}]}`), 0o600), qt.IsNil)
+	templates, err = LoadTemplates(jsonPath)
+	c.Assert(err, qt.IsNil)
+	c.Assert(templates["yesno"].Options, qt.DeepEquals, []string{"Yes", "No"})
+
+	_, err = LoadTemplates(filepath.Join(dir, "missing.json"))
+	c.Assert(err, qt.ErrorIs, ErrReadingTemplates)
+
+	for name, content := range map[string]string{
+		"invalid json":     `{`,
+		"no name":          `{"templates":[{"options":["Yes","No"]}]}`,
+		"name with spaces": `{"templates":[{"name":"yes no"}]}`,
+		"duplicated name":  `{"templates":[{"name":"yesno"},{"name":"YESNO"}]}`,
+		"empty option":     `{"templates":[{"name":"yesno","options":["Yes"," "]}]}`,
+		"invalid duration": `{"templates":[{"name":"yesno","duration":"soon"}]}`,
+		"invalid census":   `{"templates":[{"name":"yesno","census":"followers 10"}]}`,
+		"invalid type":     `{"templates":[{"name":"yesno","type":"quadratic"}]}`,
+	} {
+		c.Assert(os.WriteFile(jsonPath, []byte(content), 0o600), qt.IsNil)
+		_, err = LoadTemplates(jsonPath)
+		c.Assert(err, qt.ErrorIs, ErrInvalidTemplate, qt.Commentf(name))
+	}
+}
+
+func TestParseStringTemplate(t *testing.T) {
+	c := qt.New(t)
+
+	now := time.Date(2026, 10, 21, 10, 0, 0, 0, time.UTC)
+	timeNow = func() time.Time { return now }
+	defer func() { timeNow = time.Now }()
+
+	config := DefaultConfig
+	config.Templates = map[string]*Template{
+		"yesno": {Name: "yesno", Options: []string{"Yes", "No", "Abstain"}, Duration: "2d"},
+		"ship": {
+			Name:        "ship",
+			Options:     []string{"Ship", "No ship"},
+			Census:      "channel",
+			Type:        "multiple",
+			Description: "Weekly release poll",
+		},
+		"weekly": {Name: "weekly", Duration: "1w", Quorum: "20%"},
+		"big":    {Name: "big", Options: []string{"A", "B", "C", "D", "E"}},
+	}
+
+	tests := []struct {
+		name     string
+		message  string
+		expected *Poll
+		err      error
+		line     int
+		column   int
+	}{
+		{
+			name:    "template defaults",
+			message: "!poll yesno Should we ship v2?",
+			expected: &Poll{
+				Question:      "Should we ship v2?",
+				Options:       []string{"Yes", "No", "Abstain"},
+				Duration:      48 * time.Hour,
+				EndDate:       now.Add(48 * time.Hour),
+				Type:          VoteTypeSingle,
+				MaxSelections: 1,
+			},
+		},
+		{
+			name:    "case insensitive name and positional duration",
+			message: "!poll  YesNo Should we ship v2?\n3d\n",
+			expected: &Poll{
+				Question:      "Should we ship v2?",
+				Options:       []string{"Yes", "No", "Abstain"},
+				Duration:      72 * time.Hour,
+				EndDate:       now.Add(72 * time.Hour),
+				Type:          VoteTypeSingle,
+				MaxSelections: 1,
+			},
+		},
+		{
+			name:    "headers override the template",
+			message: "!poll ship Ship v2?\ncensus: followers\ntype: single\n",
+			expected: &Poll{
+				Question:      "Ship v2?",
+				Options:       []string{"Ship", "No ship"},
+				Duration:      DefaultConfig.DefaultDuration,
+				EndDate:       now.Add(DefaultConfig.DefaultDuration),
+				Type:          VoteTypeSingle,
+				MaxSelections: 1,
+				Description:   "Weekly release poll",
+				Census:        &Census{Type: CensusTypeFollowers},
+			},
+		},
+		{
+			name:    "template census and type",
+			message: "!poll ship Ship v2?",
+			expected: &Poll{
+				Question:      "Ship v2?",
+				Options:       []string{"Ship", "No ship"},
+				Duration:      DefaultConfig.DefaultDuration,
+				EndDate:       now.Add(DefaultConfig.DefaultDuration),
+				Type:          VoteTypeMultiple,
+				MaxSelections: 2,
+				Description:   "Weekly release poll",
+				Census:        &Census{Type: CensusTypeChannel},
+			},
+		},
+		{
+			name:    "options of the message",
+			message: "!poll weekly Which feature next?\n- Frames\n- Charts\n",
+			expected: &Poll{
+				Question:      "Which feature next?",
+				Options:       []string{"Frames", "Charts"},
+				Duration:      7 * 24 * time.Hour,
+				EndDate:       now.Add(7 * 24 * time.Hour),
+				Type:          VoteTypeSingle,
+				MaxSelections: 1,
+				Quorum:        Quorum{Percent: 20},
+			},
+		},
+		{
+			name:    "question in the next line",
+			message: "!poll yesno\nShould we ship v2?\n",
+			expected: &Poll{
+				Question:      "Should we ship v2?",
+				Options:       []string{"Yes", "No", "Abstain"},
+				Duration:      48 * time.Hour,
+				EndDate:       now.Add(48 * time.Hour),
+				Type:          VoteTypeSingle,
+				MaxSelections: 1,
+			},
+		},
+		{
+			name:    "unknown template",
+			message: "!poll maybe Should we ship v2?\n- Yes\n- No\n",
+			expected: &Poll{
+				Question:      "maybe Should we ship v2?",
+				Options:       []string{"Yes", "No"},
+				Duration:      DefaultConfig.DefaultDuration,
+				EndDate:       now.Add(DefaultConfig.DefaultDuration),
+				Type:          VoteTypeSingle,
+				MaxSelections: 1,
+			},
+		},
+		{
+			name:    "no question",
+			message: "!poll yesno",
+			err:     ErrQuestionNotSet,
+		},
+		{
+			name:    "template without options",
+			message: "!poll weekly Ship v2?",
+			expected: &Poll{
+				Question:      "Ship v2?",
+				Options:       []string{"Yes", "No"},
+				Duration:      7 * 24 * time.Hour,
+				EndDate:       now.Add(7 * 24 * time.Hour),
+				Type:          VoteTypeSingle,
+				MaxSelections: 1,
+				Quorum:        Quorum{Percent: 20},
+			},
+		},
+		{
+			name:    "template without options and question",
+			message: "!poll weekly\nWhich feature next?\n",
+			err:     ErrMinOptionsNotReached,
+		},
+		{
+			name:    "too many template options",
+			message: "!poll big Which one?",
+			err:     ErrMaxOptionsReached,
+			line:    1,
+			column:  7,
+		},
+		{
+			name:    "duration header and positional duration",
+			message: "!poll yesno Ship?\n3d\nduration: 2d\n",
+			err:     ErrDuplicatedHeader,
+			line:    3,
+			column:  1,
+		},
+	}
+	for _, test := range tests {
+		c.Run(test.name, func(c *qt.C) {
+			poll, err := ParseString(test.message, config)
+			if test.err != nil {
+				c.Assert(err, qt.ErrorIs, test.err)
+				c.Assert(poll, qt.IsNil)
+				syntaxErr := &SyntaxError{}
+				if test.line == 0 {
+					c.Assert(errors.As(err, &syntaxErr), qt.IsFalse)
+					return
+				}
+				c.Assert(errors.As(err, &syntaxErr), qt.IsTrue)
+				c.Assert(syntaxErr.Line, qt.Equals, test.line)
+				c.Assert(syntaxErr.Column, qt.Equals, test.column)
+				return
+			}
+			c.Assert(err, qt.IsNil)
+			c.Assert(poll, qt.DeepEquals, test.expected)
+		})
+	}
+}
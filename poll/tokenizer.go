@@ -0,0 +1,143 @@
+package poll
+
+import (
+	"bufio"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// tokenType represents the type of a line of a poll message
+type tokenType int
+
+const (
+	// tokenCommand is a line with the poll command, optionally followed by
+	// a value such as the name of a template
+	tokenCommand tokenType = iota
+	// tokenHeader is a line with a known key followed by a colon and a value,
+	// such as 'duration: 3d'
+	tokenHeader
+	// tokenOption is a line starting with a dash followed by an option
+	tokenOption
+	// tokenText is any other non-empty line, such as the question or the
+	// positional duration
+	tokenText
+)
+
+const (
+	// poll command
+	pollCommand = "!poll"
+	// header keys
+	headerDuration    = "duration"
+	headerStarts      = "starts"
+	headerCensus      = "census"
+	headerType        = "type"
+	headerDescription = "description"
+	headerQuorum      = "quorum"
+)
+
+var (
+	// knownHeaders contains the header keys supported by the poll grammar,
+	// the lines with other keys are considered text
+	knownHeaders = map[string]bool{
+		headerDuration:    true,
+		headerStarts:      true,
+		headerCensus:      true,
+		headerType:        true,
+		headerDescription: true,
+		headerQuorum:      true,
+	}
+	// headerRgx matches a key followed by a colon and an optional value
+	headerRgx = regexp.MustCompile(`^([A-Za-z]+)\s*:\s*`)
+)
+
+// token represents a non-empty line of a poll message with its type, its
+// position (1-based line and column of the first non-space character) and
+// its content. For headers, the key is lowercased and the value is the text
+// after the colon. For commands, the value is the text after the command. For
+// options and text, the value is the text of the option or the line. The
+// value column is the column where the value starts, and the text is the
+// whole line without surrounding spaces.
+type token struct {
+	kind        tokenType
+	line        int
+	column      int
+	key         string
+	value       string
+	valueColumn int
+	text        string
+}
+
+// tokenize splits the given message in lines and returns a token for every
+// non-empty one.
+func tokenize(message string) []*token {
+	tokens := []*token{}
+	scanner := bufio.NewScanner(strings.NewReader(message))
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		raw := scanner.Text()
+		line := strings.TrimSpace(raw)
+		if line == "" {
+			continue
+		}
+		// calculate the column of the first non-space character in runes
+		column := utf8.RuneCountInString(raw[:strings.Index(raw, line)]) + 1
+		tok := &token{
+			kind:        tokenText,
+			line:        lineNumber,
+			column:      column,
+			value:       line,
+			valueColumn: column,
+			text:        line,
+		}
+		switch {
+		case line == pollCommand:
+			tok.kind = tokenCommand
+			tok.value, tok.valueColumn = "", column+utf8.RuneCountInString(line)
+		case strings.HasPrefix(line, pollCommand) && unicode.IsSpace(rune(line[len(pollCommand)])):
+			tok.kind = tokenCommand
+			tok.value, tok.valueColumn = trimValue(line, len(pollCommand), column)
+		case strings.HasPrefix(line, optionPrefix):
+			tok.kind = tokenOption
+			tok.value, tok.valueColumn = trimValue(line, len(optionPrefix), column)
+		default:
+			match := headerRgx.FindStringSubmatch(line)
+			if match == nil || !knownHeaders[strings.ToLower(match[1])] {
+				break
+			}
+			tok.kind = tokenHeader
+			tok.key = strings.ToLower(match[1])
+			tok.value, tok.valueColumn = trimValue(line, len(match[0]), column)
+		}
+		tokens = append(tokens, tok)
+	}
+	return tokens
+}
+
+// asText returns the given token as a text token with the whole line as its
+// value, for the lines that look like a header but are not.
+func (t *token) asText() *token {
+	return &token{
+		kind:        tokenText,
+		line:        t.line,
+		column:      t.column,
+		value:       t.text,
+		valueColumn: t.column,
+		text:        t.text,
+	}
+}
+
+// trimValue returns the value of a line that starts at the given byte offset,
+// without surrounding spaces, and its column, calculated from the column of
+// the line.
+func trimValue(line string, offset, column int) (string, int) {
+	rest := line[offset:]
+	value := strings.TrimSpace(rest)
+	if value == "" {
+		return "", column + utf8.RuneCountInString(line)
+	}
+	skipped := line[:offset+strings.Index(rest, value)]
+	return value, column + utf8.RuneCountInString(skipped)
+}
@@ -0,0 +1,83 @@
+package results
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/vocdoni/votebot/election"
+)
+
+const (
+	// barWidth is the number of blocks of the bars of the text charts
+	barWidth = 8
+	// filledBlock and emptyBlock are the characters used to draw the bars
+	filledBlock = "▓"
+	emptyBlock  = "░"
+)
+
+// BarChart composes a compact text with the current results to be published
+// in a cast: the question, the number of votes, the remaining time to vote,
+// and a bar per option with its percentage. A zero or negative remaining time
+// means that the voting has ended. The question and the option names are
+// shortened if needed to fit the text in MaxTextLength bytes.
+func (s *Summary) BarChart(remaining time.Duration) string {
+	for _, maxLength := range []int{64, 32, 16, 8} {
+		if text := s.barChart(remaining, maxLength); len(text) <= MaxTextLength {
+			return text
+		}
+	}
+	return shorten(s.barChart(remaining, 8), MaxTextLength)
+}
+
+// barChart composes the text chart shortening the question to twice the
+// given length and the option names to the given length.
+func (s *Summary) barChart(remaining time.Duration, maxLength int) string {
+	status := "ended"
+	if remaining > 0 {
+		status = remainingText(remaining) + " left"
+	}
+	lines := []string{
+		fmt.Sprintf("📊 %s", shorten(s.Question, maxLength*2)),
+		fmt.Sprintf("%s · %s", votesText(s.VoteCount), status),
+	}
+	for _, option := range s.Options {
+		lines = append(lines, fmt.Sprintf("%s %s %s",
+			bar(option.Percentage), percentageText(option.Percentage), shorten(option.Name, maxLength)))
+	}
+	if s.VoteType == election.VoteTypeRanked {
+		lines = append(lines, fmt.Sprintf("(%s)", s.rankedText()))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// bar draws a bar of barWidth blocks filled according to the given
+// percentage, rounded to the nearest block.
+func bar(percentage float64) string {
+	filled := int(percentage*barWidth/100 + 0.5)
+	filled = max(0, min(filled, barWidth))
+	return strings.Repeat(filledBlock, filled) + strings.Repeat(emptyBlock, barWidth-filled)
+}
+
+// remainingText returns the given remaining time rounded down to the two
+// largest units among days, hours and minutes ('2d 5h', '3h 10m', '45m'),
+// or '<1m' if it is less than a minute.
+func remainingText(remaining time.Duration) string {
+	days := int(remaining / (24 * time.Hour))
+	hours := int(remaining % (24 * time.Hour) / time.Hour)
+	minutes := int(remaining % time.Hour / time.Minute)
+	switch {
+	case days > 0 && hours > 0:
+		return fmt.Sprintf("%dd %dh", days, hours)
+	case days > 0:
+		return fmt.Sprintf("%dd", days)
+	case hours > 0 && minutes > 0:
+		return fmt.Sprintf("%dh %dm", hours, minutes)
+	case hours > 0:
+		return fmt.Sprintf("%dh", hours)
+	case minutes > 0:
+		return fmt.Sprintf("%dm", minutes)
+	default:
+		return "<1m"
+	}
+}
@@ -0,0 +1,8 @@
+package results
+
+import "fmt"
+
+var (
+	ErrNoOptions       = fmt.Errorf("no options")
+	ErrUnknownVoteType = fmt.Errorf("unknown vote type")
+)
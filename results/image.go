@@ -0,0 +1,291 @@
+package results
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"strings"
+	"time"
+
+	"github.com/vocdoni/votebot/election"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+)
+
+const (
+	// imageWidth is the width in pixels of the results images, their height
+	// depends on the number of options
+	imageWidth = 800
+	// imagePadding is the space in pixels around the content of the images
+	imagePadding = 40
+	// maxTitleLines is the max number of lines of the question in the images
+	maxTitleLines = 2
+	// titleSize, optionSize and detailsSize are the font sizes in points of
+	// the question, the option names and the rest of the texts
+	titleSize   = 32
+	optionSize  = 22
+	detailsSize = 18
+	// optionHeight is the height in pixels of every option row, with its
+	// name, votes and bar
+	optionHeight = 72
+	// barHeight is the height in pixels of the option bars
+	barHeight = 16
+	// imageDateLayout is the layout used to show the end date in the images
+	imageDateLayout = "2006-01-02 15:04 UTC"
+)
+
+var (
+	backgroundColor = color.RGBA{0xf8, 0xf7, 0xfc, 0xff}
+	textColor       = color.RGBA{0x1a, 0x1a, 0x2e, 0xff}
+	detailsColor    = color.RGBA{0x5c, 0x5a, 0x70, 0xff}
+	trackColor      = color.RGBA{0xe5, 0xe1, 0xf0, 0xff}
+	barColor        = color.RGBA{0xa8, 0x93, 0xe0, 0xff}
+	winnerColor     = color.RGBA{0x6b, 0x46, 0xc1, 0xff}
+)
+
+// imageFaces contains the font faces used to render a results image.
+type imageFaces struct {
+	title   font.Face
+	option  font.Face
+	details font.Face
+}
+
+// newImageFaces creates the font faces of a results image from the embedded
+// Go fonts. The faces are not safe for concurrent use, so they are created
+// for every image.
+func newImageFaces() (*imageFaces, error) {
+	regular, err := opentype.Parse(goregular.TTF)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing regular font: %w", err)
+	}
+	bold, err := opentype.Parse(gobold.TTF)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing bold font: %w", err)
+	}
+	faces := &imageFaces{}
+	for _, f := range []struct {
+		face *font.Face
+		font *opentype.Font
+		size float64
+	}{
+		{&faces.title, bold, titleSize},
+		{&faces.option, bold, optionSize},
+		{&faces.details, regular, detailsSize},
+	} {
+		if *f.face, err = opentype.NewFace(f.font, &opentype.FaceOptions{
+			Size:    f.size,
+			DPI:     72,
+			Hinting: font.HintingFull,
+		}); err != nil {
+			return nil, fmt.Errorf("error creating font face: %w", err)
+		}
+	}
+	return faces, nil
+}
+
+// Image renders the results as a bar chart image: the question, the number
+// of votes, the given end date of the voting, if it is not zero, and a row
+// per option with its name, votes, percentage and bar. The bars of the
+// winners are highlighted.
+func (s *Summary) Image(endDate time.Time) (image.Image, error) {
+	return s.render(endDate, true)
+}
+
+// BallotImage renders an image with the given question and options of a
+// poll, numbered and without votes, and the given end date of the voting, if
+// it is not zero. It allows to present a poll before showing its results.
+func BallotImage(question string, options []string, endDate time.Time) (image.Image, error) {
+	summary := &Summary{Question: question, Options: make([]*Option, len(options))}
+	for i, name := range options {
+		summary.Options[i] = &Option{Name: fmt.Sprintf("%d. %s", i+1, name)}
+	}
+	return summary.render(endDate, false)
+}
+
+// render draws the image of the results, with the votes and the bars of the
+// options only if showVotes is set.
+func (s *Summary) render(endDate time.Time, showVotes bool) (image.Image, error) {
+	faces, err := newImageFaces()
+	if err != nil {
+		return nil, err
+	}
+	contentWidth := imageWidth - 2*imagePadding
+	titleLines := wrapText(faces.title, s.Question, contentWidth, maxTitleLines)
+	titleHeight := faces.title.Metrics().Height.Ceil()
+	detailsHeight := faces.details.Metrics().Height.Ceil()
+	// calculate the height of the image from the number of title lines and
+	// options
+	height := imagePadding + len(titleLines)*titleHeight + detailsHeight +
+		imagePadding/2 + len(s.Options)*optionHeight + imagePadding/2
+	if s.VoteType == election.VoteTypeRanked {
+		height += detailsHeight
+	}
+	img := image.NewRGBA(image.Rect(0, 0, imageWidth, height))
+	draw.Draw(img, img.Bounds(), image.NewUniform(backgroundColor), image.Point{}, draw.Src)
+	// draw the question and the details of the voting
+	y := imagePadding
+	for _, line := range titleLines {
+		y += titleHeight
+		drawText(img, faces.title, textColor, imagePadding, y-faces.title.Metrics().Descent.Ceil(), line)
+	}
+	details := s.details(endDate)
+	if !showVotes {
+		details = ballotDetails(endDate)
+	}
+	y += detailsHeight
+	drawText(img, faces.details, detailsColor, imagePadding, y-faces.details.Metrics().Descent.Ceil(), details)
+	y += imagePadding / 2
+	// draw a row for every option, with its name on the left, its votes and
+	// percentage on the right and its bar below them
+	winners := map[int]bool{}
+	for _, winner := range s.Winners() {
+		winners[winner] = true
+	}
+	for i, option := range s.Options {
+		baseline := y + optionHeight - barHeight - imagePadding/2 - faces.option.Metrics().Descent.Ceil()
+		if !showVotes {
+			drawText(img, faces.option, textColor, imagePadding, baseline, shortenToWidth(faces.option, option.Name, contentWidth))
+			y += optionHeight
+			continue
+		}
+		votes := fmt.Sprintf("%s · %s", votesText(option.Votes), percentageText(option.Percentage))
+		votesWidth := font.MeasureString(faces.details, votes).Ceil()
+		name := shortenToWidth(faces.option, option.Name, contentWidth-votesWidth-imagePadding/2)
+		drawText(img, faces.option, textColor, imagePadding, baseline, name)
+		drawText(img, faces.details, detailsColor, imageWidth-imagePadding-votesWidth, baseline, votes)
+		barTop := y + optionHeight - barHeight - imagePadding/4
+		track := image.Rect(imagePadding, barTop, imageWidth-imagePadding, barTop+barHeight)
+		draw.Draw(img, track, image.NewUniform(trackColor), image.Point{}, draw.Src)
+		fill := track
+		fill.Max.X = track.Min.X + int(float64(track.Dx())*option.Percentage/100+0.5)
+		fillColor := barColor
+		if winners[i] {
+			fillColor = winnerColor
+		}
+		draw.Draw(img, fill.Intersect(track), image.NewUniform(fillColor), image.Point{}, draw.Src)
+		y += optionHeight
+	}
+	if s.VoteType == election.VoteTypeRanked {
+		y += detailsHeight
+		drawText(img, faces.details, detailsColor, imagePadding, y-faces.details.Metrics().Descent.Ceil(),
+			"Votes counted as "+s.rankedText())
+	}
+	return img, nil
+}
+
+// PNG renders the results image, see Image, and returns it encoded as PNG.
+func (s *Summary) PNG(endDate time.Time) ([]byte, error) {
+	img, err := s.Image(endDate)
+	if err != nil {
+		return nil, err
+	}
+	return encodePNG(img)
+}
+
+// BallotPNG renders the ballot image, see BallotImage, and returns it encoded
+// as PNG.
+func BallotPNG(question string, options []string, endDate time.Time) ([]byte, error) {
+	img, err := BallotImage(question, options, endDate)
+	if err != nil {
+		return nil, err
+	}
+	return encodePNG(img)
+}
+
+// encodePNG returns the given image encoded as PNG.
+func encodePNG(img image.Image) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	if err := png.Encode(buf, img); err != nil {
+		return nil, fmt.Errorf("error encoding image: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// details composes the line of the image with the number of votes, the
+// turnout if the census size is known and the end date if it is not zero.
+func (s *Summary) details(endDate time.Time) string {
+	parts := []string{}
+	if s.Final {
+		parts = append(parts, "Final results")
+	}
+	parts = append(parts, votesText(s.VoteCount))
+	if s.CensusSize > 0 {
+		parts = append(parts, fmt.Sprintf("%s turnout", percentageText(s.Turnout())))
+	}
+	if !endDate.IsZero() {
+		label := "Ends"
+		if s.Final {
+			label = "Ended"
+		}
+		parts = append(parts, fmt.Sprintf("%s %s", label, endDate.UTC().Format(imageDateLayout)))
+	}
+	return strings.Join(parts, " · ")
+}
+
+// ballotDetails composes the line of the ballot image with the end date of
+// the voting if it is not zero.
+func ballotDetails(endDate time.Time) string {
+	if endDate.IsZero() {
+		return "Choose an option to vote"
+	}
+	return fmt.Sprintf("Choose an option to vote · Ends %s", endDate.UTC().Format(imageDateLayout))
+}
+
+// drawText draws the given text in the image with the given face and color,
+// starting at the given x and baseline y coordinates.
+func drawText(img draw.Image, face font.Face, c color.Color, x, y int, text string) {
+	drawer := &font.Drawer{
+		Dst:  img,
+		Src:  image.NewUniform(c),
+		Face: face,
+		Dot:  fixed.P(x, y),
+	}
+	drawer.DrawString(text)
+}
+
+// wrapText splits the given text into lines that fit in the given width with
+// the given face, up to the given max number of lines. The last line is
+// shortened if the text does not fit in them.
+func wrapText(face font.Face, text string, width, maxLines int) []string {
+	lines := []string{}
+	line := ""
+	words := strings.Fields(text)
+	for i, word := range words {
+		candidate := strings.TrimSpace(line + " " + word)
+		if line == "" || font.MeasureString(face, candidate).Ceil() <= width {
+			line = candidate
+			continue
+		}
+		if len(lines) == maxLines-1 {
+			// the last line gets the rest of the words to be shortened
+			line = strings.Join(append([]string{line}, words[i:]...), " ")
+			break
+		}
+		lines = append(lines, line)
+		line = word
+	}
+	return append(lines, shortenToWidth(face, line, width))
+}
+
+// shortenToWidth returns the given text cut to fit in the given width with
+// the given face, with an ellipsis appended if it is cut.
+func shortenToWidth(face font.Face, text string, width int) string {
+	if font.MeasureString(face, text).Ceil() <= width {
+		return text
+	}
+	runes := []rune(text)
+	for len(runes) > 0 {
+		runes = runes[:len(runes)-1]
+		shortened := strings.TrimSpace(string(runes)) + ellipsis
+		if font.MeasureString(face, shortened).Ceil() <= width {
+			return shortened
+		}
+	}
+	return ellipsis
+}
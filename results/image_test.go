@@ -0,0 +1,129 @@
+package results
+
+import (
+	"bytes"
+	"flag"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/vocdoni/votebot/election"
+)
+
+// update allows to regenerate the golden images of the tests with
+// 'go test ./results -update'
+var update = flag.Bool("update", false, "update the golden images")
+
+func TestImage(t *testing.T) {
+	c := qt.New(t)
+
+	endDate := time.Date(2026, 10, 21, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		question string
+		options  []string
+		voteType string
+		results  *election.Results
+		endDate  time.Time
+	}{
+		{
+			name:     "ongoing",
+			question: "Ship it?",
+			options:  []string{"Yes", "No"},
+			voteType: election.VoteTypeSingle,
+			results:  &election.Results{Tally: [][]uint64{{2, 1}}, VoteCount: 3},
+			endDate:  endDate,
+		},
+		{
+			name:     "final",
+			question: "What should we build next for the community of farcaster voters and their friends?",
+			options: []string{
+				"Frames",
+				"Polls with a very long option name that does not fit in the image at all",
+				"Bots",
+			},
+			voteType: election.VoteTypeSingle,
+			results:  &election.Results{Tally: [][]uint64{{5, 3, 1}}, VoteCount: 9, CensusSize: 20, Final: true},
+			endDate:  endDate,
+		},
+		{
+			name:     "ranked-tie",
+			question: "Rank the colors",
+			options:  []string{"Red", "Green", "Blue", "Yellow"},
+			voteType: election.VoteTypeRanked,
+			results: &election.Results{
+				Tally:     [][]uint64{{1, 2, 1, 1, 0}, {1, 2, 1, 1, 0}, {2, 1, 1, 1, 0}, {5, 0, 0, 0, 0}},
+				VoteCount: 5,
+			},
+		},
+		{
+			name:     "no-votes",
+			question: "Anybody?",
+			options:  []string{"Yes", "No"},
+			voteType: election.VoteTypeApproval,
+			results:  &election.Results{Tally: [][]uint64{{0, 0}, {0, 0}}, Final: true},
+			endDate:  endDate,
+		},
+	}
+	for _, test := range tests {
+		summary, err := New(test.question, test.options, test.voteType, test.results)
+		c.Assert(err, qt.IsNil, qt.Commentf(test.name))
+		data, err := summary.PNG(test.endDate)
+		c.Assert(err, qt.IsNil, qt.Commentf(test.name))
+		assertGolden(c, data, test.name)
+	}
+}
+
+func TestBallotImage(t *testing.T) {
+	c := qt.New(t)
+
+	endDate := time.Date(2026, 10, 21, 12, 0, 0, 0, time.UTC)
+	data, err := BallotPNG("What should we build next?", []string{"Frames", "Polls", "Bots"}, endDate)
+	c.Assert(err, qt.IsNil)
+	assertGolden(c, data, "ballot")
+}
+
+// assertGolden checks that the given PNG image is the same as the golden
+// image with the given name, or updates the golden image if the update flag
+// is set.
+func assertGolden(c *qt.C, data []byte, name string) {
+	golden := filepath.Join("testdata", name+".png")
+	if *update {
+		c.Assert(os.WriteFile(golden, data, 0o644), qt.IsNil)
+		return
+	}
+	expected, err := os.ReadFile(golden)
+	c.Assert(err, qt.IsNil, qt.Commentf(name))
+	// compare the pixels instead of the bytes, which depend on the encoder
+	assertSameImage(c, data, expected, name)
+}
+
+// assertSameImage checks that the given PNG images have the same size and
+// pixels.
+func assertSameImage(c *qt.C, data, expected []byte, name string) {
+	img, err := png.Decode(bytes.NewReader(data))
+	c.Assert(err, qt.IsNil, qt.Commentf(name))
+	expectedImg, err := png.Decode(bytes.NewReader(expected))
+	c.Assert(err, qt.IsNil, qt.Commentf(name))
+	c.Assert(img.Bounds(), qt.Equals, expectedImg.Bounds(), qt.Commentf(name))
+	bounds := img.Bounds()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			if !sameColor(img, expectedImg, x, y) {
+				c.Fatalf("%s: pixel (%d, %d) differs from the golden image", name, x, y)
+			}
+		}
+	}
+}
+
+// sameColor returns if the pixels of the given images at the given
+// coordinates have the same color.
+func sameColor(a, b image.Image, x, y int) bool {
+	ar, ag, ab, aa := a.At(x, y).RGBA()
+	br, bg, bb, ba := b.At(x, y).RGBA()
+	return ar == br && ag == bg && ab == bb && aa == ba
+}
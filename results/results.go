@@ -0,0 +1,291 @@
+package results
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/vocdoni/votebot/election"
+	"github.com/vocdoni/votebot/tally"
+)
+
+// MaxTextLength is the max length in bytes of the texts composed to publish
+// the results, which must fit in a cast
+const MaxTextLength = 320
+
+// ellipsis is appended to the texts that are shortened to fit in a cast
+const ellipsis = "…"
+
+// Option contains the votes received by an option of a poll and their
+// percentage over the number of voters.
+type Option struct {
+	Name       string
+	Votes      uint64
+	Percentage float64
+}
+
+// Summary contains the results of a poll, the votes of every option, the
+// number of voters and the size of the census, which is zero if it is not
+// known. Final is set when the results will not change anymore. Rounds is the
+// number of rounds of the instant-runoff of a ranked choice poll, or zero if
+// its votes are the first preferences.
+type Summary struct {
+	Question   string
+	VoteType   string
+	Options    []*Option
+	VoteCount  uint64
+	CensusSize uint64
+	Final      bool
+	Rounds     int
+}
+
+// New creates the summary of the results of a poll with the given question,
+// options and vote type from the tally of its election, which follows the
+// election ballot mode (see election.ElectionOptions.BallotMode):
+//   - single choice: the votes of every option.
+//   - multiple choice: the votes of every option in any of the fields.
+//   - approval: the approvals of every option.
+//   - ranked choice: the votes of every option in the last round of the
+//     instant-runoff of the ballots, or its first preferences if the backend
+//     does not keep the ballots.
+//
+// It returns an error if there are no options or the vote type is unknown.
+func New(question string, options []string, voteType string, res *election.Results) (*Summary, error) {
+	if len(options) == 0 {
+		return nil, ErrNoOptions
+	}
+	summary := &Summary{
+		Question:   question,
+		VoteType:   voteType,
+		Options:    make([]*Option, len(options)),
+		VoteCount:  res.VoteCount,
+		CensusSize: res.CensusSize,
+		Final:      res.Final,
+	}
+	for i, name := range options {
+		summary.Options[i] = &Option{Name: name}
+	}
+	switch voteType {
+	case "", election.VoteTypeSingle:
+		if len(res.Tally) > 0 {
+			summary.addVotes(res.Tally[0])
+		}
+	case election.VoteTypeRanked:
+		if err := summary.addRanked(res); err != nil {
+			return nil, err
+		}
+	case election.VoteTypeMultiple:
+		for _, field := range res.Tally {
+			summary.addVotes(field)
+		}
+	case election.VoteTypeApproval:
+		for i, field := range res.Tally {
+			if i < len(summary.Options) && len(field) > 1 {
+				summary.Options[i].Votes += field[1]
+			}
+		}
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownVoteType, voteType)
+	}
+	if summary.VoteCount > 0 {
+		for _, option := range summary.Options {
+			option.Percentage = float64(option.Votes) * 100 / float64(summary.VoteCount)
+		}
+	}
+	return summary, nil
+}
+
+// addVotes adds the votes of a field of the tally, indexed by option, to the
+// options of the summary, ignoring the values out of range.
+func (s *Summary) addVotes(field []uint64) {
+	for i, votes := range field {
+		if i < len(s.Options) {
+			s.Options[i].Votes += votes
+		}
+	}
+}
+
+// addRanked adds the votes of a ranked choice poll, whose ballots contain the
+// position of every option in the ranking: the votes of the last round of
+// the instant-runoff of the ballots, or the first preferences of every option
+// from the tally if there are no ballots.
+func (s *Summary) addRanked(res *election.Results) error {
+	if res.Ballots == nil {
+		for i, field := range res.Tally {
+			if i < len(s.Options) && len(field) > 1 {
+				s.Options[i].Votes += field[1]
+			}
+		}
+		return nil
+	}
+	ballots := make([]tally.Ballot, len(res.Ballots))
+	for i, positions := range res.Ballots {
+		ballots[i] = rankedBallot(positions)
+	}
+	result, err := tally.InstantRunoff(len(s.Options), ballots)
+	if errors.Is(err, tally.ErrNoValidBallots) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error counting the ranked choice ballots: %w", err)
+	}
+	s.Rounds = len(result.Rounds)
+	for i, votes := range result.Rounds[len(result.Rounds)-1].Votes {
+		s.Options[i].Votes = votes
+	}
+	return nil
+}
+
+// rankedBallot returns the options of a ballot that contains the position of
+// every option in the ranking, ordered by preference. The unranked options
+// are skipped, and if a position is repeated, the ballot contains an invalid
+// option so it is discarded by the tally.
+func rankedBallot(positions []int) tally.Ballot {
+	byPosition := make([]int, len(positions)+1)
+	for i := range byPosition {
+		byPosition[i] = tally.NoWinner
+	}
+	for option, position := range positions {
+		if position == 0 {
+			continue
+		}
+		if position < 0 || position >= len(byPosition) || byPosition[position] != tally.NoWinner {
+			return tally.Ballot{tally.NoWinner}
+		}
+		byPosition[position] = option
+	}
+	ballot := tally.Ballot{}
+	for _, option := range byPosition {
+		if option != tally.NoWinner {
+			ballot = append(ballot, option)
+		}
+	}
+	return ballot
+}
+
+// rankedText returns how the votes of a ranked choice poll are counted, by
+// instant-runoff or as first preferences.
+func (s *Summary) rankedText() string {
+	switch s.Rounds {
+	case 0:
+		return "first preferences"
+	case 1:
+		return "instant-runoff, 1 round"
+	default:
+		return fmt.Sprintf("instant-runoff, %d rounds", s.Rounds)
+	}
+}
+
+// Winners returns the indexes of the options with the most votes, more than
+// one if there is a tie. It returns an empty list if nobody has voted.
+func (s *Summary) Winners() []int {
+	winners := []int{}
+	var max uint64
+	for i, option := range s.Options {
+		switch {
+		case option.Votes == 0 || option.Votes < max:
+			continue
+		case option.Votes > max:
+			max = option.Votes
+			winners = winners[:0]
+		}
+		winners = append(winners, i)
+	}
+	return winners
+}
+
+// Turnout returns the percentage of the census that has voted, or zero if
+// the size of the census is not known.
+func (s *Summary) Turnout() float64 {
+	if s.CensusSize == 0 {
+		return 0
+	}
+	return float64(s.VoteCount) * 100 / float64(s.CensusSize)
+}
+
+// Text composes a text with the results to be published in a cast: the
+// question, the votes and percentage of every option, the winner and the
+// turnout. The question and the option names are shortened if needed to fit
+// the text in MaxTextLength bytes.
+func (s *Summary) Text() string {
+	// try with decreasing lengths of the question and the option names until
+	// the text fits
+	for _, maxLength := range []int{128, 64, 32, 16, 8} {
+		if text := s.text(maxLength); len(text) <= MaxTextLength {
+			return text
+		}
+	}
+	return shorten(s.text(8), MaxTextLength)
+}
+
+// text composes the text of the results shortening the question to twice
+// the given length and the option names to the given length.
+func (s *Summary) text(maxLength int) string {
+	lines := []string{}
+	title := "📊 Results"
+	if s.Final {
+		title = "📊 Final results"
+	}
+	lines = append(lines, fmt.Sprintf("%s: %s", title, shorten(s.Question, maxLength*2)))
+	for i, option := range s.Options {
+		lines = append(lines, fmt.Sprintf("%d. %s: %s (%s)", i+1,
+			shorten(option.Name, maxLength), votesText(option.Votes), percentageText(option.Percentage)))
+	}
+	if s.VoteType == election.VoteTypeRanked {
+		lines = append(lines, fmt.Sprintf("(%s)", s.rankedText()))
+	}
+	switch winners := s.Winners(); {
+	case s.VoteCount == 0:
+		lines = append(lines, "Nobody voted 😶")
+	case len(winners) == 1:
+		label := "🏆 Leading"
+		if s.Final {
+			label = "🏆 Winner"
+		}
+		lines = append(lines, fmt.Sprintf("%s: %s", label, shorten(s.Options[winners[0]].Name, maxLength)))
+	case len(winners) > 1:
+		names := make([]string, len(winners))
+		for i, winner := range winners {
+			names[i] = shorten(s.Options[winner].Name, maxLength)
+		}
+		lines = append(lines, fmt.Sprintf("🤝 Tie: %s", strings.Join(names, ", ")))
+	}
+	if s.CensusSize > 0 {
+		lines = append(lines, fmt.Sprintf("👥 Turnout: %d of %d (%s)", s.VoteCount, s.CensusSize, percentageText(s.Turnout())))
+	} else if s.VoteCount > 0 {
+		lines = append(lines, fmt.Sprintf("👥 Voters: %d", s.VoteCount))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// votesText returns the given number of votes with the right noun.
+func votesText(votes uint64) string {
+	if votes == 1 {
+		return "1 vote"
+	}
+	return fmt.Sprintf("%d votes", votes)
+}
+
+// percentageText returns the given percentage rounded to one decimal, without
+// it if the percentage is a whole number.
+func percentageText(percentage float64) string {
+	text := fmt.Sprintf("%.1f", percentage)
+	return strings.TrimSuffix(text, ".0") + "%"
+}
+
+// shorten returns the given text cut to the given max length in bytes,
+// ellipsis included, without splitting any rune.
+func shorten(text string, maxLength int) string {
+	if len(text) <= maxLength {
+		return text
+	}
+	cut := maxLength - len(ellipsis)
+	for cut > 0 && !utf8.RuneStart(text[cut]) {
+		cut--
+	}
+	if cut <= 0 {
+		return ellipsis
+	}
+	return strings.TrimSpace(text[:cut]) + ellipsis
+}
@@ -0,0 +1,206 @@
+package results
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/vocdoni/votebot/election"
+	"github.com/vocdoni/votebot/tally"
+)
+
+func TestNew(t *testing.T) {
+	c := qt.New(t)
+
+	tests := []struct {
+		name     string
+		voteType string
+		tally    [][]uint64
+		votes    []uint64
+		winners  []int
+	}{
+		{
+			name:     "single choice",
+			voteType: election.VoteTypeSingle,
+			tally:    [][]uint64{{3, 1, 0}},
+			votes:    []uint64{3, 1, 0},
+			winners:  []int{0},
+		},
+		{
+			name:     "multiple choice",
+			voteType: election.VoteTypeMultiple,
+			tally:    [][]uint64{{2, 1, 1}, {0, 1, 2}},
+			votes:    []uint64{2, 2, 3},
+			winners:  []int{2},
+		},
+		{
+			name:     "approval",
+			voteType: election.VoteTypeApproval,
+			tally:    [][]uint64{{1, 3}, {2, 2}, {1, 3}},
+			votes:    []uint64{3, 2, 3},
+			winners:  []int{0, 2},
+		},
+		{
+			name:     "ranked choice",
+			voteType: election.VoteTypeRanked,
+			tally:    [][]uint64{{2, 1, 1, 0}, {1, 2, 1, 0}, {1, 1, 2, 0}},
+			votes:    []uint64{1, 2, 1},
+			winners:  []int{1},
+		},
+		{
+			name:     "empty tally",
+			voteType: election.VoteTypeSingle,
+			tally:    [][]uint64{},
+			votes:    []uint64{0, 0, 0},
+			winners:  []int{},
+		},
+	}
+	for _, test := range tests {
+		summary, err := New("Question?", []string{"A", "B", "C"}, test.voteType, &election.Results{
+			Tally:     test.tally,
+			VoteCount: 4,
+		})
+		c.Assert(err, qt.IsNil, qt.Commentf(test.name))
+		votes := []uint64{}
+		for _, option := range summary.Options {
+			votes = append(votes, option.Votes)
+		}
+		c.Assert(votes, qt.DeepEquals, test.votes, qt.Commentf(test.name))
+		c.Assert(summary.Winners(), qt.DeepEquals, test.winners, qt.Commentf(test.name))
+	}
+
+	_, err := New("Question?", nil, election.VoteTypeSingle, &election.Results{})
+	c.Assert(err, qt.ErrorIs, ErrNoOptions)
+	_, err = New("Question?", []string{"A"}, "unknown", &election.Results{})
+	c.Assert(err, qt.ErrorIs, ErrUnknownVoteType)
+}
+
+func TestNewRanked(t *testing.T) {
+	c := qt.New(t)
+
+	// the ballots contain the position of every option: A and B are the
+	// favourites of two voters, C of one, who prefers A to B, and the last
+	// ballot repeats a position, so it is discarded
+	summary, err := New("Question?", []string{"A", "B", "C"}, election.VoteTypeRanked, &election.Results{
+		Tally:     [][]uint64{{2, 2, 2, 0}, {2, 3, 0, 1}, {1, 1, 4, 0}},
+		Ballots:   [][]int{{1, 0, 2}, {1, 0, 2}, {0, 1, 2}, {0, 1, 2}, {2, 3, 1}, {1, 1, 0}},
+		VoteCount: 6,
+		Final:     true,
+	})
+	c.Assert(err, qt.IsNil)
+	// C is eliminated in the first round and A wins the second one
+	c.Assert(summary.Rounds, qt.Equals, 2)
+	votes := []uint64{}
+	for _, option := range summary.Options {
+		votes = append(votes, option.Votes)
+	}
+	c.Assert(votes, qt.DeepEquals, []uint64{3, 2, 0})
+	c.Assert(summary.Winners(), qt.DeepEquals, []int{0})
+	c.Assert(summary.Text(), qt.Contains, "(instant-runoff, 2 rounds)")
+
+	// without ballots, the first preferences of the tally are reported
+	summary, err = New("Question?", []string{"A", "B", "C"}, election.VoteTypeRanked, &election.Results{
+		Tally:     [][]uint64{{2, 2, 2, 0}, {2, 3, 0, 1}, {1, 1, 4, 0}},
+		VoteCount: 6,
+	})
+	c.Assert(err, qt.IsNil)
+	c.Assert(summary.Rounds, qt.Equals, 0)
+	c.Assert(summary.Options[1].Votes, qt.Equals, uint64(3))
+	c.Assert(summary.Text(), qt.Contains, "(first preferences)")
+}
+
+func TestRankedBallot(t *testing.T) {
+	c := qt.New(t)
+
+	c.Assert(rankedBallot([]int{2, 0, 1}), qt.DeepEquals, tally.Ballot{2, 0})
+	c.Assert(rankedBallot([]int{3, 1, 0}), qt.DeepEquals, tally.Ballot{1, 0})
+	c.Assert(rankedBallot([]int{0, 0, 0}), qt.DeepEquals, tally.Ballot{})
+	c.Assert(rankedBallot([]int{1, 1, 0}), qt.DeepEquals, tally.Ballot{tally.NoWinner})
+	c.Assert(rankedBallot([]int{4, 1, 0}), qt.DeepEquals, tally.Ballot{tally.NoWinner})
+}
+
+func TestText(t *testing.T) {
+	c := qt.New(t)
+
+	summary, err := New("Ship it?", []string{"Yes", "No"}, election.VoteTypeSingle, &election.Results{
+		Tally:      [][]uint64{{2, 1}},
+		VoteCount:  3,
+		CensusSize: 10,
+		Final:      true,
+	})
+	c.Assert(err, qt.IsNil)
+	c.Assert(summary.Text(), qt.Equals, "📊 Final results: Ship it?\n"+
+		"1. Yes: 2 votes (66.7%)\n"+
+		"2. No: 1 vote (33.3%)\n"+
+		"🏆 Winner: Yes\n"+
+		"👥 Turnout: 3 of 10 (30%)")
+
+	// long questions and options are shortened to fit in a cast
+	options := []string{}
+	for _, name := range []string{"á", "b", "c", "d", "e", "f"} {
+		options = append(options, strings.Repeat(name, 100))
+	}
+	summary, err = New(strings.Repeat("question ", 50), options, election.VoteTypeSingle, &election.Results{
+		Tally:     [][]uint64{{1, 1, 0, 0, 0, 0}},
+		VoteCount: 2,
+	})
+	c.Assert(err, qt.IsNil)
+	text := summary.Text()
+	c.Assert(len(text) <= MaxTextLength, qt.IsTrue)
+	c.Assert(strings.ToValidUTF8(text, ""), qt.Equals, text)
+	c.Assert(text, qt.Contains, "🤝 Tie")
+	c.Assert(text, qt.Contains, "👥 Voters: 2")
+}
+
+func TestShorten(t *testing.T) {
+	c := qt.New(t)
+
+	c.Assert(shorten("hello", 5), qt.Equals, "hello")
+	c.Assert(shorten("hello world", 8), qt.Equals, "hello…")
+	c.Assert(shorten("ññññ", 6), qt.Equals, "ñ…")
+	c.Assert(shorten("hello", 2), qt.Equals, ellipsis)
+}
+
+func TestBarChart(t *testing.T) {
+	c := qt.New(t)
+
+	summary, err := New("Ship it?", []string{"Yes", "No"}, election.VoteTypeSingle, &election.Results{
+		Tally:     [][]uint64{{2, 1}},
+		VoteCount: 3,
+	})
+	c.Assert(err, qt.IsNil)
+	c.Assert(summary.BarChart(26*time.Hour+30*time.Minute), qt.Equals, "📊 Ship it?\n"+
+		"3 votes · 1d 2h left\n"+
+		"▓▓▓▓▓░░░ 66.7% Yes\n"+
+		"▓▓▓░░░░░ 33.3% No")
+	c.Assert(summary.BarChart(0), qt.Contains, "3 votes · ended")
+
+	// long questions and options are shortened to fit in a cast
+	options := []string{}
+	for _, name := range []string{"a", "b", "c", "d", "e", "f", "g", "h"} {
+		options = append(options, strings.Repeat(name, 100))
+	}
+	summary, err = New(strings.Repeat("question ", 50), options, election.VoteTypeSingle, &election.Results{})
+	c.Assert(err, qt.IsNil)
+	c.Assert(len(summary.BarChart(time.Hour)) <= MaxTextLength, qt.IsTrue)
+}
+
+func TestRemainingText(t *testing.T) {
+	c := qt.New(t)
+
+	tests := []struct {
+		remaining time.Duration
+		expected  string
+	}{
+		{30 * time.Second, "<1m"},
+		{45 * time.Minute, "45m"},
+		{3 * time.Hour, "3h"},
+		{3*time.Hour + 10*time.Minute, "3h 10m"},
+		{48 * time.Hour, "2d"},
+		{53*time.Hour + 59*time.Minute, "2d 5h"},
+	}
+	for _, test := range tests {
+		c.Assert(remainingText(test.remaining), qt.Equals, test.expected)
+	}
+}
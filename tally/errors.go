@@ -0,0 +1,8 @@
+package tally
+
+import "fmt"
+
+var (
+	ErrInvalidNumOptions = fmt.Errorf("invalid number of options")
+	ErrNoValidBallots    = fmt.Errorf("no valid ballots")
+)
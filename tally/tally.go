@@ -0,0 +1,160 @@
+package tally
+
+import "fmt"
+
+// NoWinner is the index used when there is no winner or no eliminated option
+const NoWinner = -1
+
+// Ballot represents a ranked choice ballot, it contains the indexes of the
+// ranked options ordered by preference, the first one is the favourite. A
+// ballot can rank less options than available.
+type Ballot []int
+
+// Round contains the votes of every option in a round of an instant-runoff
+// tally, indexed by option, and the option eliminated at the end of the round,
+// or NoWinner if the round has a winner. The eliminated options have zero
+// votes. Exhausted is the number of ballots without any continuing option.
+type Round struct {
+	Votes      []uint64
+	Exhausted  uint64
+	Eliminated int
+}
+
+// Result contains the winner of an instant-runoff tally, the details of every
+// round and the number of invalid ballots, which are discarded.
+type Result struct {
+	Winner  int
+	Rounds  []*Round
+	Invalid uint64
+}
+
+// InstantRunoff computes the winner of a ranked choice election with the
+// given number of options and ballots using instant-runoff voting. In every
+// round, each ballot counts for its favourite continuing option. If an option
+// gets more than half of the votes of the round, or it is the only continuing
+// option, it wins. Otherwise, the option with the least votes is eliminated
+// and a new round starts. The ties to eliminate an option are broken
+// deterministically:
+//  1. The option with less votes in the latest previous round where the tied
+//     options had different votes is eliminated.
+//  2. If they are still tied, the option with the highest index is
+//     eliminated, so the options listed first have precedence.
+//
+// The ballots with an option out of range or with repeated options are
+// invalid and they are discarded. It returns an error if the number of
+// options is not positive or if there are no valid ballots.
+func InstantRunoff(numOptions int, ballots []Ballot) (*Result, error) {
+	if numOptions <= 0 {
+		return nil, fmt.Errorf("%w: %d", ErrInvalidNumOptions, numOptions)
+	}
+	result := &Result{Winner: NoWinner}
+	validBallots := make([]Ballot, 0, len(ballots))
+	for _, ballot := range ballots {
+		if !isValid(ballot, numOptions) {
+			result.Invalid++
+			continue
+		}
+		validBallots = append(validBallots, ballot)
+	}
+	if len(validBallots) == 0 {
+		return nil, ErrNoValidBallots
+	}
+	eliminated := make([]bool, numOptions)
+	continuing := numOptions
+	for {
+		round := countRound(numOptions, validBallots, eliminated)
+		result.Rounds = append(result.Rounds, round)
+		// check if there is a winner, an option with the majority of the
+		// votes of the round or the last continuing option
+		counted := uint64(len(validBallots)) - round.Exhausted
+		for option, votes := range round.Votes {
+			if eliminated[option] {
+				continue
+			}
+			if votes*2 > counted || continuing == 1 {
+				round.Eliminated = NoWinner
+				result.Winner = option
+				return result, nil
+			}
+		}
+		// eliminate the option with the least votes
+		loser := findLoser(result.Rounds, eliminated)
+		round.Eliminated = loser
+		eliminated[loser] = true
+		continuing--
+	}
+}
+
+// isValid returns if every option of the ballot is in range and it is ranked
+// only once.
+func isValid(ballot Ballot, numOptions int) bool {
+	seen := make(map[int]bool, len(ballot))
+	for _, option := range ballot {
+		if option < 0 || option >= numOptions || seen[option] {
+			return false
+		}
+		seen[option] = true
+	}
+	return true
+}
+
+// countRound counts the votes of every continuing option, every ballot counts
+// for its favourite continuing option or as exhausted if it has none.
+func countRound(numOptions int, ballots []Ballot, eliminated []bool) *Round {
+	round := &Round{Votes: make([]uint64, numOptions), Eliminated: NoWinner}
+	for _, ballot := range ballots {
+		counted := false
+		for _, option := range ballot {
+			if !eliminated[option] {
+				round.Votes[option]++
+				counted = true
+				break
+			}
+		}
+		if !counted {
+			round.Exhausted++
+		}
+	}
+	return round
+}
+
+// findLoser returns the continuing option with the least votes in the last
+// round, breaking the ties with the previous rounds and, if they persist, by
+// eliminating the option with the highest index.
+func findLoser(rounds []*Round, eliminated []bool) int {
+	// get the continuing options with the least votes in the last round
+	candidates := []int{}
+	for i := len(rounds) - 1; i >= 0; i-- {
+		votes := rounds[i].Votes
+		var min uint64
+		next := []int{}
+		for option := range votes {
+			if eliminated[option] || (i < len(rounds)-1 && !contains(candidates, option)) {
+				continue
+			}
+			switch {
+			case len(next) == 0 || votes[option] < min:
+				min = votes[option]
+				next = []int{option}
+			case votes[option] == min:
+				next = append(next, option)
+			}
+		}
+		candidates = next
+		if len(candidates) == 1 {
+			return candidates[0]
+		}
+	}
+	// the candidates are sorted by index, so the last one has the highest
+	return candidates[len(candidates)-1]
+}
+
+// contains returns if the given option is in the list.
+func contains(options []int, option int) bool {
+	for _, o := range options {
+		if o == option {
+			return true
+		}
+	}
+	return false
+}
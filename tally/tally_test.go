@@ -0,0 +1,200 @@
+package tally
+
+import (
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+// repeat returns a list with n copies of the given ballot
+func repeat(n int, ballot Ballot) []Ballot {
+	ballots := make([]Ballot, n)
+	for i := range ballots {
+		ballots[i] = ballot
+	}
+	return ballots
+}
+
+// join concatenates the given lists of ballots
+func join(lists ...[]Ballot) []Ballot {
+	ballots := []Ballot{}
+	for _, list := range lists {
+		ballots = append(ballots, list...)
+	}
+	return ballots
+}
+
+func TestInstantRunoff(t *testing.T) {
+	c := qt.New(t)
+
+	tests := []struct {
+		name       string
+		numOptions int
+		ballots    []Ballot
+		winner     int
+		eliminated []int
+		invalid    uint64
+	}{
+		{
+			name:       "first round majority",
+			numOptions: 3,
+			ballots:    join(repeat(6, Ballot{0, 1}), repeat(3, Ballot{1}), repeat(1, Ballot{2, 1})),
+			winner:     0,
+			eliminated: []int{NoWinner},
+		},
+		{
+			name:       "single option",
+			numOptions: 1,
+			ballots:    repeat(2, Ballot{0}),
+			winner:     0,
+			eliminated: []int{NoWinner},
+		},
+		{
+			name:       "transferred votes change the winner",
+			numOptions: 3,
+			ballots:    join(repeat(4, Ballot{0}), repeat(3, Ballot{1, 0}), repeat(2, Ballot{2, 1})),
+			winner:     1,
+			eliminated: []int{2, NoWinner},
+		},
+		{
+			name:       "several rounds",
+			numOptions: 4,
+			ballots: join(
+				repeat(5, Ballot{0, 1, 2, 3}),
+				repeat(4, Ballot{1, 2, 0, 3}),
+				repeat(3, Ballot{2, 1, 0, 3}),
+				repeat(2, Ballot{3, 2, 1, 0}),
+			),
+			// round 1: 5, 4, 3, 2 -> eliminate 3
+			// round 2: 5, 4, 5, 0 -> eliminate 1
+			// round 3: 5, 0, 9, 0 -> 2 wins
+			winner:     2,
+			eliminated: []int{3, 1, NoWinner},
+		},
+		{
+			name:       "exhausted ballots are not counted for the majority",
+			numOptions: 3,
+			ballots:    join(repeat(4, Ballot{0}), repeat(3, Ballot{1}), repeat(2, Ballot{2})),
+			winner:     0,
+			eliminated: []int{2, NoWinner},
+		},
+		{
+			name:       "options without votes are eliminated first",
+			numOptions: 4,
+			ballots:    join(repeat(2, Ballot{0}), repeat(2, Ballot{1}), repeat(1, Ballot{2, 1})),
+			winner:     1,
+			eliminated: []int{3, 2, NoWinner},
+		},
+		{
+			name:       "tie broken by the previous round",
+			numOptions: 4,
+			ballots: join(
+				repeat(6, Ballot{0}),
+				repeat(3, Ballot{1}),
+				repeat(4, Ballot{2}),
+				repeat(1, Ballot{3, 1}),
+			),
+			// round 1: 6, 3, 4, 1 -> eliminate 3
+			// round 2: 6, 4, 4, 0 -> 1 and 2 tied, 1 had less votes in round 1
+			// round 3: 6, 0, 4, 0 -> 4 ballots exhausted, 0 has the majority
+			winner:     0,
+			eliminated: []int{3, 1, NoWinner},
+		},
+		{
+			name:       "tie broken by the option index",
+			numOptions: 3,
+			ballots:    join(repeat(3, Ballot{0}), repeat(2, Ballot{1, 0}), repeat(2, Ballot{2, 1})),
+			// round 1: 3, 2, 2 -> 1 and 2 tied in every round, eliminate 2
+			// round 2: 3, 4, 0 -> 1 wins
+			winner:     1,
+			eliminated: []int{2, NoWinner},
+		},
+		{
+			name:       "final tie",
+			numOptions: 2,
+			ballots:    join(repeat(2, Ballot{0}), repeat(2, Ballot{1})),
+			winner:     0,
+			eliminated: []int{1, NoWinner},
+		},
+		{
+			name:       "invalid ballots are discarded",
+			numOptions: 3,
+			ballots: join(
+				repeat(2, Ballot{0}),
+				repeat(3, Ballot{1}),
+				repeat(2, Ballot{2, 0}),
+				repeat(4, Ballot{2, 2}),
+				repeat(1, Ballot{3}),
+				repeat(1, Ballot{-1}),
+			),
+			// round 1: 2, 3, 2 -> 0 and 2 tied, eliminate 2 by index
+			// round 2: 4, 3, 0 -> 0 wins
+			winner:     0,
+			eliminated: []int{2, NoWinner},
+			invalid:    6,
+		},
+		{
+			name:       "empty ballots are exhausted",
+			numOptions: 2,
+			ballots:    join(repeat(3, Ballot{}), repeat(1, Ballot{1})),
+			winner:     1,
+			eliminated: []int{NoWinner},
+		},
+	}
+	for _, test := range tests {
+		c.Run(test.name, func(c *qt.C) {
+			result, err := InstantRunoff(test.numOptions, test.ballots)
+			c.Assert(err, qt.IsNil)
+			c.Assert(result.Winner, qt.Equals, test.winner)
+			c.Assert(result.Invalid, qt.Equals, test.invalid)
+			eliminated := []int{}
+			for _, round := range result.Rounds {
+				eliminated = append(eliminated, round.Eliminated)
+			}
+			c.Assert(eliminated, qt.DeepEquals, test.eliminated)
+		})
+	}
+}
+
+func TestInstantRunoffRounds(t *testing.T) {
+	c := qt.New(t)
+
+	ballots := join(repeat(4, Ballot{0}), repeat(3, Ballot{1, 0}), repeat(2, Ballot{2}))
+	result, err := InstantRunoff(3, ballots)
+	c.Assert(err, qt.IsNil)
+	c.Assert(result.Rounds, qt.HasLen, 2)
+	c.Assert(result.Rounds[0].Votes, qt.DeepEquals, []uint64{4, 3, 2})
+	c.Assert(result.Rounds[0].Exhausted, qt.Equals, uint64(0))
+	c.Assert(result.Rounds[1].Votes, qt.DeepEquals, []uint64{4, 3, 0})
+	c.Assert(result.Rounds[1].Exhausted, qt.Equals, uint64(2))
+	c.Assert(result.Winner, qt.Equals, 0)
+}
+
+func TestInstantRunoffDeterministic(t *testing.T) {
+	c := qt.New(t)
+
+	ballots := join(repeat(2, Ballot{0, 1}), repeat(2, Ballot{1, 2}), repeat(2, Ballot{2, 0}))
+	first, err := InstantRunoff(3, ballots)
+	c.Assert(err, qt.IsNil)
+	// the order of the ballots does not change the result
+	reversed := make([]Ballot, len(ballots))
+	for i, ballot := range ballots {
+		reversed[len(ballots)-1-i] = ballot
+	}
+	for i := 0; i < 10; i++ {
+		result, err := InstantRunoff(3, reversed)
+		c.Assert(err, qt.IsNil)
+		c.Assert(result, qt.DeepEquals, first)
+	}
+}
+
+func TestInstantRunoffErrors(t *testing.T) {
+	c := qt.New(t)
+
+	_, err := InstantRunoff(0, repeat(1, Ballot{0}))
+	c.Assert(err, qt.ErrorIs, ErrInvalidNumOptions)
+	_, err = InstantRunoff(2, nil)
+	c.Assert(err, qt.ErrorIs, ErrNoValidBallots)
+	_, err = InstantRunoff(2, repeat(2, Ballot{0, 0}))
+	c.Assert(err, qt.ErrorIs, ErrNoValidBallots)
+}